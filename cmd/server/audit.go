@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"google-drive-mcp-server/pkg/httpauth"
+	"google-drive-mcp-server/pkg/tools"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// auditToolCalls logs every tool call with the authenticated principal that
+// made it, the Drive identity it targeted and its outcome.
+func auditToolCalls(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		start := time.Now()
+		result, err := next(ctx, request)
+
+		outcome := "ok"
+		if err != nil {
+			outcome = "error: " + err.Error()
+		} else if result != nil && result.IsError {
+			outcome = "tool error"
+		}
+		log.Printf("audit: principal=%s session=%s tool=%s account=%q as_user=%q duration=%s outcome=%s",
+			httpauth.PrincipalFromContext(ctx), tools.SessionID(ctx), request.Params.Name,
+			request.GetString("account", ""), request.GetString("as_user", ""),
+			time.Since(start).Round(time.Millisecond), outcome)
+		return result, err
+	}
+}
@@ -0,0 +1,726 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+
+	"google-drive-mcp-server/pkg/config"
+	"google-drive-mcp-server/pkg/driveapi"
+	"google-drive-mcp-server/pkg/driveapi/drivetest"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"google.golang.org/api/drive/v3"
+)
+
+// testEnv is the real MCP server wired to a fake Drive and connected to an
+// in-process MCP client.
+type testEnv struct {
+	drive  *drivetest.Server
+	client *client.Client
+}
+
+func newTestEnv(t *testing.T, configure func(*config.Config)) *testEnv {
+	t.Helper()
+	ctx := context.Background()
+	ts := drivetest.NewServer(nil)
+	t.Cleanup(ts.Close)
+	srv, err := ts.Service(ctx)
+	if err != nil {
+		t.Fatalf("drive service: %v", err)
+	}
+
+	cfg := config.Default()
+	if configure != nil {
+		configure(cfg)
+	}
+	s := newMCPServer(cfg, driveapi.NewStaticAccounts(config.DefaultAccount, srv), nil)
+
+	c, err := client.NewInProcessClient(s)
+	if err != nil {
+		t.Fatalf("client: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	if err := c.Start(ctx); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	init := mcp.InitializeRequest{}
+	init.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	init.Params.ClientInfo = mcp.Implementation{Name: "e2e", Version: "1.0.0"}
+	if _, err := c.Initialize(ctx, init); err != nil {
+		t.Fatalf("initialize: %v", err)
+	}
+	return &testEnv{drive: ts, client: c}
+}
+
+// call invokes a tool and decodes its JSON result into out. It fails the
+// test if the tool reports an error.
+func (e *testEnv) call(t *testing.T, name string, args map[string]any, out any) {
+	t.Helper()
+	text, isError := e.callRaw(t, name, args)
+	if isError {
+		t.Fatalf("%s: tool error: %s", name, text)
+	}
+	if out != nil {
+		if err := json.Unmarshal([]byte(text), out); err != nil {
+			t.Fatalf("%s: decode %q: %v", name, text, err)
+		}
+	}
+}
+
+// callRaw invokes a tool and returns its text result and error flag.
+func (e *testEnv) callRaw(t *testing.T, name string, args map[string]any) (string, bool) {
+	t.Helper()
+	req := mcp.CallToolRequest{}
+	req.Params.Name = name
+	req.Params.Arguments = args
+	res, err := e.client.CallTool(context.Background(), req)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	if len(res.Content) == 0 {
+		t.Fatalf("%s: empty result", name)
+	}
+	text, ok := res.Content[0].(mcp.TextContent)
+	if !ok {
+		t.Fatalf("%s: unexpected content %T", name, res.Content[0])
+	}
+	return text.Text, res.IsError
+}
+
+func (e *testEnv) toolNames(t *testing.T) []string {
+	t.Helper()
+	res, err := e.client.ListTools(context.Background(), mcp.ListToolsRequest{})
+	if err != nil {
+		t.Fatalf("list tools: %v", err)
+	}
+	names := make([]string, len(res.Tools))
+	for i, tool := range res.Tools {
+		names[i] = tool.Name
+	}
+	sort.Strings(names)
+	return names
+}
+
+type fileRef struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	MimeType string `json:"mime_type"`
+}
+
+func TestCreateListAndRead(t *testing.T) {
+	e := newTestEnv(t, nil)
+
+	var created struct {
+		FileID   string `json:"file_id"`
+		FileName string `json:"file_name"`
+	}
+	e.call(t, "create_file_in_path", map[string]any{"path": "Projects/2024/plan.txt", "content": "ship it"}, &created)
+	if created.FileName != "plan.txt" || created.FileID == "" {
+		t.Fatalf("created = %+v", created)
+	}
+
+	var roots struct {
+		Folders []fileRef `json:"folders"`
+	}
+	e.call(t, "list_root_folders", nil, &roots)
+	if len(roots.Folders) != 1 || roots.Folders[0].Name != "Projects" {
+		t.Fatalf("root folders = %+v", roots.Folders)
+	}
+
+	var listing struct {
+		Files []fileRef `json:"files"`
+	}
+	e.call(t, "list_files_and_folders", map[string]any{"folder_id": roots.Folders[0].ID}, &listing)
+	if len(listing.Files) != 1 || listing.Files[0].Name != "2024" {
+		t.Fatalf("Projects contains %+v", listing.Files)
+	}
+
+	var found struct {
+		Items []fileRef `json:"found_items"`
+	}
+	e.call(t, "search_drive_items", map[string]any{"query": "name contains 'plan' and trashed = false"}, &found)
+	if len(found.Items) != 1 || found.Items[0].ID != created.FileID {
+		t.Fatalf("search found %+v", found.Items)
+	}
+
+	var read struct {
+		Content string `json:"content"`
+	}
+	e.call(t, "read_file_content", map[string]any{"file_id": created.FileID, "mime_type": "text/plain"}, &read)
+	if read.Content != "ship it" {
+		t.Errorf("content = %q, want %q", read.Content, "ship it")
+	}
+
+	// A second file in the same path reuses the existing folders.
+	e.call(t, "create_file_in_path", map[string]any{"path": "Projects/2024/notes.txt", "content": "n"}, nil)
+	e.call(t, "list_root_folders", nil, &roots)
+	if len(roots.Folders) != 1 {
+		t.Errorf("folders were duplicated: %+v", roots.Folders)
+	}
+}
+
+func TestReadGoogleDocExportsText(t *testing.T) {
+	e := newTestEnv(t, nil)
+	ctx := context.Background()
+	srv, err := e.drive.Service(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := srv.Files.Create(&drive.File{Name: "Doc", MimeType: "application/vnd.google-apps.document"}).Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.drive.Drive.SetExport(doc.Id, "text/plain", []byte("from the doc")); err != nil {
+		t.Fatal(err)
+	}
+
+	var read struct {
+		Content string `json:"content"`
+	}
+	e.call(t, "read_file_content", map[string]any{"file_id": doc.Id, "mime_type": doc.MimeType}, &read)
+	if read.Content != "from the doc" {
+		t.Errorf("content = %q", read.Content)
+	}
+}
+
+func TestSearchFiles(t *testing.T) {
+	e := newTestEnv(t, nil)
+	ctx := context.Background()
+	d := e.drive.Drive
+	for _, f := range []*drive.File{
+		{Name: "Q1 budget.xlsx", Starred: true},
+		{Name: "Q1 budget notes.txt"},
+		{Name: "Bob's budget.pdf", Owners: []*drive.User{{EmailAddress: "bob@example.com"}}},
+	} {
+		if _, err := d.CreateFile(ctx, f, nil, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var res struct {
+		Query string    `json:"query"`
+		Items []fileRef `json:"found_items"`
+	}
+	e.call(t, "search_files", map[string]any{"name_contains": "budget", "starred": false, "mime_types": []string{"text", "pdf"}}, &res)
+	if len(res.Items) != 2 {
+		t.Errorf("query %s found %+v", res.Query, res.Items)
+	}
+	e.call(t, "search_files", map[string]any{"name_contains": "Bob's", "owner": "bob@example.com"}, &res)
+	if len(res.Items) != 1 || res.Items[0].Name != "Bob's budget.pdf" {
+		t.Errorf("query %s found %+v", res.Query, res.Items)
+	}
+	if want := `name contains 'Bob\'s' and 'bob@example.com' in owners and trashed = false`; res.Query != want {
+		t.Errorf("query = %s, want %s", res.Query, want)
+	}
+
+	for _, args := range []map[string]any{
+		{"modified_after": "last week"},
+		{"mime_types": []string{"sheets"}},
+	} {
+		if text, isError := e.callRaw(t, "search_files", args); !isError {
+			t.Errorf("search_files(%v) succeeded: %s", args, text)
+		}
+	}
+}
+
+func TestListingFields(t *testing.T) {
+	e := newTestEnv(t, nil)
+	e.call(t, "create_file_in_path", map[string]any{"path": "Docs/a.txt", "content": "abc"}, nil)
+	var found struct {
+		Items []driveapi.FileInfo `json:"found_items"`
+	}
+	e.call(t, "search_files", map[string]any{"name_contains": "a.txt", "fields": []string{"size", "md5Checksum", "parents", "web_view_link"}}, &found)
+	if len(found.Items) != 1 {
+		t.Fatalf("found %+v", found.Items)
+	}
+	f := found.Items[0]
+	if f.Size == nil || *f.Size != 3 || f.MD5Checksum != "900150983cd24fb0d6963f7d28e17f72" || len(f.Parents) != 1 || f.WebViewLink == "" || f.ModifiedTime != "" {
+		t.Errorf("file info = %+v", f)
+	}
+	if text, isError := e.callRaw(t, "list_files_and_folders", map[string]any{"fields": []string{"color"}}); !isError {
+		t.Errorf("unknown field was accepted: %s", text)
+	}
+}
+
+func TestOrderAndRecentFiles(t *testing.T) {
+	e := newTestEnv(t, nil)
+	ctx := context.Background()
+	for _, f := range []*drive.File{
+		{Name: "b.txt", ViewedByMeTime: "2024-03-01T00:00:00Z"},
+		{Name: "a.txt"},
+		{Name: "c.txt", ViewedByMeTime: "2024-04-01T00:00:00Z"},
+		{Name: "Folder", MimeType: driveapi.FolderMimeType},
+	} {
+		if _, err := e.drive.Drive.CreateFile(ctx, f, nil, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	names := func(files []fileRef) string {
+		var s []string
+		for _, f := range files {
+			s = append(s, f.Name)
+		}
+		return strings.Join(s, " ")
+	}
+
+	var listing struct {
+		Files []fileRef `json:"files"`
+	}
+	e.call(t, "list_files_and_folders", map[string]any{"order_by": "folder, name desc"}, &listing)
+	if got := names(listing.Files); got != "Folder c.txt b.txt a.txt" {
+		t.Errorf("ordered listing = %s", got)
+	}
+	e.call(t, "recent_files", nil, &listing)
+	if got := names(listing.Files); got != "c.txt a.txt b.txt" {
+		t.Errorf("recently modified = %s", got)
+	}
+	e.call(t, "recent_files", map[string]any{"by": "viewed", "max_results": 1}, &listing)
+	if got := names(listing.Files); got != "c.txt" {
+		t.Errorf("recently viewed = %s", got)
+	}
+
+	if text, isError := e.callRaw(t, "search_files", map[string]any{"full_text": "x", "order_by": "name"}); !isError {
+		t.Errorf("full_text with order_by succeeded: %s", text)
+	}
+	if text, isError := e.callRaw(t, "search_drive_items", map[string]any{"query": "trashed = false", "order_by": "size"}); !isError {
+		t.Errorf("unknown sort key was accepted: %s", text)
+	}
+}
+
+func TestPathAddressing(t *testing.T) {
+	e := newTestEnv(t, nil)
+	var created struct {
+		FileID string `json:"file_id"`
+	}
+	e.call(t, "create_file_in_path", map[string]any{"path": "Projects/2024/plan.txt", "content": "ship it"}, &created)
+
+	var stat struct {
+		Matches   []driveapi.FileInfo `json:"matches"`
+		Ambiguous bool                `json:"ambiguous"`
+	}
+	e.call(t, "stat_path", map[string]any{"path": "Projects/2024/plan.txt"}, &stat)
+	if len(stat.Matches) != 1 || stat.Matches[0].ID != created.FileID || stat.Ambiguous {
+		t.Errorf("stat_path = %+v", stat)
+	}
+
+	var resolved struct {
+		Paths []string `json:"paths"`
+	}
+	e.call(t, "resolve_id", map[string]any{"file_id": created.FileID}, &resolved)
+	if len(resolved.Paths) != 1 || resolved.Paths[0] != "/Projects/2024/plan.txt" {
+		t.Errorf("resolve_id = %v", resolved.Paths)
+	}
+
+	var listing struct {
+		Files []fileRef `json:"files"`
+	}
+	e.call(t, "list_files_and_folders", map[string]any{"path": "Projects/2024"}, &listing)
+	if len(listing.Files) != 1 || listing.Files[0].ID != created.FileID {
+		t.Errorf("listing by path = %+v", listing.Files)
+	}
+	var read struct {
+		Content string `json:"content"`
+	}
+	e.call(t, "read_file_content", map[string]any{"path": "/Projects/2024/plan.txt", "mime_type": "text/plain"}, &read)
+	if read.Content != "ship it" {
+		t.Errorf("content by path = %q", read.Content)
+	}
+
+	for name, args := range map[string]map[string]any{
+		"stat_path":              {"path": "Projects/2025"},
+		"list_files_and_folders": {"path": "Projects/2024/plan.txt"},
+		"read_file_content":      {"path": "Projects/2024", "mime_type": "text/plain"},
+	} {
+		if text, isError := e.callRaw(t, name, args); !isError {
+			t.Errorf("%s(%v) succeeded: %s", name, args, text)
+		}
+	}
+}
+
+// zipParts zips the named parts, as in an Office file.
+func zipParts(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, content)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadDocx(t *testing.T) {
+	e := newTestEnv(t, nil)
+	docx := zipParts(t, map[string]string{
+		"word/document.xml": `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+			`<w:p><w:pPr><w:outlineLvl w:val="0"/></w:pPr><w:r><w:t>Plan</w:t></w:r></w:p>` +
+			`<w:p><w:r><w:t>Ship it.</w:t></w:r></w:p></w:body></w:document>`,
+	})
+	doc, err := e.drive.Drive.CreateFile(context.Background(), &drive.File{Name: "plan.docx", MimeType: driveapi.DocxMimeType}, bytes.NewReader(docx), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var read struct {
+		Content string `json:"content"`
+	}
+	e.call(t, "read_file_content", map[string]any{"file_id": doc.Id, "mime_type": driveapi.DocxMimeType, "format": "markdown"}, &read)
+	if read.Content != "# Plan\n\nShip it.\n" {
+		t.Errorf("markdown = %q", read.Content)
+	}
+	e.call(t, "read_file_content", map[string]any{"file_id": doc.Id, "mime_type": driveapi.DocxMimeType}, &read)
+	if read.Content != "Plan\n\nShip it.\n" {
+		t.Errorf("text = %q", read.Content)
+	}
+
+	// Files created by create_docx_file_in_path hold plain text.
+	var created struct {
+		FileID string `json:"file_id"`
+	}
+	e.call(t, "create_docx_file_in_path", map[string]any{"path": "notes.docx", "content": "plain"}, &created)
+	e.call(t, "read_file_content", map[string]any{"file_id": created.FileID, "mime_type": driveapi.DocxMimeType}, &read)
+	if read.Content != "plain" {
+		t.Errorf("plain docx = %q", read.Content)
+	}
+}
+
+// testWorkbook returns an .xlsx workbook with sheets 2024 and 2025.
+func testWorkbook(t *testing.T) []byte {
+	const ns = `xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+	return zipParts(t, map[string]string{
+		"xl/workbook.xml": `<workbook ` + ns + `><sheets><sheet name="2024" r:id="rId1"/><sheet name="2025" r:id="rId2"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+			`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet2.xml"/></Relationships>`,
+		"xl/worksheets/sheet1.xml": `<worksheet ` + ns + `><sheetData><row r="1"><c r="A1" t="inlineStr"><is><t>Item</t></is></c><c r="B1"><v>10</v></c></row></sheetData></worksheet>`,
+		"xl/worksheets/sheet2.xml": `<worksheet ` + ns + `><sheetData><row r="2"><c r="B2"><v>20</v></c></row></sheetData></worksheet>`,
+	})
+}
+
+// testPresentation returns a .pptx presentation with one slide and its
+// speaker notes.
+func testPresentation(t *testing.T) []byte {
+	const ns = `xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main"`
+	return zipParts(t, map[string]string{
+		"ppt/presentation.xml": `<p:presentation ` + ns + `><p:sldIdLst><p:sldId id="256" r:id="rId1"/></p:sldIdLst></p:presentation>`,
+		"ppt/_rels/presentation.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide" Target="slides/slide1.xml"/></Relationships>`,
+		"ppt/slides/slide1.xml": `<p:sld ` + ns + `><p:cSld><p:spTree>` +
+			`<p:sp><p:nvSpPr><p:nvPr><p:ph type="title"/></p:nvPr></p:nvSpPr><p:txBody><a:p><a:r><a:t>Why now</a:t></a:r></a:p></p:txBody></p:sp>` +
+			`<p:sp><p:txBody><a:p><a:r><a:t>Demand is up</a:t></a:r></a:p></p:txBody></p:sp></p:spTree></p:cSld></p:sld>`,
+		"ppt/slides/_rels/slide1.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/notesSlide" Target="../notesSlides/notesSlide1.xml"/></Relationships>`,
+		"ppt/notesSlides/notesSlide1.xml": `<p:notes ` + ns + `><p:cSld><p:spTree>` +
+			`<p:sp><p:nvSpPr><p:nvPr><p:ph type="body" idx="1"/></p:nvPr></p:nvSpPr><p:txBody><a:p><a:r><a:t>Pause here.</a:t></a:r></a:p></p:txBody></p:sp></p:spTree></p:cSld></p:notes>`,
+	})
+}
+
+func TestReadGoogleSheetsSlidesAndDrawings(t *testing.T) {
+	e := newTestEnv(t, nil)
+	ctx := context.Background()
+	srv, err := e.drive.Service(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	create := func(name, mimeType, exportType string, data []byte) string {
+		t.Helper()
+		f, err := srv.Files.Create(&drive.File{Name: name, MimeType: mimeType}).Do()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := e.drive.Drive.SetExport(f.Id, exportType, data); err != nil {
+			t.Fatal(err)
+		}
+		return f.Id
+	}
+	sheetID := create("Budget", driveapi.GoogleSheetMimeType, driveapi.XLSXMimeType, testWorkbook(t))
+
+	var sheets struct {
+		Sheets []driveapi.SheetContent `json:"sheets"`
+	}
+	e.call(t, "read_file_content", map[string]any{"file_id": sheetID, "mime_type": driveapi.GoogleSheetMimeType}, &sheets)
+	want := []driveapi.SheetContent{{Name: "2024", Rows: 1, CSV: "Item,10\n"}, {Name: "2025", Rows: 2, CSV: "\n,20\n"}}
+	if fmt.Sprint(sheets.Sheets) != fmt.Sprint(want) {
+		t.Errorf("sheets = %+v, want %+v", sheets.Sheets, want)
+	}
+	e.call(t, "read_file_content", map[string]any{"file_id": sheetID, "mime_type": driveapi.GoogleSheetMimeType, "sheet": "2025"}, &sheets)
+	if len(sheets.Sheets) != 1 || sheets.Sheets[0].Name != "2025" {
+		t.Errorf("sheet 2025 = %+v", sheets.Sheets)
+	}
+	if text, isError := e.callRaw(t, "read_file_content", map[string]any{"file_id": sheetID, "mime_type": driveapi.GoogleSheetMimeType, "sheet": "2026"}); !isError || !strings.Contains(text, "'2024', '2025'") {
+		t.Errorf("unknown sheet = %s", text)
+	}
+
+	slidesID := create("Pitch", driveapi.GoogleSlidesMimeType, driveapi.PPTXMimeType, testPresentation(t))
+	var slides struct {
+		Slides []driveapi.SlideContent `json:"slides"`
+	}
+	e.call(t, "read_file_content", map[string]any{"file_id": slidesID, "mime_type": driveapi.GoogleSlidesMimeType}, &slides)
+	if want := []driveapi.SlideContent{{Number: 1, Title: "Why now", Text: "Demand is up", Notes: "Pause here."}}; fmt.Sprint(slides.Slides) != fmt.Sprint(want) {
+		t.Errorf("slides = %+v, want %+v", slides.Slides, want)
+	}
+
+	png := []byte("\x89PNG\r\n\x1a\nfake")
+	drawingID := create("Diagram", driveapi.GoogleDrawingMimeType, "image/png", png)
+	req := mcp.CallToolRequest{}
+	req.Params.Name = "read_file_content"
+	req.Params.Arguments = map[string]any{"file_id": drawingID, "mime_type": driveapi.GoogleDrawingMimeType}
+	res, err := e.client.CallTool(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.IsError || len(res.Content) != 2 {
+		t.Fatalf("drawing result = %+v", res)
+	}
+	image, ok := res.Content[1].(mcp.ImageContent)
+	if !ok || image.MIMEType != "image/png" || image.Data != base64.StdEncoding.EncodeToString(png) {
+		t.Errorf("image content = %+v", res.Content[1])
+	}
+}
+
+func TestReadXLSXAndPPTX(t *testing.T) {
+	e := newTestEnv(t, nil)
+	ctx := context.Background()
+	xlsx, err := e.drive.Drive.CreateFile(ctx, &drive.File{Name: "budget.xlsx", MimeType: driveapi.XLSXMimeType}, bytes.NewReader(testWorkbook(t)), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pptx, err := e.drive.Drive.CreateFile(ctx, &drive.File{Name: "pitch.pptx", MimeType: driveapi.PPTXMimeType}, bytes.NewReader(testPresentation(t)), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sheets struct {
+		Sheets []driveapi.SheetContent `json:"sheets"`
+	}
+	e.call(t, "read_file_content", map[string]any{"file_id": xlsx.Id, "mime_type": driveapi.XLSXMimeType, "format": "markdown", "sheet": "2024"}, &sheets)
+	if want := []driveapi.SheetContent{{Name: "2024", Rows: 1, Markdown: "| Item | 10 |\n| --- | --- |\n"}}; fmt.Sprint(sheets.Sheets) != fmt.Sprint(want) {
+		t.Errorf("markdown = %+v, want %+v", sheets.Sheets, want)
+	}
+	sheets.Sheets = nil
+	e.call(t, "read_file_content", map[string]any{"file_id": xlsx.Id, "mime_type": driveapi.XLSXMimeType, "cells": "B2"}, &sheets)
+	if want := []driveapi.SheetContent{{Name: "2024"}, {Name: "2025", Rows: 1, CSV: "20\n"}}; fmt.Sprint(sheets.Sheets) != fmt.Sprint(want) {
+		t.Errorf("cells B2 = %+v, want %+v", sheets.Sheets, want)
+	}
+	if text, isError := e.callRaw(t, "read_file_content", map[string]any{"file_id": pptx.Id, "mime_type": driveapi.PPTXMimeType, "cells": "A1"}); !isError {
+		t.Errorf("selecting cells of a presentation succeeded: %s", text)
+	}
+
+	var slides struct {
+		Slides []driveapi.SlideContent `json:"slides"`
+	}
+	e.call(t, "read_file_content", map[string]any{"file_id": pptx.Id, "mime_type": driveapi.PPTXMimeType}, &slides)
+	if len(slides.Slides) != 1 || slides.Slides[0].Title != "Why now" || slides.Slides[0].Notes != "Pause here." {
+		t.Errorf("slides = %+v", slides.Slides)
+	}
+}
+
+// simplePDF returns a PDF with one page of Helvetica text per string.
+func simplePDF(texts ...string) []byte {
+	objs := []string{"<< /Type /Catalog /Pages 2 0 R >>", "", "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>"}
+	var kids []string
+	for _, text := range texts {
+		content := fmt.Sprintf("BT /F1 12 Tf 72 700 Td (%s) Tj ET", text)
+		objs = append(objs, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+		objs = append(objs, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", len(objs)))
+		kids = append(kids, fmt.Sprintf("%d 0 R", len(objs)))
+	}
+	objs[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids))
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	var offsets []int
+	for i, obj := range objs {
+		offsets = append(offsets, b.Len())
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objs)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objs)+1, xref)
+	return b.Bytes()
+}
+
+func TestReadPDF(t *testing.T) {
+	e := newTestEnv(t, nil)
+	pdf, err := e.drive.Drive.CreateFile(context.Background(), &drive.File{Name: "report.pdf", MimeType: driveapi.PDFMimeType}, bytes.NewReader(simplePDF("First page", "Second page")), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var read struct {
+		Content string `json:"content"`
+	}
+	e.call(t, "read_file_content", map[string]any{"file_id": pdf.Id, "mime_type": driveapi.PDFMimeType}, &read)
+	if want := "--- Page 1 ---\nFirst page\n\n--- Page 2 ---\nSecond page\n"; read.Content != want {
+		t.Errorf("content = %q, want %q", read.Content, want)
+	}
+	e.call(t, "read_file_content", map[string]any{"file_id": pdf.Id, "mime_type": driveapi.PDFMimeType, "pages": "2"}, &read)
+	if want := "--- Page 2 ---\nSecond page\n"; read.Content != want {
+		t.Errorf("page 2 = %q, want %q", read.Content, want)
+	}
+	if text, isError := e.callRaw(t, "read_file_content", map[string]any{"file_id": pdf.Id, "mime_type": driveapi.PDFMimeType, "pages": "3"}); !isError {
+		t.Errorf("reading a page past the end succeeded: %s", text)
+	}
+}
+
+func TestReadDetectsTypeAndFollowsShortcuts(t *testing.T) {
+	e := newTestEnv(t, nil)
+	ctx := context.Background()
+	pdf, err := e.drive.Drive.CreateFile(ctx, &drive.File{Name: "report.pdf", MimeType: driveapi.PDFMimeType}, bytes.NewReader(simplePDF("Only page")), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shortcut, err := e.drive.Drive.CreateFile(ctx, &drive.File{Name: "report link", MimeType: driveapi.ShortcutMimeType, ShortcutDetails: &drive.FileShortcutDetails{TargetId: pdf.Id}}, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var read struct {
+		ID           string `json:"id"`
+		Name         string `json:"name"`
+		MimeType     string `json:"mime_type"`
+		ModifiedTime string `json:"modified_time"`
+		ShortcutID   string `json:"shortcut_id"`
+		Content      string `json:"content"`
+	}
+	// The MIME type is detected, and a wrong one is overridden.
+	for _, args := range []map[string]any{
+		{"file_id": pdf.Id},
+		{"file_id": pdf.Id, "mime_type": "text/plain"},
+		{"path": "report.pdf"},
+	} {
+		read.ShortcutID = ""
+		e.call(t, "read_file_content", args, &read)
+		if read.ID != pdf.Id || read.Name != "report.pdf" || read.MimeType != driveapi.PDFMimeType || read.ModifiedTime == "" || read.ShortcutID != "" || read.Content != "--- Page 1 ---\nOnly page\n" {
+			t.Errorf("%v: read = %+v", args, read)
+		}
+	}
+
+	e.call(t, "read_file_content", map[string]any{"file_id": shortcut.Id}, &read)
+	if read.ID != pdf.Id || read.ShortcutID != shortcut.Id || read.Content != "--- Page 1 ---\nOnly page\n" {
+		t.Errorf("shortcut: read = %+v", read)
+	}
+
+	folder, err := e.drive.Drive.CreateFile(ctx, &drive.File{Name: "Reports", MimeType: driveapi.FolderMimeType}, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, isError := e.callRaw(t, "read_file_content", map[string]any{"file_id": folder.Id}); !isError || !strings.Contains(text, "is a folder") {
+		t.Errorf("reading a folder = %s", text)
+	}
+}
+
+func TestReadSparseXLSXIsBounded(t *testing.T) {
+	e := newTestEnv(t, nil)
+	const ns = `xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+	cell := func(ref string) string {
+		return `<row><c r="` + ref + `" t="inlineStr"><is><t>x</t></is></c></row>`
+	}
+	sparse := zipParts(t, map[string]string{
+		"xl/workbook.xml": `<workbook ` + ns + `><sheets><sheet name="Sparse" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`,
+		"xl/worksheets/sheet1.xml": `<worksheet ` + ns + `><sheetData>` + cell("A1") + cell("XFD1") + cell("A1048576") + `</sheetData></worksheet>`,
+	})
+	xlsx, err := e.drive.Drive.CreateFile(context.Background(), &drive.File{Name: "sparse.xlsx", MimeType: driveapi.XLSXMimeType}, bytes.NewReader(sparse), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// CSV rows are not padded, so the output stays near the cell count.
+	text, isError := e.callRaw(t, "read_file_content", map[string]any{"file_id": xlsx.Id})
+	if isError || len(text) > 4<<20 {
+		t.Errorf("CSV: error %v, %d bytes", isError, len(text))
+	}
+	// A Markdown table would be 16384 columns by 1048576 rows.
+	text, isError = e.callRaw(t, "read_file_content", map[string]any{"file_id": xlsx.Id, "format": "markdown"})
+	if !isError || !strings.Contains(text, "too large") {
+		t.Errorf("Markdown = %.200s", text)
+	}
+	// Selecting cells reads just those.
+	var read struct {
+		Sheets []driveapi.SheetContent `json:"sheets"`
+	}
+	e.call(t, "read_file_content", map[string]any{"file_id": xlsx.Id, "format": "markdown", "cells": "A1048570:B1048576"}, &read)
+	if len(read.Sheets) != 1 || read.Sheets[0].Rows != 7 {
+		t.Errorf("cells = %+v", read.Sheets)
+	}
+}
+
+func TestReadContentIsBounded(t *testing.T) {
+	e := newTestEnv(t, nil)
+	big, err := e.drive.Drive.CreateFile(context.Background(), &drive.File{Name: "big.txt", MimeType: "text/plain"}, strings.NewReader(strings.Repeat("x", driveapi.MaxContentSize+1)), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, isError := e.callRaw(t, "read_file_content", map[string]any{"file_id": big.Id}); !isError || !strings.Contains(text, "more than") {
+		t.Errorf("reading an oversized file = %.200s", text)
+	}
+}
+
+func TestToolErrorsAreReported(t *testing.T) {
+	e := newTestEnv(t, nil)
+	if text, isError := e.callRaw(t, "read_file_content", map[string]any{"file_id": "missing", "mime_type": "text/plain"}); !isError {
+		t.Errorf("reading a missing file succeeded: %s", text)
+	}
+	if text, isError := e.callRaw(t, "search_drive_items", map[string]any{"query": "name = 'unterminated"}); !isError {
+		t.Errorf("invalid query succeeded: %s", text)
+	}
+	if text, isError := e.callRaw(t, "list_root_folders", map[string]any{"as_user": "someone@example.com"}); !isError {
+		t.Errorf("as_user without delegation succeeded: %s", text)
+	}
+}
+
+func TestDisableAsUser(t *testing.T) {
+	e := newTestEnv(t, func(cfg *config.Config) {
+		cfg.Server.Transport = config.TransportHTTP
+		cfg.Server.DisableAsUser = true
+	})
+	text, isError := e.callRaw(t, "list_root_folders", map[string]any{"as_user": "someone@example.com"})
+	if !isError || !strings.Contains(text, "as_user is disabled") {
+		t.Errorf("as_user on a server that disables it: %s", text)
+	}
+	e.call(t, "list_root_folders", nil, nil)
+}
+
+func TestListAccounts(t *testing.T) {
+	e := newTestEnv(t, nil)
+	var res struct {
+		Accounts []driveapi.AccountStatus `json:"accounts"`
+	}
+	e.call(t, "list_accounts", nil, &res)
+	if len(res.Accounts) != 1 || !res.Accounts[0].Authorized || res.Accounts[0].Email != drivetest.UserEmail {
+		t.Errorf("accounts = %+v", res.Accounts)
+	}
+}
+
+func TestReadOnlyAndDisabledTools(t *testing.T) {
+	e := newTestEnv(t, func(cfg *config.Config) {
+		cfg.ReadOnly = true
+		cfg.Tools.Disabled = []string{"summarize_content"}
+	})
+	for _, name := range e.toolNames(t) {
+		switch name {
+		case "create_file_in_path", "create_docx_file_in_path", "suggest_folder_for_content", "summarize_content":
+			t.Errorf("tool %s is registered", name)
+		}
+	}
+}
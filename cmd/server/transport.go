@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"google-drive-mcp-server/pkg/config"
+	"google-drive-mcp-server/pkg/driveapi"
+	"google-drive-mcp-server/pkg/httpauth"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// serve runs the MCP server on the configured transport until it fails or
+// the process is asked to stop. sessions is nil unless per-session
+// authorization is enabled.
+func serve(cfg *config.Config, s *server.MCPServer, sessions *driveapi.SessionAuth) error {
+	switch cfg.Server.Transport {
+	case config.TransportStdio:
+		// stdout carries the protocol stream; everything else goes to stderr.
+		log.Printf("Starting MCP stdio server...")
+		return server.ServeStdio(s, server.WithErrorLogger(log.New(os.Stderr, "", log.LstdFlags)))
+	case config.TransportSSE, config.TransportHTTP:
+		return serveHTTP(cfg, s, sessions)
+	default:
+		return fmt.Errorf("unknown transport '%s'", cfg.Server.Transport)
+	}
+}
+
+// serveHTTP exposes the MCP server over SSE or streamable HTTP and shuts it
+// down gracefully on SIGINT or SIGTERM.
+func serveHTTP(cfg *config.Config, s *server.MCPServer, sessions *driveapi.SessionAuth) error {
+	mux := http.NewServeMux()
+	// Behind a reverse proxy the base URL may carry a path prefix that the
+	// proxy may or may not strip, so every endpoint answers on both paths.
+	basePath := cfg.Server.BasePath()
+	handle := func(path string, h http.Handler) {
+		mux.Handle(path, h)
+		if basePath != "" {
+			mux.Handle(basePath+path, h)
+		}
+	}
+	switch cfg.Server.Transport {
+	case config.TransportSSE:
+		// The SSE endpoint event advertises the message URL under the public base URL.
+		sseServer := server.NewSSEServer(s,
+			server.WithBaseURL(cfg.Server.BaseURL),
+		)
+		handle("/sse", sseServer.SSEHandler())
+		handle("/message", sseServer.MessageHandler())
+	case config.TransportHTTP:
+		handle("/mcp", server.NewStreamableHTTPServer(s))
+	}
+
+	// Callers are authenticated before any request reaches the MCP server.
+	// Both transports derive the tool call context from the request, so the
+	// principal stored by the middleware is visible to the audit log.
+	var handler http.Handler = mux
+	if authn := cfg.Server.Auth.Authenticator(); authn != nil {
+		handler = httpauth.Middleware(authn, mux)
+	} else {
+		log.Printf("WARNING: no server.auth credentials configured; anyone who can reach %s can use the Drive tools", cfg.Server.ListenAddr)
+	}
+	// The OAuth callback is opened by the user's browser, which carries no
+	// client credentials, so it is served outside the authenticator.
+	if sessions != nil {
+		public := http.NewServeMux()
+		public.Handle("/", handler)
+		public.Handle(config.SessionOAuthCallbackPath, sessions.CallbackHandler())
+		if basePath != "" {
+			public.Handle(basePath+config.SessionOAuthCallbackPath, sessions.CallbackHandler())
+		}
+		handler = public
+	}
+
+	httpServer := &http.Server{
+		Addr:              cfg.Server.ListenAddr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Server shutdown error: %v", err)
+		}
+	}()
+
+	log.Printf("Starting MCP %s server on %s (public URL %s)...", cfg.Server.Transport, cfg.Server.ListenAddr, cfg.Server.BaseURL)
+	var err error
+	if cfg.Server.TLS() {
+		err = httpServer.ListenAndServeTLS(cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile)
+	} else {
+		err = httpServer.ListenAndServe()
+	}
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
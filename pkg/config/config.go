@@ -0,0 +1,631 @@
+// Package config loads the server configuration from defaults, an optional
+// JSON config file, environment variables and command-line flags, in that
+// order of precedence (flags win).
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google-drive-mcp-server/pkg/driveapi"
+	"google-drive-mcp-server/pkg/httpauth"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// Transports the MCP server can be exposed over.
+const (
+	TransportStdio = "stdio"
+	TransportSSE   = "sse"
+	TransportHTTP  = "http" // Streamable HTTP
+)
+
+// SessionOAuthCallbackPath is where Google redirects browsers back to the
+// server in per-session OAuth mode, relative to the base URL.
+const SessionOAuthCallbackPath = "/oauth/callback"
+
+// DefaultAccount names the account configured by the top-level auth section.
+const DefaultAccount = "default"
+
+// Config is the complete server configuration.
+type Config struct {
+	// Auth configures the default Drive account.
+	Auth AuthConfig `json:"auth"`
+	// Accounts configures additional named Drive accounts. Empty OAuth
+	// client settings are inherited from Auth, and each OAuth account gets
+	// its own token file next to Auth's unless one is set.
+	Accounts map[string]AuthConfig `json:"accounts"`
+	// ReadOnly requests least-privilege scopes (drive.readonly unless scopes
+	// are set explicitly) and leaves out every tool that writes to Drive.
+	ReadOnly bool         `json:"read_only"`
+	Server   ServerConfig `json:"server"`
+	Tools    ToolsConfig  `json:"tools"`
+}
+
+// readOnlyScopes are the scopes allowed in read-only mode. drive.file is
+// included because it only grants access to files the app opened or created.
+var readOnlyScopes = map[string]bool{
+	drive.DriveReadonlyScope:         true,
+	drive.DriveFileScope:             true,
+	drive.DriveMetadataReadonlyScope: true,
+}
+
+// deviceFlowScopes are the scopes Google grants through the device
+// authorization flow. It refuses drive and drive.readonly.
+var deviceFlowScopes = map[string]bool{
+	drive.DriveFileScope:                               true,
+	drive.DriveAppdataScope:                            true,
+	"https://www.googleapis.com/auth/userinfo.email":   true,
+	"https://www.googleapis.com/auth/userinfo.profile": true,
+}
+
+// AuthConfig selects and locates the Google credentials of one account.
+type AuthConfig struct {
+	Mode                  string `json:"mode"`
+	CredentialsFile       string `json:"credentials_file"`
+	OAuthClientSecretFile string `json:"oauth_client_secret_file"`
+	TokenFile             string `json:"token_file"`
+	OAuthFlow             string `json:"oauth_flow"`
+	OAuthCallbackAddr     string `json:"oauth_callback_addr"`
+	ImpersonateUser       string `json:"impersonate_user"`
+	// AsUserAllowlist lists who the as_user argument may name: email
+	// addresses, or "@example.com" for a whole domain. Empty refuses as_user.
+	AsUserAllowlist []string `json:"as_user_allowlist"`
+	Scopes          []string `json:"scopes"`
+}
+
+// ServerConfig controls how the MCP server is exposed. Everything but
+// Transport only applies to the HTTP transports.
+type ServerConfig struct {
+	Transport  string `json:"transport"`
+	ListenAddr string `json:"listen_addr"`
+	// BaseURL is the public URL clients reach the server at, including any
+	// path prefix added by a reverse proxy. It defaults to localhost on the
+	// listen port.
+	BaseURL     string `json:"base_url"`
+	TLSCertFile string `json:"tls_cert_file"`
+	TLSKeyFile  string `json:"tls_key_file"`
+	// Auth authenticates clients of the HTTP transports.
+	Auth InboundAuthConfig `json:"auth"`
+	// PerSessionAuth makes every MCP session authorize its own Google
+	// account through the OAuth client of the auth section, instead of
+	// sharing the configured accounts.
+	PerSessionAuth bool `json:"per_session_auth"`
+	// DisableAsUser refuses the as_user argument of every tool call.
+	DisableAsUser bool `json:"disable_as_user"`
+}
+
+// InboundAuthConfig lists the credentials accepted from MCP clients. A
+// request is let in if it matches any API key or carries a valid JWT. With
+// neither configured the HTTP transports are open to anyone who can reach them.
+type InboundAuthConfig struct {
+	// APIKeys maps a key name, recorded as the caller in audit logs, to the key.
+	APIKeys map[string]string `json:"api_keys"`
+	JWT     JWTAuthConfig     `json:"jwt"`
+}
+
+// JWTAuthConfig accepts bearer JWTs signed by a key in a JWKS.
+type JWTAuthConfig struct {
+	JWKSURL  string `json:"jwks_url"`
+	Issuer   string `json:"issuer"`
+	Audience string `json:"audience"`
+}
+
+// ToolsConfig controls which tools are registered.
+type ToolsConfig struct {
+	Disabled []string `json:"disabled"`
+}
+
+// Default returns the configuration used when nothing else is set. The paths
+// match the volumes mounted by the Docker image.
+func Default() *Config {
+	return &Config{
+		Auth: AuthConfig{
+			OAuthClientSecretFile: "/app/secrets/Oauth.json",
+			TokenFile:             "/app/data/token.json",
+			OAuthFlow:             string(driveapi.OAuthFlowLoopback),
+		},
+		Server: ServerConfig{
+			Transport:  TransportSSE,
+			ListenAddr: ":8080",
+		},
+	}
+}
+
+// Load builds the configuration from args (usually os.Args[1:]) and the environment.
+// The config file is named by -config or GDRIVE_CONFIG.
+func Load(args []string) (*Config, error) {
+	fs := flag.NewFlagSet("google-drive-mcp-server", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("GDRIVE_CONFIG"), "Path to a JSON config file (env GDRIVE_CONFIG)")
+	authMode := fs.String("auth-mode", "", "Credential source: oauth, service_account or adc (env GDRIVE_AUTH_MODE)")
+	credentialsFile := fs.String("credentials-file", "", "Service account key file (env GOOGLE_APPLICATION_CREDENTIALS)")
+	clientSecretFile := fs.String("oauth-client-secret-file", "", "OAuth client secret JSON (env GDRIVE_OAUTH_CLIENT_SECRET_FILE)")
+	tokenFile := fs.String("token-file", "", "Where the OAuth token is stored (env GDRIVE_TOKEN_FILE)")
+	oauthFlow := fs.String("oauth-flow", "", "OAuth authorization flow: loopback or device (env GDRIVE_OAUTH_FLOW)")
+	callbackAddr := fs.String("oauth-callback-addr", "", "Listen address for the OAuth loopback callback (env GDRIVE_OAUTH_CALLBACK_ADDR)")
+	impersonate := fs.String("impersonate-user", "", "Workspace user to impersonate with domain-wide delegation (env GDRIVE_IMPERSONATE_USER)")
+	asUserAllowlist := fs.String("as-user-allowlist", "", "Comma-separated users and @domains as_user may name (env GDRIVE_AS_USER_ALLOWLIST)")
+	scopes := fs.String("scopes", "", "Comma-separated OAuth scopes (env GDRIVE_SCOPES)")
+	transport := fs.String("transport", "", "MCP transport: stdio, sse or http (env GDRIVE_TRANSPORT)")
+	listenAddr := fs.String("listen-addr", "", "Address the MCP server listens on (env GDRIVE_LISTEN_ADDR)")
+	baseURL := fs.String("base-url", "", "Public base URL of the MCP server (env GDRIVE_BASE_URL)")
+	tlsCertFile := fs.String("tls-cert-file", "", "TLS certificate for the HTTP transports (env GDRIVE_TLS_CERT_FILE)")
+	tlsKeyFile := fs.String("tls-key-file", "", "TLS private key for the HTTP transports (env GDRIVE_TLS_KEY_FILE)")
+	jwksURL := fs.String("jwks-url", "", "JWKS URL for validating client JWTs (env GDRIVE_JWKS_URL)")
+	jwtIssuer := fs.String("jwt-issuer", "", "Required issuer of client JWTs (env GDRIVE_JWT_ISSUER)")
+	jwtAudience := fs.String("jwt-audience", "", "Required audience of client JWTs (env GDRIVE_JWT_AUDIENCE)")
+	disabledTools := fs.String("disabled-tools", "", "Comma-separated tool names not to register (env GDRIVE_DISABLED_TOOLS)")
+	perSessionAuth := fs.Bool("per-session-auth", false, "Have each MCP session authorize its own Google account (env GDRIVE_PER_SESSION_AUTH)")
+	disableAsUser := fs.Bool("disable-as-user", false, "Refuse the as_user argument on the HTTP transports (env GDRIVE_DISABLE_AS_USER)")
+	readOnly := fs.Bool("read-only", false, "Request read-only scopes and disable write tools (env GDRIVE_READ_ONLY)")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	flagSet := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { flagSet[f.Name] = true })
+
+	cfg := Default()
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	// Environment overrides the file.
+	setString(&cfg.Auth.Mode, os.Getenv("GDRIVE_AUTH_MODE"))
+	setString(&cfg.Auth.CredentialsFile, os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"))
+	setString(&cfg.Auth.OAuthClientSecretFile, os.Getenv("GDRIVE_OAUTH_CLIENT_SECRET_FILE"))
+	setString(&cfg.Auth.TokenFile, os.Getenv("GDRIVE_TOKEN_FILE"))
+	setString(&cfg.Auth.OAuthFlow, os.Getenv("GDRIVE_OAUTH_FLOW"))
+	setString(&cfg.Auth.OAuthCallbackAddr, os.Getenv("GDRIVE_OAUTH_CALLBACK_ADDR"))
+	setString(&cfg.Auth.ImpersonateUser, os.Getenv("GDRIVE_IMPERSONATE_USER"))
+	setList(&cfg.Auth.AsUserAllowlist, os.Getenv("GDRIVE_AS_USER_ALLOWLIST"))
+	setList(&cfg.Auth.Scopes, os.Getenv("GDRIVE_SCOPES"))
+	setString(&cfg.Server.Transport, os.Getenv("GDRIVE_TRANSPORT"))
+	setString(&cfg.Server.ListenAddr, os.Getenv("GDRIVE_LISTEN_ADDR"))
+	setString(&cfg.Server.BaseURL, os.Getenv("GDRIVE_BASE_URL"))
+	setString(&cfg.Server.TLSCertFile, os.Getenv("GDRIVE_TLS_CERT_FILE"))
+	setString(&cfg.Server.TLSKeyFile, os.Getenv("GDRIVE_TLS_KEY_FILE"))
+	if err := setKeys(&cfg.Server.Auth.APIKeys, os.Getenv("GDRIVE_API_KEYS")); err != nil {
+		return nil, fmt.Errorf("GDRIVE_API_KEYS: %w", err)
+	}
+	setString(&cfg.Server.Auth.JWT.JWKSURL, os.Getenv("GDRIVE_JWKS_URL"))
+	setString(&cfg.Server.Auth.JWT.Issuer, os.Getenv("GDRIVE_JWT_ISSUER"))
+	setString(&cfg.Server.Auth.JWT.Audience, os.Getenv("GDRIVE_JWT_AUDIENCE"))
+	setList(&cfg.Tools.Disabled, os.Getenv("GDRIVE_DISABLED_TOOLS"))
+	if err := setBool(&cfg.ReadOnly, os.Getenv("GDRIVE_READ_ONLY")); err != nil {
+		return nil, fmt.Errorf("GDRIVE_READ_ONLY: %w", err)
+	}
+	if err := setBool(&cfg.Server.PerSessionAuth, os.Getenv("GDRIVE_PER_SESSION_AUTH")); err != nil {
+		return nil, fmt.Errorf("GDRIVE_PER_SESSION_AUTH: %w", err)
+	}
+	if err := setBool(&cfg.Server.DisableAsUser, os.Getenv("GDRIVE_DISABLE_AS_USER")); err != nil {
+		return nil, fmt.Errorf("GDRIVE_DISABLE_AS_USER: %w", err)
+	}
+
+	// Flags override everything.
+	setString(&cfg.Auth.Mode, *authMode)
+	setString(&cfg.Auth.CredentialsFile, *credentialsFile)
+	setString(&cfg.Auth.OAuthClientSecretFile, *clientSecretFile)
+	setString(&cfg.Auth.TokenFile, *tokenFile)
+	setString(&cfg.Auth.OAuthFlow, *oauthFlow)
+	setString(&cfg.Auth.OAuthCallbackAddr, *callbackAddr)
+	setString(&cfg.Auth.ImpersonateUser, *impersonate)
+	setList(&cfg.Auth.AsUserAllowlist, *asUserAllowlist)
+	setList(&cfg.Auth.Scopes, *scopes)
+	setString(&cfg.Server.Transport, *transport)
+	setString(&cfg.Server.ListenAddr, *listenAddr)
+	setString(&cfg.Server.BaseURL, *baseURL)
+	setString(&cfg.Server.TLSCertFile, *tlsCertFile)
+	setString(&cfg.Server.TLSKeyFile, *tlsKeyFile)
+	setString(&cfg.Server.Auth.JWT.JWKSURL, *jwksURL)
+	setString(&cfg.Server.Auth.JWT.Issuer, *jwtIssuer)
+	setString(&cfg.Server.Auth.JWT.Audience, *jwtAudience)
+	setList(&cfg.Tools.Disabled, *disabledTools)
+	if flagSet["read-only"] {
+		cfg.ReadOnly = *readOnly
+	}
+	if flagSet["per-session-auth"] {
+		cfg.Server.PerSessionAuth = *perSessionAuth
+	}
+	if flagSet["disable-as-user"] {
+		cfg.Server.DisableAsUser = *disableAsUser
+	}
+
+	if cfg.Server.BaseURL == "" {
+		cfg.Server.BaseURL = cfg.Server.defaultBaseURL()
+	}
+	cfg.Auth.inferMode()
+	for name, account := range cfg.Accounts {
+		inheritString(&account.OAuthClientSecretFile, cfg.Auth.OAuthClientSecretFile)
+		inheritString(&account.OAuthFlow, cfg.Auth.OAuthFlow)
+		if account.TokenFile == "" && cfg.Auth.TokenFile != "" {
+			account.TokenFile = filepath.Join(filepath.Dir(cfg.Auth.TokenFile), "token-"+name+".json")
+		}
+		account.inferMode()
+		cfg.Accounts[name] = account
+	}
+	return cfg, nil
+}
+
+// loadFile merges the JSON config file at path into c.
+func (c *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("unable to open config file '%s': %w", path, err)
+	}
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("unable to parse config file '%s': %w", path, err)
+	}
+	return nil
+}
+
+// Validate reports every problem with the configuration at once.
+func (c *Config) Validate() error {
+	var errs []error
+
+	errs = append(errs, c.Auth.validate("auth", c.ReadOnly)...)
+	tokenFiles := map[string]string{}
+	if c.Auth.Mode == string(driveapi.AuthModeOAuth) {
+		tokenFiles[c.Auth.TokenFile] = DefaultAccount
+	}
+	for _, name := range c.accountNames() {
+		account := c.Accounts[name]
+		prefix := "accounts." + name
+		if name == "" || name == DefaultAccount {
+			errs = append(errs, fmt.Errorf("%s: account name must not be empty or '%s'", prefix, DefaultAccount))
+		}
+		errs = append(errs, account.validate(prefix, c.ReadOnly)...)
+		if account.Mode == string(driveapi.AuthModeOAuth) {
+			if other, ok := tokenFiles[account.TokenFile]; ok {
+				errs = append(errs, fmt.Errorf("%s.token_file is already used by account '%s'", prefix, other))
+			}
+			tokenFiles[account.TokenFile] = name
+		}
+	}
+
+	switch c.Server.Transport {
+	case TransportStdio:
+	case TransportSSE, TransportHTTP:
+		if _, _, err := net.SplitHostPort(c.Server.ListenAddr); err != nil {
+			errs = append(errs, fmt.Errorf("server.listen_addr: %w", err))
+		}
+		if u, err := url.Parse(c.Server.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" {
+			errs = append(errs, fmt.Errorf("server.base_url '%s' must be an absolute http(s) URL without a query", c.Server.BaseURL))
+		}
+		if (c.Server.TLSCertFile == "") != (c.Server.TLSKeyFile == "") {
+			errs = append(errs, errors.New("server.tls_cert_file and server.tls_key_file must be set together"))
+		}
+		if c.Server.TLSCertFile != "" {
+			if err := checkFile(c.Server.TLSCertFile); err != nil {
+				errs = append(errs, fmt.Errorf("server.tls_cert_file: %w", err))
+			}
+		}
+		if c.Server.TLSKeyFile != "" {
+			if err := checkFile(c.Server.TLSKeyFile); err != nil {
+				errs = append(errs, fmt.Errorf("server.tls_key_file: %w", err))
+			}
+		}
+		errs = append(errs, c.Server.Auth.validate()...)
+	default:
+		errs = append(errs, fmt.Errorf("server.transport '%s' must be one of stdio, sse, http", c.Server.Transport))
+	}
+
+	if c.Server.PerSessionAuth {
+		if c.Server.Transport == TransportStdio {
+			errs = append(errs, errors.New("server.per_session_auth requires the sse or http transport"))
+		}
+		if c.Auth.Mode != string(driveapi.AuthModeOAuth) {
+			errs = append(errs, errors.New("server.per_session_auth requires auth.mode oauth"))
+		}
+		if len(c.Accounts) > 0 {
+			errs = append(errs, errors.New("server.per_session_auth cannot be combined with named accounts"))
+		}
+		if c.Server.Auth.Authenticator() == nil {
+			errs = append(errs, errors.New("server.per_session_auth requires server.auth api_keys or jwt, so that each session is tied to its caller"))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validate checks the inbound auth settings.
+func (a *InboundAuthConfig) validate() []error {
+	var errs []error
+	seen := map[string]string{}
+	for _, name := range sortedKeys(a.APIKeys) {
+		key := a.APIKeys[name]
+		if name == "" || key == "" {
+			errs = append(errs, fmt.Errorf("server.auth.api_keys: key names and keys must not be empty"))
+		} else if len(key) < 16 {
+			errs = append(errs, fmt.Errorf("server.auth.api_keys.%s: key must be at least 16 characters", name))
+		}
+		if other, ok := seen[key]; ok && key != "" {
+			errs = append(errs, fmt.Errorf("server.auth.api_keys.%s: key is already used by '%s'", name, other))
+		}
+		seen[key] = name
+	}
+	if a.JWT.JWKSURL != "" {
+		if u, err := url.Parse(a.JWT.JWKSURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("server.auth.jwt.jwks_url '%s' must be an absolute http(s) URL", a.JWT.JWKSURL))
+		}
+		// Without them any token signed by the JWKS, issued for any service, would be accepted.
+		if a.JWT.Audience == "" {
+			errs = append(errs, errors.New("server.auth.jwt.audience is required with jwks_url, so that tokens issued for other services are refused"))
+		}
+		if a.JWT.Issuer == "" {
+			errs = append(errs, errors.New("server.auth.jwt.issuer is required with jwks_url"))
+		}
+	} else if a.JWT.Issuer != "" || a.JWT.Audience != "" {
+		errs = append(errs, errors.New("server.auth.jwt.jwks_url is required when issuer or audience is set"))
+	}
+	return errs
+}
+
+// Authenticator builds the authenticator for the configured credentials, or
+// returns nil when none are configured.
+func (a *InboundAuthConfig) Authenticator() httpauth.Authenticator {
+	var chain httpauth.Chain
+	if len(a.APIKeys) > 0 {
+		chain = append(chain, httpauth.NewAPIKeys(a.APIKeys))
+	}
+	if a.JWT.JWKSURL != "" {
+		chain = append(chain, httpauth.NewJWTValidator(httpauth.JWTConfig{
+			JWKSURL:  a.JWT.JWKSURL,
+			Issuer:   a.JWT.Issuer,
+			Audience: a.JWT.Audience,
+		}))
+	}
+	if len(chain) == 0 {
+		return nil
+	}
+	return chain
+}
+
+// SessionOAuthRedirectURL is the redirect URL to register on the OAuth
+// client for per-session authorization.
+func (s *ServerConfig) SessionOAuthRedirectURL() string {
+	return strings.TrimSuffix(s.BaseURL, "/") + SessionOAuthCallbackPath
+}
+
+// SessionScopes returns the scopes requested from each session's user.
+func (c *Config) SessionScopes() []string {
+	return c.Auth.driveAuth(c.ReadOnly).Scopes
+}
+
+// TLS reports whether the HTTP transports serve TLS themselves.
+func (s *ServerConfig) TLS() bool {
+	return s.TLSCertFile != "" && s.TLSKeyFile != ""
+}
+
+// BasePath returns the path component of BaseURL without a trailing slash.
+func (s *ServerConfig) BasePath() string {
+	u, err := url.Parse(s.BaseURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(u.Path, "/")
+}
+
+// defaultBaseURL points at localhost on the listen port.
+func (s *ServerConfig) defaultBaseURL() string {
+	scheme := "http"
+	if s.TLS() {
+		scheme = "https"
+	}
+	host, port, err := net.SplitHostPort(s.ListenAddr)
+	if err != nil {
+		return ""
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return scheme + "://" + net.JoinHostPort(host, port)
+}
+
+// inferMode fills in the auth mode when it was left empty: a service-account
+// key implies that mode, otherwise the OAuth user flow is used.
+func (a *AuthConfig) inferMode() {
+	if a.Mode != "" {
+		return
+	}
+	if a.CredentialsFile != "" {
+		a.Mode = string(driveapi.AuthModeServiceAccount)
+	} else {
+		a.Mode = string(driveapi.AuthModeOAuth)
+	}
+}
+
+// validate checks one account's auth settings; prefix names it in errors.
+func (a *AuthConfig) validate(prefix string, readOnly bool) []error {
+	var errs []error
+
+	switch driveapi.AuthMode(a.Mode) {
+	case driveapi.AuthModeServiceAccount:
+		if a.CredentialsFile == "" {
+			errs = append(errs, fmt.Errorf("%s.credentials_file is required for service_account auth", prefix))
+		} else if err := checkFile(a.CredentialsFile); err != nil {
+			errs = append(errs, fmt.Errorf("%s.credentials_file: %w", prefix, err))
+		}
+	case driveapi.AuthModeADC:
+	case driveapi.AuthModeOAuth:
+		if a.OAuthClientSecretFile == "" {
+			errs = append(errs, fmt.Errorf("%s.oauth_client_secret_file is required for oauth auth", prefix))
+		} else if err := checkFile(a.OAuthClientSecretFile); err != nil {
+			errs = append(errs, fmt.Errorf("%s.oauth_client_secret_file: %w", prefix, err))
+		}
+		if a.TokenFile == "" {
+			errs = append(errs, fmt.Errorf("%s.token_file is required for oauth auth", prefix))
+		}
+		if a.ImpersonateUser != "" {
+			errs = append(errs, fmt.Errorf("%s.impersonate_user is not supported with oauth auth", prefix))
+		}
+		if len(a.AsUserAllowlist) > 0 {
+			errs = append(errs, fmt.Errorf("%s.as_user_allowlist is not supported with oauth auth", prefix))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("%s.mode '%s' must be one of oauth, service_account, adc", prefix, a.Mode))
+	}
+
+	switch driveapi.OAuthFlow(a.OAuthFlow) {
+	case driveapi.OAuthFlowLoopback, driveapi.OAuthFlowDevice, "":
+	default:
+		errs = append(errs, fmt.Errorf("%s.oauth_flow '%s' must be loopback or device", prefix, a.OAuthFlow))
+	}
+	if a.OAuthCallbackAddr != "" {
+		if _, _, err := net.SplitHostPort(a.OAuthCallbackAddr); err != nil {
+			errs = append(errs, fmt.Errorf("%s.oauth_callback_addr: %w", prefix, err))
+		}
+	}
+	for _, entry := range a.AsUserAllowlist {
+		addr := entry
+		if strings.HasPrefix(entry, "@") {
+			addr = "user" + entry
+		}
+		if parsed, err := mail.ParseAddress(addr); err != nil || parsed.Address != addr {
+			errs = append(errs, fmt.Errorf("%s.as_user_allowlist: '%s' is not an email address or @domain", prefix, entry))
+		}
+	}
+	for _, scope := range a.Scopes {
+		if !strings.HasPrefix(scope, "https://") {
+			errs = append(errs, fmt.Errorf("%s.scopes: '%s' is not a scope URL", prefix, scope))
+		} else if readOnly && !readOnlyScopes[scope] {
+			errs = append(errs, fmt.Errorf("%s.scopes: '%s' is not allowed in read-only mode", prefix, scope))
+		}
+	}
+	if a.Mode == string(driveapi.AuthModeOAuth) && a.OAuthFlow == string(driveapi.OAuthFlowDevice) {
+		if len(a.Scopes) == 0 {
+			errs = append(errs, fmt.Errorf("%s.scopes must be set for the device oauth flow: Google does not grant the default drive or drive.readonly scope to devices, use '%s'", prefix, drive.DriveFileScope))
+		}
+		for _, scope := range a.Scopes {
+			if !deviceFlowScopes[scope] {
+				errs = append(errs, fmt.Errorf("%s.scopes: '%s' is not granted by Google's device oauth flow, use '%s' or the loopback flow", prefix, scope, drive.DriveFileScope))
+			}
+		}
+	}
+	return errs
+}
+
+// DriveAccounts converts the auth sections into driveapi accounts. The
+// default account comes first, followed by the named accounts in name order.
+func (c *Config) DriveAccounts() []driveapi.AccountConfig {
+	accounts := []driveapi.AccountConfig{{Name: DefaultAccount, Auth: c.Auth.driveAuth(c.ReadOnly)}}
+	for _, name := range c.accountNames() {
+		account := c.Accounts[name]
+		accounts = append(accounts, driveapi.AccountConfig{Name: name, Auth: account.driveAuth(c.ReadOnly)})
+	}
+	return accounts
+}
+
+func (c *Config) accountNames() []string {
+	return sortedKeys(c.Accounts)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (a *AuthConfig) driveAuth(readOnly bool) driveapi.AuthConfig {
+	scopes := a.Scopes
+	if readOnly && len(scopes) == 0 {
+		scopes = []string{drive.DriveReadonlyScope}
+	}
+	return driveapi.AuthConfig{
+		Mode:                  driveapi.AuthMode(a.Mode),
+		CredentialsFile:       a.CredentialsFile,
+		OAuthClientSecretFile: a.OAuthClientSecretFile,
+		TokenFile:             a.TokenFile,
+		OAuthFlow:             driveapi.OAuthFlow(a.OAuthFlow),
+		OAuthCallbackAddr:     a.OAuthCallbackAddr,
+		Subject:               a.ImpersonateUser,
+		AsUserAllowlist:       a.AsUserAllowlist,
+		Scopes:                scopes,
+	}
+}
+
+func setString(dst *string, v string) {
+	if v != "" {
+		*dst = v
+	}
+}
+
+func inheritString(dst *string, v string) {
+	if *dst == "" {
+		*dst = v
+	}
+}
+
+func setList(dst *[]string, v string) {
+	if v == "" {
+		return
+	}
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	*dst = out
+}
+
+// setKeys parses a comma-separated list of name=key pairs.
+func setKeys(dst *map[string]string, v string) error {
+	if v == "" {
+		return nil
+	}
+	keys := map[string]string{}
+	for i, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		// Never echo the entry: it may be a key without a name.
+		name, key, ok := strings.Cut(item, "=")
+		if !ok {
+			return fmt.Errorf("entry %d is not a name=key pair", i+1)
+		}
+		keys[strings.TrimSpace(name)] = strings.TrimSpace(key)
+	}
+	*dst = keys
+	return nil
+}
+
+func setBool(dst *bool, v string) error {
+	if v == "" {
+		return nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return err
+	}
+	*dst = b
+	return nil
+}
+
+func checkFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("'%s' is a directory", path)
+	}
+	return nil
+}
@@ -0,0 +1,317 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// configEnv lists every environment variable Load reads.
+var configEnv = []string{
+	"GDRIVE_CONFIG", "GDRIVE_AUTH_MODE", "GOOGLE_APPLICATION_CREDENTIALS", "GDRIVE_OAUTH_CLIENT_SECRET_FILE",
+	"GDRIVE_TOKEN_FILE", "GDRIVE_OAUTH_FLOW", "GDRIVE_OAUTH_CALLBACK_ADDR", "GDRIVE_IMPERSONATE_USER",
+	"GDRIVE_AS_USER_ALLOWLIST", "GDRIVE_SCOPES", "GDRIVE_TRANSPORT", "GDRIVE_LISTEN_ADDR", "GDRIVE_BASE_URL",
+	"GDRIVE_TLS_CERT_FILE", "GDRIVE_TLS_KEY_FILE", "GDRIVE_API_KEYS", "GDRIVE_JWKS_URL", "GDRIVE_JWT_ISSUER",
+	"GDRIVE_JWT_AUDIENCE", "GDRIVE_DISABLED_TOOLS", "GDRIVE_READ_ONLY", "GDRIVE_PER_SESSION_AUTH",
+	"GDRIVE_DISABLE_AS_USER",
+}
+
+// setEnv clears the configuration environment and then sets env.
+func setEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	for _, name := range configEnv {
+		t.Setenv(name, env[name])
+	}
+}
+
+// writeFile creates a file in a temporary directory and returns its path.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestValidateDeviceFlowScopes(t *testing.T) {
+	secret := writeFile(t, "client.json", "{}")
+	tests := []struct {
+		scopes   []string
+		readOnly bool
+		wantErr  string
+	}{
+		{nil, false, "auth.scopes must be set for the device oauth flow"},
+		{nil, true, "auth.scopes must be set for the device oauth flow"},
+		{[]string{"https://www.googleapis.com/auth/drive"}, false, "'https://www.googleapis.com/auth/drive' is not granted by Google's device oauth flow"},
+		{[]string{"https://www.googleapis.com/auth/drive.readonly"}, true, "'https://www.googleapis.com/auth/drive.readonly' is not granted by Google's device oauth flow"},
+		{[]string{"https://www.googleapis.com/auth/drive.file"}, false, ""},
+		{[]string{"https://www.googleapis.com/auth/drive.file"}, true, ""},
+	}
+	for _, tt := range tests {
+		cfg := Default()
+		cfg.Server.Transport = TransportStdio
+		cfg.ReadOnly = tt.readOnly
+		cfg.Auth.Mode = "oauth"
+		cfg.Auth.OAuthClientSecretFile = secret
+		cfg.Auth.OAuthFlow = "device"
+		cfg.Auth.Scopes = tt.scopes
+		err := cfg.Validate()
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("scopes %v: %v", tt.scopes, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("scopes %v: got error %v, want '%s'", tt.scopes, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateJWTRequiresAudienceAndIssuer(t *testing.T) {
+	tests := []struct {
+		jwt     JWTAuthConfig
+		wantErr []string
+	}{
+		{JWTAuthConfig{JWKSURL: "https://idp.example.com/jwks"}, []string{"server.auth.jwt.audience is required", "server.auth.jwt.issuer is required"}},
+		{JWTAuthConfig{JWKSURL: "https://idp.example.com/jwks", Issuer: "https://idp.example.com"}, []string{"server.auth.jwt.audience is required"}},
+		{JWTAuthConfig{JWKSURL: "https://idp.example.com/jwks", Audience: "drive-mcp"}, []string{"server.auth.jwt.issuer is required"}},
+		{JWTAuthConfig{Audience: "drive-mcp"}, []string{"server.auth.jwt.jwks_url is required"}},
+		{JWTAuthConfig{JWKSURL: "https://idp.example.com/jwks", Issuer: "https://idp.example.com", Audience: "drive-mcp"}, nil},
+	}
+	for _, tt := range tests {
+		a := InboundAuthConfig{JWT: tt.jwt}
+		errs := a.validate()
+		if len(errs) != len(tt.wantErr) {
+			t.Errorf("%+v: got errors %v, want %q", tt.jwt, errs, tt.wantErr)
+			continue
+		}
+		for i, want := range tt.wantErr {
+			if !strings.Contains(errs[i].Error(), want) {
+				t.Errorf("%+v: got error '%v', want '%s'", tt.jwt, errs[i], want)
+			}
+		}
+	}
+}
+
+func TestValidateAsUserAllowlist(t *testing.T) {
+	key := writeFile(t, "key.json", "{}")
+	tests := []struct {
+		mode      string
+		allowlist []string
+		wantErr   string
+	}{
+		{"service_account", []string{"boss@example.com", "@example.com"}, ""},
+		{"service_account", []string{"example.com"}, "'example.com' is not an email address or @domain"},
+		{"service_account", []string{"Boss <boss@example.com>"}, "is not an email address or @domain"},
+		{"service_account", []string{"@"}, "'@' is not an email address or @domain"},
+		{"oauth", []string{"boss@example.com"}, "auth.as_user_allowlist is not supported with oauth auth"},
+	}
+	for _, tt := range tests {
+		cfg := Default()
+		cfg.Server.Transport = TransportStdio
+		cfg.Auth.Mode = tt.mode
+		cfg.Auth.CredentialsFile = key
+		cfg.Auth.OAuthClientSecretFile = key
+		cfg.Auth.AsUserAllowlist = tt.allowlist
+		err := cfg.Validate()
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%v: %v", tt.allowlist, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%v: got error %v, want '%s'", tt.allowlist, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := writeFile(t, "config.json", `{
+		"auth": {"token_file": "/file/token.json", "oauth_flow": "device", "scopes": ["https://www.googleapis.com/auth/drive.file"]},
+		"read_only": true,
+		"server": {"transport": "http", "listen_addr": ":9000", "per_session_auth": true},
+		"tools": {"disabled": ["summarize_content"]}
+	}`)
+	setEnv(t, map[string]string{
+		"GDRIVE_CONFIG":         file,
+		"GDRIVE_TOKEN_FILE":     "/env/token.json",
+		"GDRIVE_LISTEN_ADDR":    ":9100",
+		"GDRIVE_READ_ONLY":      "false",
+		"GDRIVE_DISABLED_TOOLS": "list_accounts, reauthorize",
+	})
+	cfg, err := Load([]string{"-listen-addr", ":9200", "-per-session-auth=false"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"default", cfg.Auth.OAuthClientSecretFile, "/app/secrets/Oauth.json"},
+		{"inferred mode", cfg.Auth.Mode, "oauth"},
+		{"file over default", cfg.Auth.OAuthFlow, "device"},
+		{"file over default", cfg.Server.Transport, "http"},
+		{"file list", cfg.Auth.Scopes, []string{"https://www.googleapis.com/auth/drive.file"}},
+		{"env over file", cfg.Auth.TokenFile, "/env/token.json"},
+		{"env bool over file", cfg.ReadOnly, false},
+		{"env list over file", cfg.Tools.Disabled, []string{"list_accounts", "reauthorize"}},
+		{"flag over env", cfg.Server.ListenAddr, ":9200"},
+		{"flag bool over file", cfg.Server.PerSessionAuth, false},
+		{"base url from listen addr", cfg.Server.BaseURL, "http://localhost:9200"},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s: got %#v, want %#v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	// An unset bool flag leaves the environment's value.
+	setEnv(t, map[string]string{"GDRIVE_READ_ONLY": "true"})
+	if cfg, err := Load(nil); err != nil || !cfg.ReadOnly {
+		t.Errorf("GDRIVE_READ_ONLY=true without the flag: read_only %v, %v", cfg != nil && cfg.ReadOnly, err)
+	}
+	if cfg, err := Load([]string{"-read-only=false"}); err != nil || cfg.ReadOnly {
+		t.Errorf("-read-only=false over the environment: read_only %v, %v", cfg != nil && cfg.ReadOnly, err)
+	}
+}
+
+func TestLoadInfersAuthMode(t *testing.T) {
+	file := writeFile(t, "config.json", `{
+		"auth": {"token_file": "/data/token.json", "oauth_client_secret_file": "/secrets/client.json"},
+		"accounts": {"work": {"credentials_file": "/secrets/work.json"}, "home": {}}
+	}`)
+	tests := []struct {
+		env      map[string]string
+		args     []string
+		wantMode string
+	}{
+		{nil, nil, "oauth"},
+		{map[string]string{"GOOGLE_APPLICATION_CREDENTIALS": "/secrets/key.json"}, nil, "service_account"},
+		{map[string]string{"GOOGLE_APPLICATION_CREDENTIALS": "/secrets/key.json", "GDRIVE_AUTH_MODE": "adc"}, nil, "adc"},
+		{map[string]string{"GDRIVE_AUTH_MODE": "adc"}, []string{"-auth-mode", "oauth"}, "oauth"},
+		{nil, []string{"-credentials-file", "/secrets/key.json"}, "service_account"},
+	}
+	for _, tt := range tests {
+		env := map[string]string{"GDRIVE_CONFIG": file}
+		for k, v := range tt.env {
+			env[k] = v
+		}
+		setEnv(t, env)
+		cfg, err := Load(tt.args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Auth.Mode != tt.wantMode {
+			t.Errorf("env %v, args %v: mode '%s', want '%s'", tt.env, tt.args, cfg.Auth.Mode, tt.wantMode)
+		}
+	}
+
+	// Named accounts infer their own mode and inherit the OAuth client.
+	setEnv(t, map[string]string{"GDRIVE_CONFIG": file})
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	work, home := cfg.Accounts["work"], cfg.Accounts["home"]
+	if work.Mode != "service_account" || home.Mode != "oauth" {
+		t.Errorf("account modes: work '%s', home '%s'", work.Mode, home.Mode)
+	}
+	if home.OAuthClientSecretFile != "/secrets/client.json" || home.TokenFile != "/data/token-home.json" {
+		t.Errorf("home account: %+v", home)
+	}
+}
+
+func TestLoadBoolEnv(t *testing.T) {
+	for v, want := range map[string]bool{"": false, "true": true, "1": true, "TRUE": true, "t": true, "false": false, "0": false} {
+		setEnv(t, map[string]string{"GDRIVE_READ_ONLY": v, "GDRIVE_PER_SESSION_AUTH": v, "GDRIVE_DISABLE_AS_USER": v})
+		cfg, err := Load(nil)
+		if err != nil {
+			t.Errorf("'%s': %v", v, err)
+			continue
+		}
+		if cfg.ReadOnly != want || cfg.Server.PerSessionAuth != want || cfg.Server.DisableAsUser != want {
+			t.Errorf("'%s': got %v, %v, %v, want %v", v, cfg.ReadOnly, cfg.Server.PerSessionAuth, cfg.Server.DisableAsUser, want)
+		}
+	}
+	for _, name := range []string{"GDRIVE_READ_ONLY", "GDRIVE_PER_SESSION_AUTH", "GDRIVE_DISABLE_AS_USER"} {
+		setEnv(t, map[string]string{name: "yes"})
+		if _, err := Load(nil); err == nil || !strings.HasPrefix(err.Error(), name+":") {
+			t.Errorf("%s=yes: got error %v", name, err)
+		}
+	}
+	setEnv(t, map[string]string{"GDRIVE_API_KEYS": "ci=key,secret-without-name"})
+	if _, err := Load(nil); err == nil || strings.Contains(err.Error(), "secret-without-name") {
+		t.Errorf("GDRIVE_API_KEYS with a bare key: got error %v", err)
+	}
+}
+
+func TestValidateMessages(t *testing.T) {
+	secret := writeFile(t, "client.json", "{}")
+	tests := []struct {
+		name      string
+		configure func(*Config)
+		wantErr   []string
+	}{
+		{"defaults with a client secret", func(c *Config) {}, nil},
+		{"unknown mode", func(c *Config) { c.Auth.Mode = "magic" }, []string{"auth.mode 'magic' must be one of oauth, service_account, adc"}},
+		{"service account without key", func(c *Config) { c.Auth.Mode = "service_account" }, []string{"auth.credentials_file is required for service_account auth"}},
+		{"missing key file", func(c *Config) {
+			c.Auth.Mode = "service_account"
+			c.Auth.CredentialsFile = "/missing/key.json"
+		}, []string{"auth.credentials_file: stat /missing/key.json"}},
+		{"oauth without token file", func(c *Config) { c.Auth.TokenFile = "" }, []string{"auth.token_file is required for oauth auth"}},
+		{"oauth impersonation", func(c *Config) { c.Auth.ImpersonateUser = "boss@example.com" }, []string{"auth.impersonate_user is not supported with oauth auth"}},
+		{"unknown flow", func(c *Config) { c.Auth.OAuthFlow = "carrier-pigeon" }, []string{"auth.oauth_flow 'carrier-pigeon' must be loopback or device"}},
+		{"bad scope", func(c *Config) { c.Auth.Scopes = []string{"drive"} }, []string{"auth.scopes: 'drive' is not a scope URL"}},
+		{"read-only scope", func(c *Config) {
+			c.ReadOnly = true
+			c.Auth.Scopes = []string{"https://www.googleapis.com/auth/drive"}
+		}, []string{"auth.scopes: 'https://www.googleapis.com/auth/drive' is not allowed in read-only mode"}},
+		{"shared token file", func(c *Config) {
+			c.Accounts = map[string]AuthConfig{"work": {Mode: "oauth", OAuthClientSecretFile: secret, TokenFile: c.Auth.TokenFile}}
+		}, []string{"accounts.work.token_file is already used by account 'default'"}},
+		{"reserved account name", func(c *Config) {
+			c.Accounts = map[string]AuthConfig{"default": {Mode: "adc"}}
+		}, []string{"accounts.default: account name must not be empty or 'default'"}},
+		{"unknown transport", func(c *Config) { c.Server.Transport = "pigeon" }, []string{"server.transport 'pigeon' must be one of stdio, sse, http"}},
+		{"bad base url", func(c *Config) { c.Server.BaseURL = "localhost:8080" }, []string{"server.base_url 'localhost:8080' must be an absolute http(s) URL without a query"}},
+		{"half tls", func(c *Config) { c.Server.TLSCertFile = secret }, []string{"server.tls_cert_file and server.tls_key_file must be set together"}},
+		{"short api key", func(c *Config) { c.Server.Auth.APIKeys = map[string]string{"ci": "short"} }, []string{"server.auth.api_keys.ci: key must be at least 16 characters"}},
+		{"per-session auth over stdio", func(c *Config) {
+			c.Server.Transport = TransportStdio
+			c.Server.PerSessionAuth = true
+		}, []string{"server.per_session_auth requires the sse or http transport"}},
+		{"per-session auth without inbound auth", func(c *Config) {
+			c.Server.Transport = TransportHTTP
+			c.Server.PerSessionAuth = true
+		}, []string{"server.per_session_auth requires server.auth api_keys or jwt"}},
+		{"every problem at once", func(c *Config) {
+			c.Auth.Mode = "magic"
+			c.Server.Transport = "pigeon"
+		}, []string{"auth.mode 'magic'", "server.transport 'pigeon'"}},
+	}
+	for _, tt := range tests {
+		cfg := Default()
+		cfg.Auth.Mode = "oauth"
+		cfg.Auth.OAuthClientSecretFile = secret
+		cfg.Server.BaseURL = "http://localhost:8080"
+		tt.configure(cfg)
+		err := cfg.Validate()
+		if len(tt.wantErr) == 0 {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%s: no error, want %q", tt.name, tt.wantErr)
+			continue
+		}
+		for _, want := range tt.wantErr {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: got error '%v', want '%s'", tt.name, err, want)
+			}
+		}
+	}
+}
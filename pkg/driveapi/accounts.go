@@ -0,0 +1,228 @@
+package driveapi
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// AccountConfig names one Drive account and its credentials.
+type AccountConfig struct {
+	Name string
+	Auth AuthConfig
+}
+
+// AccountStatus reports whether an account can currently reach Drive.
+type AccountStatus struct {
+	Name       string `json:"name"`
+	Default    bool   `json:"default"`
+	Mode       string `json:"auth_mode"`
+	Authorized bool   `json:"authorized"`
+	Email      string `json:"email,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Accounts holds a ServicePool for each configured Drive account.
+type Accounts struct {
+	configs []AccountConfig // The first one is the default account
+
+	mu       sync.Mutex
+	pools    map[string]*ServicePool
+	failures map[string]error
+	pending  map[string]*authorization // Authorizations of accounts that failed to connect
+}
+
+// ConnectAccounts connects every account concurrently, so several OAuth
+// accounts can be authorized at the same time. The first account is the
+// default; failing to connect it is an error, while other accounts that fail
+// are logged and reported by Status.
+func ConnectAccounts(ctx context.Context, configs []AccountConfig) (*Accounts, error) {
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("no accounts configured")
+	}
+	a := &Accounts{
+		configs:  configs,
+		pools:    make(map[string]*ServicePool),
+		failures: make(map[string]error),
+		pending:  make(map[string]*authorization),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, account := range configs {
+		wg.Add(1)
+		go func(account AccountConfig) {
+			defer wg.Done()
+			pool, err := NewServicePool(ctx, account.Auth)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				a.failures[account.Name] = err
+				return
+			}
+			a.pools[account.Name] = pool
+		}(account)
+	}
+	wg.Wait()
+
+	defaultName := configs[0].Name
+	if err := a.failures[defaultName]; err != nil {
+		return nil, fmt.Errorf("unable to connect account '%s': %w", defaultName, err)
+	}
+	for name, err := range a.failures {
+		log.Printf("Unable to connect account '%s': %v", name, err)
+	}
+	return a, nil
+}
+
+// NewStaticAccounts serves an already constructed Drive service as the only,
+// default account, e.g. one pointed at a fake Drive in tests. It supports
+// neither impersonation nor reauthorization.
+func NewStaticAccounts(name string, srv *drive.Service) *Accounts {
+	return &Accounts{
+		configs:  []AccountConfig{{Name: name}},
+		pools:    map[string]*ServicePool{name: {defaultSrv: srv}},
+		failures: map[string]error{},
+		pending:  map[string]*authorization{},
+	}
+}
+
+// Pool returns the service pool of the named account, or of the default
+// account when name is empty.
+func (a *Accounts) Pool(name string) (*ServicePool, error) {
+	if name == "" {
+		name = a.configs[0].Name
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if pool, ok := a.pools[name]; ok {
+		return pool, nil
+	}
+	if err, ok := a.failures[name]; ok {
+		return nil, fmt.Errorf("account '%s' is not connected: %w", name, err)
+	}
+	return nil, fmt.Errorf("unknown account '%s'", name)
+}
+
+// Reauthorize starts a new OAuth authorization for the named account, or the
+// default one when name is empty. An account that failed to connect is
+// connected again: OAuth accounts once the user completes the authorization,
+// other accounts right away.
+func (a *Accounts) Reauthorize(ctx context.Context, name string) (*Reauthorization, error) {
+	pool, err := a.Pool(name)
+	if err == nil {
+		return pool.Reauthorize(ctx)
+	}
+	if name == "" {
+		name = a.configs[0].Name
+	}
+	var account AccountConfig
+	for _, c := range a.configs {
+		if c.Name == name {
+			account = c
+		}
+	}
+	if account.Name == "" {
+		return nil, err
+	}
+
+	source, err := NewCredentialSource(account.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure credentials: %w", err)
+	}
+	au, ok := source.(authorizer)
+	if !ok {
+		// Nothing to authorize; the cause may have been fixed since.
+		if err := a.connect(ctx, account); err != nil {
+			return nil, fmt.Errorf("account '%s' is not connected: %w", name, err)
+		}
+		return &Reauthorization{Instructions: fmt.Sprintf("Account '%s' is connected.", name)}, nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	auth, ok := a.pending[name]
+	if !ok {
+		// The flow outlives the tool call that started it.
+		ctx = context.WithoutCancel(ctx)
+		auth, err = au.authorize(ctx, driveScopes(account.Auth.Scopes))
+		if err != nil {
+			return nil, fmt.Errorf("unable to start authorization: %w", err)
+		}
+		a.pending[name] = auth
+		go a.finishAuthorization(ctx, account, auth)
+	}
+	return &Reauthorization{URL: auth.URL, UserCode: auth.UserCode, Instructions: auth.Instructions()}, nil
+}
+
+// finishAuthorization waits for the user to complete auth and connects the
+// account with the new token.
+func (a *Accounts) finishAuthorization(ctx context.Context, account AccountConfig, auth *authorization) {
+	waitCtx, cancel := context.WithTimeout(ctx, authorizationTimeout)
+	defer cancel()
+	_, err := auth.wait(waitCtx)
+	if err == nil {
+		err = a.connect(ctx, account)
+	}
+	if err != nil {
+		log.Printf("Authorization of account '%s' failed: %v", account.Name, err)
+	} else {
+		log.Printf("Account '%s' is connected", account.Name)
+	}
+	a.mu.Lock()
+	delete(a.pending, account.Name)
+	a.mu.Unlock()
+}
+
+// connect builds the service pool of account, replacing its failure.
+func (a *Accounts) connect(ctx context.Context, account AccountConfig) error {
+	pool, err := NewServicePool(ctx, account.Auth)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err != nil {
+		a.failures[account.Name] = err
+		return err
+	}
+	a.pools[account.Name] = pool
+	delete(a.failures, account.Name)
+	return nil
+}
+
+// Service returns the Drive service for account acting as asUser. Empty
+// values select the default account and identity.
+func (a *Accounts) Service(ctx context.Context, account, asUser string) (*drive.Service, error) {
+	pool, err := a.Pool(account)
+	if err != nil {
+		return nil, err
+	}
+	return pool.Service(ctx, asUser)
+}
+
+// Status checks every account against the Drive About endpoint.
+func (a *Accounts) Status(ctx context.Context) []AccountStatus {
+	statuses := make([]AccountStatus, len(a.configs))
+	for i, account := range a.configs {
+		status := AccountStatus{
+			Name:    account.Name,
+			Default: i == 0,
+			Mode:    string(account.Auth.Mode),
+		}
+		srv, err := a.Service(ctx, account.Name, "")
+		if err == nil {
+			var about *drive.About
+			about, err = srv.About.Get().Fields("user(emailAddress)").Context(ctx).Do()
+			if err == nil {
+				status.Authorized = true
+				status.Email = about.User.EmailAddress
+			}
+		}
+		if err != nil {
+			status.Error = err.Error()
+		}
+		statuses[i] = status
+	}
+	return statuses
+}
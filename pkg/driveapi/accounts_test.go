@@ -0,0 +1,76 @@
+package driveapi
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReauthorizeConnectsFailedAccount(t *testing.T) {
+	var polls atomic.Int32
+	secret := writeTestFile(t, "client.json", deviceFlowServer(t, &polls))
+	account := AccountConfig{Name: "work", Auth: AuthConfig{
+		Mode:                  AuthModeOAuth,
+		OAuthClientSecretFile: secret,
+		TokenFile:             filepath.Join(t.TempDir(), "token-work.json"),
+		OAuthFlow:             OAuthFlowDevice,
+		Scopes:                []string{"https://www.googleapis.com/auth/drive.file"},
+	}}
+	a := NewStaticAccounts("default", nil)
+	a.configs = append(a.configs, account)
+	a.failures["work"] = errors.New("authorization was not completed")
+
+	ctx := context.Background()
+	if _, err := a.Pool("work"); err == nil {
+		t.Fatal("the failed account has a pool")
+	}
+	r, err := a.Reauthorize(ctx, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.URL != "https://www.google.com/device" || r.UserCode != "ABCD-EFGH" {
+		t.Errorf("got %+v", r)
+	}
+	// A second call returns the pending authorization.
+	if again, err := a.Reauthorize(ctx, "work"); err != nil || again.UserCode != r.UserCode {
+		t.Errorf("second call: %+v, %v", again, err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := a.Pool("work"); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the account was not connected after authorizing")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := polls.Load(); n != 1 {
+		t.Errorf("token endpoint polled %d times, want 1", n)
+	}
+	if _, err := tokenFromFile(account.Auth.TokenFile, account.Auth.Scopes); err != nil {
+		t.Errorf("token was not saved: %v", err)
+	}
+}
+
+func TestReauthorizeRetriesOtherAccounts(t *testing.T) {
+	a := NewStaticAccounts("default", nil)
+	a.configs = append(a.configs, AccountConfig{Name: "robot", Auth: AuthConfig{
+		Mode:            AuthModeServiceAccount,
+		CredentialsFile: filepath.Join(t.TempDir(), "missing.json"),
+	}})
+	a.failures["robot"] = errors.New("no key")
+
+	_, err := a.Reauthorize(context.Background(), "robot")
+	if err == nil || !strings.Contains(err.Error(), "unable to read service account key") {
+		t.Errorf("got error %v", err)
+	}
+	if _, err := a.Reauthorize(context.Background(), "missing"); err == nil || !strings.Contains(err.Error(), "unknown account 'missing'") {
+		t.Errorf("unknown account: got error %v", err)
+	}
+}
@@ -0,0 +1,340 @@
+package driveapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"google-drive-mcp-server/pkg/extract"
+)
+
+// MIME types of the files ReadFileContent converts.
+const (
+	GoogleDocMimeType     = "application/vnd.google-apps.document"
+	GoogleSheetMimeType   = "application/vnd.google-apps.spreadsheet"
+	GoogleSlidesMimeType  = "application/vnd.google-apps.presentation"
+	GoogleDrawingMimeType = "application/vnd.google-apps.drawing"
+	PDFMimeType           = "application/pdf"
+	XLSXMimeType          = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	PPTXMimeType          = "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+)
+
+// Limits on ReadFileContent, so that a large or crafted file cannot exhaust
+// the server's memory or flood the client.
+const (
+	// MaxDownloadSize bounds the files and exports ReadFileContent fetches.
+	MaxDownloadSize = 64 << 20
+	// MaxContentSize bounds the text ReadFileContent returns.
+	MaxContentSize = 8 << 20
+)
+
+// ReadOptions selects how ReadFileContent converts a file.
+type ReadOptions struct {
+	Format extract.Format    // Plain text or Markdown, for documents and spreadsheets
+	Pages  extract.Ranges    // The pages of a PDF to read; all of them if empty
+	Sheet  string            // The one sheet of a spreadsheet to read; all of them if empty
+	Cells  extract.CellRange // The cells to read from each sheet; all of them if zero
+}
+
+// FileContent is the content of a file, converted for reading. Documents,
+// PDFs and text files fill Text, spreadsheets Sheets, presentations Slides
+// and drawings Image. ReadFile also fills in the file's metadata.
+type FileContent struct {
+	FileInfo
+	ShortcutID     string         `json:"shortcut_id,omitempty"` // The shortcut followed to the file
+	Text           string         `json:"content,omitempty"`
+	PageCount      int            `json:"page_count,omitempty"`
+	ImageOnlyPages []int          `json:"image_only_pages,omitempty"` // PDF pages that need OCR
+	Sheets         []SheetContent `json:"sheets,omitempty"`
+	Slides         []SlideContent `json:"slides,omitempty"`
+	Image          []byte         `json:"-"`
+	ImageMimeType  string         `json:"image_mime_type,omitempty"`
+}
+
+// SheetContent is one sheet of a spreadsheet, as CSV or, in the Markdown
+// format, as a Markdown table.
+type SheetContent struct {
+	Name     string `json:"name"`
+	Hidden   bool   `json:"hidden,omitempty"`
+	Rows     int    `json:"rows"`
+	CSV      string `json:"csv,omitempty"`
+	Markdown string `json:"markdown,omitempty"`
+}
+
+// SlideContent is the text of one slide of a presentation.
+type SlideContent struct {
+	Number int    `json:"number"`
+	Title  string `json:"title,omitempty"`
+	Text   string `json:"text,omitempty"`
+	Notes  string `json:"notes,omitempty"`
+}
+
+// readFileSelector is the files.get field selector ReadFile uses.
+var readFileSelector = DefaultFileFields.fileSelector() + ", shortcutDetails(targetId)"
+
+// ReadFile reads the content of a file like ReadFileContent, detecting its
+// MIME type from its metadata, which it returns along with the content. A
+// shortcut is read as its target. A mimeType given by the caller is only
+// checked: the detected type is used if they differ.
+func ReadFile(ctx context.Context, client DriveClient, fileID string, mimeType string, opts ReadOptions) (*FileContent, error) {
+	file, err := client.GetFile(ctx, fileID, readFileSelector)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get file '%s': %w", fileID, err)
+	}
+	shortcutID := ""
+	if file.MimeType == ShortcutMimeType {
+		if file.ShortcutDetails == nil || file.ShortcutDetails.TargetId == "" {
+			return nil, fmt.Errorf("shortcut '%s' has no target", fileID)
+		}
+		shortcutID, fileID = file.Id, file.ShortcutDetails.TargetId
+		if file, err = client.GetFile(ctx, fileID, readFileSelector); err != nil {
+			return nil, fmt.Errorf("unable to get the target '%s' of shortcut '%s': %w", fileID, shortcutID, err)
+		}
+	}
+	if file.MimeType == FolderMimeType {
+		return nil, fmt.Errorf("'%s' is a folder, not a file", file.Name)
+	}
+	if mimeType != "" && mimeType != file.MimeType {
+		log.Printf("File '%s' is %s, not %s as given; reading it as %s", file.Id, file.MimeType, mimeType, file.MimeType)
+	}
+
+	content, err := ReadFileContent(ctx, client, file.Id, file.MimeType, opts)
+	if err != nil {
+		return nil, err
+	}
+	content.FileInfo = DefaultFileFields.Info(file)
+	content.ShortcutID = shortcutID
+	return content, nil
+}
+
+// ReadFileContent reads the content of a file, handling different MIME types.
+// Google Docs are exported and .docx files are converted, both as plain text
+// or Markdown depending on the format. The text of PDFs is extracted page by
+// page, and pages that are only images are marked as needing OCR. Google
+// Sheets and .xlsx files are read sheet by sheet as CSV or Markdown tables,
+// Google Slides and .pptx files slide by slide with speaker notes, and Google
+// Drawings are exported as PNG images. Files larger than MaxDownloadSize and
+// content longer than MaxContentSize are errors.
+func ReadFileContent(ctx context.Context, client DriveClient, fileID string, mimeType string, opts ReadOptions) (*FileContent, error) {
+	content, err := readContent(ctx, client, fileID, mimeType, opts)
+	if err != nil {
+		return nil, err
+	}
+	if n := content.size(); n > MaxContentSize {
+		return nil, fmt.Errorf("the content of '%s' is %d bytes, more than the %d that can be returned; read part of it by selecting pages, a sheet or cells", fileID, n, MaxContentSize)
+	}
+	return content, nil
+}
+
+// readContent reads and converts a file for ReadFileContent.
+func readContent(ctx context.Context, client DriveClient, fileID string, mimeType string, opts ReadOptions) (*FileContent, error) {
+	if len(opts.Pages) > 0 && mimeType != PDFMimeType {
+		return nil, fmt.Errorf("pages can only be selected from PDF files, not %s", mimeType)
+	}
+	spreadsheet := mimeType == GoogleSheetMimeType || mimeType == XLSXMimeType
+	if opts.Sheet != "" && !spreadsheet {
+		return nil, fmt.Errorf("a sheet can only be selected from spreadsheets, not %s", mimeType)
+	}
+	if opts.Cells != (extract.CellRange{}) && !spreadsheet {
+		return nil, fmt.Errorf("cells can only be selected from spreadsheets, not %s", mimeType)
+	}
+
+	switch mimeType {
+	// CASE A: Google Native Docs (Must use Export)
+	case GoogleDocMimeType:
+		exportType := "text/plain"
+		if opts.Format == extract.Markdown {
+			exportType = "text/markdown"
+		}
+		data, err := exportData(ctx, client, fileID, exportType, "google doc")
+		if err != nil {
+			return nil, err
+		}
+		return &FileContent{Text: string(data)}, nil
+
+	// CASE B: Google Sheets are exported as .xlsx and read sheet by sheet
+	case GoogleSheetMimeType:
+		data, err := exportData(ctx, client, fileID, XLSXMimeType, "google sheet")
+		if err != nil {
+			return nil, err
+		}
+		content, err := sheetsContent(data, opts)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read google sheet '%s': %w", fileID, err)
+		}
+		return content, nil
+
+	// CASE C: Google Slides are exported as .pptx and read slide by slide
+	case GoogleSlidesMimeType:
+		data, err := exportData(ctx, client, fileID, PPTXMimeType, "google slides")
+		if err != nil {
+			return nil, err
+		}
+		content, err := slidesContent(data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read google slides '%s': %w", fileID, err)
+		}
+		return content, nil
+
+	// CASE D: Google Drawings are exported as images
+	case GoogleDrawingMimeType:
+		data, err := exportData(ctx, client, fileID, "image/png", "google drawing")
+		if err != nil {
+			return nil, err
+		}
+		return &FileContent{Image: data, ImageMimeType: "image/png"}, nil
+
+	// CASE E: Word documents are downloaded and converted
+	case DocxMimeType:
+		data, err := downloadData(ctx, client, fileID, "docx file")
+		if err != nil {
+			return nil, err
+		}
+		text, err := extract.DOCX(data, opts.Format)
+		if errors.Is(err, extract.ErrNotPackage) && utf8.Valid(data) {
+			// CreateDocxFileInPath stores the text it is given as is.
+			log.Printf("Docx file '%s' is plain text, returning it unconverted", fileID)
+			return &FileContent{Text: string(data)}, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to extract text from docx file '%s': %w", fileID, err)
+		}
+		return &FileContent{Text: text}, nil
+
+	// CASE F: Excel workbooks are downloaded and read sheet by sheet
+	case XLSXMimeType:
+		data, err := downloadData(ctx, client, fileID, "xlsx file")
+		if err != nil {
+			return nil, err
+		}
+		content, err := sheetsContent(data, opts)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read xlsx file '%s': %w", fileID, err)
+		}
+		return content, nil
+
+	// CASE G: PowerPoint presentations are downloaded and read slide by slide
+	case PPTXMimeType:
+		data, err := downloadData(ctx, client, fileID, "pptx file")
+		if err != nil {
+			return nil, err
+		}
+		content, err := slidesContent(data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read pptx file '%s': %w", fileID, err)
+		}
+		return content, nil
+
+	// CASE H: PDF files are downloaded and their text extracted
+	case PDFMimeType:
+		data, err := downloadData(ctx, client, fileID, "pdf file")
+		if err != nil {
+			return nil, err
+		}
+		result, err := extract.PDF(data, extract.PDFOptions{Pages: opts.Pages})
+		if err != nil {
+			return nil, fmt.Errorf("unable to extract text from pdf file '%s': %w", fileID, err)
+		}
+		if len(result.ImageOnlyPages) > 0 {
+			log.Printf("PDF file '%s' has image-only pages %v", fileID, result.ImageOnlyPages)
+		}
+		return &FileContent{Text: result.Text, PageCount: result.PageCount, ImageOnlyPages: result.ImageOnlyPages}, nil
+	}
+
+	// CASE I: Plain Text
+	if !strings.HasPrefix(mimeType, "text/") {
+		return nil, fmt.Errorf("unsupported mime type for reading: %s", mimeType)
+	}
+	data, err := downloadData(ctx, client, fileID, "text file")
+	if err != nil {
+		return nil, err
+	}
+	return &FileContent{Text: string(data)}, nil
+}
+
+// sheetsContent reads the sheets of an .xlsx workbook.
+func sheetsContent(data []byte, opts ReadOptions) (*FileContent, error) {
+	sheets, err := extract.XLSX(data, extract.XLSXOptions{Sheet: opts.Sheet, Cells: opts.Cells})
+	if err != nil {
+		return nil, err
+	}
+	content := &FileContent{Sheets: []SheetContent{}}
+	budget := MaxContentSize
+	for _, s := range sheets {
+		sheet := SheetContent{Name: s.Name, Hidden: s.Hidden, Rows: len(s.Rows)}
+		if opts.Format == extract.Markdown {
+			sheet.Markdown, err = s.Markdown(budget)
+		} else {
+			sheet.CSV, err = s.CSV(budget)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("sheet '%s': %w; read part of the workbook by selecting a sheet or cells", s.Name, err)
+		}
+		budget -= len(sheet.CSV) + len(sheet.Markdown)
+		content.Sheets = append(content.Sheets, sheet)
+	}
+	return content, nil
+}
+
+// size returns the length of the text in c.
+func (c *FileContent) size() int {
+	n := len(c.Text)
+	for _, s := range c.Sheets {
+		n += len(s.Name) + len(s.CSV) + len(s.Markdown)
+	}
+	for _, s := range c.Slides {
+		n += len(s.Title) + len(s.Text) + len(s.Notes)
+	}
+	return n
+}
+
+// slidesContent reads the slides of a .pptx presentation.
+func slidesContent(data []byte) (*FileContent, error) {
+	slides, err := extract.PPTX(data)
+	if err != nil {
+		return nil, err
+	}
+	content := &FileContent{Slides: []SlideContent{}}
+	for _, s := range slides {
+		content.Slides = append(content.Slides, SlideContent{Number: s.Number, Title: s.Title, Text: s.Text, Notes: s.Notes})
+	}
+	return content, nil
+}
+
+// exportData exports a Google Workspace file as exportType; kind names the
+// file in errors.
+func exportData(ctx context.Context, client DriveClient, fileID, exportType, kind string) ([]byte, error) {
+	body, err := client.ExportFile(ctx, fileID, exportType)
+	if err != nil {
+		return nil, fmt.Errorf("unable to export %s '%s': %w", kind, fileID, err)
+	}
+	return readBody(body)
+}
+
+// downloadData downloads the content of a file; kind names the file in errors.
+func downloadData(ctx context.Context, client DriveClient, fileID, kind string) ([]byte, error) {
+	body, err := client.DownloadFile(ctx, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to download %s '%s': %w", kind, fileID, err)
+	}
+	return readBody(body)
+}
+
+// readBody reads and closes a download or export body of at most
+// MaxDownloadSize bytes.
+func readBody(body io.ReadCloser) ([]byte, error) {
+	defer body.Close()
+	buf := new(bytes.Buffer)
+	if _, err := buf.ReadFrom(io.LimitReader(body, MaxDownloadSize+1)); err != nil {
+		return nil, fmt.Errorf("unable to read file content: %w", err)
+	}
+	if buf.Len() > MaxDownloadSize {
+		return nil, fmt.Errorf("the file is larger than %d bytes, more than can be read", MaxDownloadSize)
+	}
+	return buf.Bytes(), nil
+}
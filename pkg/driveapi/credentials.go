@@ -0,0 +1,293 @@
+package driveapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// AuthMode selects where the server obtains its Google credentials from.
+type AuthMode string
+
+const (
+	// AuthModeOAuth runs the installed-app OAuth flow on behalf of a user.
+	AuthModeOAuth AuthMode = "oauth"
+	// AuthModeServiceAccount authenticates with a service-account JSON key.
+	AuthModeServiceAccount AuthMode = "service_account"
+	// AuthModeADC uses Application Default Credentials (gcloud, metadata server, workload identity).
+	AuthModeADC AuthMode = "adc"
+)
+
+// AuthConfig describes how GetDriveService authenticates against Google Drive.
+type AuthConfig struct {
+	Mode AuthMode
+	// CredentialsFile is the service-account key used by AuthModeServiceAccount.
+	CredentialsFile string
+	// OAuthClientSecretFile, TokenFile, OAuthFlow and OAuthCallbackAddr are
+	// used by AuthModeOAuth. The callback address only matters for the
+	// loopback flow and defaults to a random port on 127.0.0.1.
+	OAuthClientSecretFile string
+	TokenFile             string
+	OAuthFlow             OAuthFlow
+	OAuthCallbackAddr     string
+	// Subject is the Workspace user to impersonate through domain-wide
+	// delegation. Only service-account backed credentials support it.
+	Subject string
+	// AsUserAllowlist lists the users a call may impersonate: email
+	// addresses, or "@example.com" for every user of a domain. When it is
+	// empty no call may impersonate anyone.
+	AsUserAllowlist []string
+	// Scopes requested for the Drive client. Defaults to full Drive access.
+	Scopes []string
+}
+
+// CredentialSource produces token sources for the Drive API.
+type CredentialSource interface {
+	TokenSource(ctx context.Context, scopes ...string) (oauth2.TokenSource, error)
+}
+
+// impersonator is implemented by credential sources that can act as another
+// user through domain-wide delegation.
+type impersonator interface {
+	impersonate(subject string) CredentialSource
+}
+
+// NewCredentialSource returns the credential source selected by cfg.Mode.
+func NewCredentialSource(cfg AuthConfig) (CredentialSource, error) {
+	switch cfg.Mode {
+	case AuthModeServiceAccount:
+		if cfg.CredentialsFile == "" {
+			return nil, fmt.Errorf("service account auth requires a credentials file")
+		}
+		return &serviceAccountSource{keyFile: cfg.CredentialsFile, subject: cfg.Subject}, nil
+	case AuthModeADC:
+		return &adcSource{subject: cfg.Subject}, nil
+	case AuthModeOAuth, "":
+		if cfg.Subject != "" {
+			return nil, fmt.Errorf("user impersonation is not supported with oauth auth mode")
+		}
+		if cfg.OAuthClientSecretFile == "" || cfg.TokenFile == "" {
+			return nil, fmt.Errorf("oauth auth requires a client secret file and a token file")
+		}
+		return &oauthUserSource{
+			clientSecretFile: cfg.OAuthClientSecretFile,
+			tokenFile:        cfg.TokenFile,
+			flow:             cfg.OAuthFlow,
+			callbackAddr:     cfg.OAuthCallbackAddr,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown auth mode '%s'", cfg.Mode)
+	}
+}
+
+// serviceAccountSource authenticates with a service-account JSON key file,
+// optionally impersonating subject.
+type serviceAccountSource struct {
+	keyFile string
+	subject string
+}
+
+func (s *serviceAccountSource) impersonate(subject string) CredentialSource {
+	return &serviceAccountSource{keyFile: s.keyFile, subject: subject}
+}
+
+func (s *serviceAccountSource) TokenSource(ctx context.Context, scopes ...string) (oauth2.TokenSource, error) {
+	b, err := os.ReadFile(s.keyFile)
+	if err != nil {
+		log.Printf("Unable to read service account key from '%s': %v", s.keyFile, err)
+		return nil, fmt.Errorf("unable to read service account key from '%s': %w", s.keyFile, err)
+	}
+	conf, err := google.JWTConfigFromJSON(b, scopes...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse service account key '%s': %w", s.keyFile, err)
+	}
+	conf.Subject = s.subject
+	return conf.TokenSource(ctx), nil
+}
+
+// adcSource resolves Application Default Credentials. A subject can only be
+// impersonated when ADC points at a service-account key.
+type adcSource struct {
+	subject string
+}
+
+func (s *adcSource) impersonate(subject string) CredentialSource {
+	return &adcSource{subject: subject}
+}
+
+func (s *adcSource) TokenSource(ctx context.Context, scopes ...string) (oauth2.TokenSource, error) {
+	creds, err := google.FindDefaultCredentialsWithParams(ctx, google.CredentialsParams{
+		Scopes:  scopes,
+		Subject: s.subject,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to find application default credentials: %w", err)
+	}
+	// Other credentials ignore the subject and would act as themselves.
+	if kind := credentialsType(creds.JSON); s.subject != "" && kind != "service_account" {
+		if kind == "" {
+			kind = "metadata server"
+		}
+		return nil, fmt.Errorf("impersonating '%s' requires application default credentials from a service account key, not '%s' credentials", s.subject, kind)
+	}
+	return creds.TokenSource, nil
+}
+
+// credentialsType returns the type of a credentials JSON file, or "" for
+// credentials without one, such as the metadata server's.
+func credentialsType(data []byte) string {
+	var f struct {
+		Type string `json:"type"`
+	}
+	json.Unmarshal(data, &f)
+	return f.Type
+}
+
+// reauthorizer is implemented by credential sources whose grant can be
+// renewed interactively while the server keeps running.
+type reauthorizer interface {
+	reauthorize(ctx context.Context) (*authorization, error)
+}
+
+// authorizer is implemented by credential sources that can be authorized
+// interactively before they have any grant.
+type authorizer interface {
+	authorize(ctx context.Context, scopes []string) (*authorization, error)
+}
+
+// oauthUserSource runs the installed-app OAuth flow and caches the token on disk.
+type oauthUserSource struct {
+	clientSecretFile string
+	tokenFile        string
+	flow             OAuthFlow
+	callbackAddr     string
+
+	mu      sync.Mutex
+	config  *oauth2.Config
+	ts      *persistentTokenSource
+	pending *authorization
+}
+
+// clientConfig reads the OAuth client for scopes and makes sure the token
+// file can be written.
+func (s *oauthUserSource) clientConfig(scopes []string) (*oauth2.Config, error) {
+	b, err := os.ReadFile(s.clientSecretFile)
+	if err != nil {
+		log.Printf("Unable to read client secret file from '%s': %v", s.clientSecretFile, err)
+		return nil, fmt.Errorf("unable to read client secret file from '%s': %w", s.clientSecretFile, err)
+	}
+
+	config, err := google.ConfigFromJSON(b, scopes...)
+	if err != nil {
+		log.Printf("Unable to parse client secret file to config: %v", err)
+		return nil, fmt.Errorf("unable to parse client secret file to config: %w", err)
+	}
+
+	// Ensure the directory for token.json exists
+	tokenDir := filepath.Dir(s.tokenFile)
+	if _, err := os.Stat(tokenDir); os.IsNotExist(err) {
+		if err := os.MkdirAll(tokenDir, 0700); err != nil {
+			return nil, fmt.Errorf("unable to create token directory '%s': %w", tokenDir, err)
+		}
+	}
+	return config, nil
+}
+
+// TokenSource retrieves a token, or asks the user to authorize if needed.
+func (s *oauthUserSource) TokenSource(ctx context.Context, scopes ...string) (oauth2.TokenSource, error) {
+	config, err := s.clientConfig(scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	// Try to read the token from a file
+	tok, err := tokenFromFile(s.tokenFile, config.Scopes)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Not using the stored OAuth token: %v", err)
+		}
+		tok, err = getTokenFromWeb(ctx, config, s.flow, s.callbackAddr)
+		if err != nil {
+			return nil, fmt.Errorf("unable to authorize: %w", err)
+		}
+		if err := saveToken(s.tokenFile, tok, config.Scopes); err != nil {
+			return nil, err
+		}
+	}
+
+	ts := newPersistentTokenSource(ctx, config, s.tokenFile, tok)
+	s.mu.Lock()
+	s.config, s.ts = config, ts
+	s.mu.Unlock()
+	return ts, nil
+}
+
+// authorize starts an authorization for scopes before the source has a
+// token, e.g. for an account that could not connect at startup. Waiting for
+// it saves the token, so that TokenSource picks it up.
+func (s *oauthUserSource) authorize(ctx context.Context, scopes []string) (*authorization, error) {
+	config, err := s.clientConfig(scopes)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := startAuthorization(ctx, config, s.flow, s.callbackAddr)
+	if err != nil {
+		return nil, err
+	}
+	wait := auth.wait
+	auth.wait = func(ctx context.Context) (*oauth2.Token, error) {
+		tok, err := wait(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return tok, saveToken(s.tokenFile, tok, config.Scopes)
+	}
+	return auth, nil
+}
+
+// reauthorize starts a new authorization and returns it without waiting.
+// Once the user completes it, the new token replaces the current one.
+// A reauthorization that is already in progress is returned as is.
+func (s *oauthUserSource) reauthorize(ctx context.Context) (*authorization, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ts == nil {
+		return nil, fmt.Errorf("oauth credentials have not been initialized")
+	}
+	if s.pending != nil {
+		return s.pending, nil
+	}
+
+	// The flow outlives the tool call that started it.
+	ctx = context.WithoutCancel(ctx)
+	auth, err := startAuthorization(ctx, s.config, s.flow, s.callbackAddr)
+	if err != nil {
+		return nil, err
+	}
+	s.pending = auth
+
+	go func() {
+		waitCtx, cancel := context.WithTimeout(ctx, authorizationTimeout)
+		defer cancel()
+		tok, err := auth.wait(waitCtx)
+		if err == nil {
+			err = s.ts.reset(tok)
+		}
+		if err != nil {
+			log.Printf("Reauthorization failed: %v", err)
+		} else {
+			log.Printf("Reauthorization complete")
+		}
+		s.mu.Lock()
+		s.pending = nil
+		s.mu.Unlock()
+	}()
+	return auth, nil
+}
@@ -0,0 +1,57 @@
+package driveapi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewCredentialSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     AuthConfig
+		want    CredentialSource
+		wantErr string
+	}{
+		{"service account", AuthConfig{Mode: AuthModeServiceAccount, CredentialsFile: "key.json", Subject: "boss@example.com"},
+			&serviceAccountSource{keyFile: "key.json", subject: "boss@example.com"}, ""},
+		{"service account without key", AuthConfig{Mode: AuthModeServiceAccount}, nil, "requires a credentials file"},
+		{"adc", AuthConfig{Mode: AuthModeADC, Subject: "boss@example.com"}, &adcSource{subject: "boss@example.com"}, ""},
+		{"oauth", AuthConfig{Mode: AuthModeOAuth, OAuthClientSecretFile: "client.json", TokenFile: "token.json", OAuthFlow: OAuthFlowDevice},
+			&oauthUserSource{clientSecretFile: "client.json", tokenFile: "token.json", flow: OAuthFlowDevice}, ""},
+		{"oauth by default", AuthConfig{OAuthClientSecretFile: "client.json", TokenFile: "token.json"},
+			&oauthUserSource{clientSecretFile: "client.json", tokenFile: "token.json"}, ""},
+		{"oauth without token file", AuthConfig{Mode: AuthModeOAuth, OAuthClientSecretFile: "client.json"}, nil, "requires a client secret file and a token file"},
+		{"oauth impersonation", AuthConfig{Mode: AuthModeOAuth, OAuthClientSecretFile: "client.json", TokenFile: "token.json", Subject: "boss@example.com"}, nil, "not supported with oauth"},
+		{"unknown mode", AuthConfig{Mode: "magic"}, nil, "unknown auth mode 'magic'"},
+	}
+	for _, tt := range tests {
+		got, err := NewCredentialSource(tt.cfg)
+		switch {
+		case tt.wantErr != "":
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: got error %v, want '%s'", tt.name, err, tt.wantErr)
+			}
+		case err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case !reflect.DeepEqual(got, tt.want):
+			t.Errorf("%s: got %#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+
+	// Only service-account backed sources can impersonate, and they keep
+	// their credentials while doing so.
+	for _, source := range []CredentialSource{&serviceAccountSource{keyFile: "key.json"}, &adcSource{}} {
+		imp, ok := source.(impersonator)
+		if !ok {
+			t.Errorf("%T cannot impersonate", source)
+			continue
+		}
+		if sa, ok := imp.impersonate("dev@example.com").(*serviceAccountSource); ok && (sa.keyFile != "key.json" || sa.subject != "dev@example.com") {
+			t.Errorf("impersonated source: %+v", sa)
+		}
+	}
+	if _, ok := CredentialSource(&oauthUserSource{}).(impersonator); ok {
+		t.Error("oauth credentials can impersonate")
+	}
+}
@@ -0,0 +1,348 @@
+// Package drivefake is an in-memory driveapi.DriveClient for tests. It keeps
+// files in a map, evaluates a subset of the files.list query language and
+// returns googleapi errors with the status codes Drive uses.
+package drivefake
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google-drive-mcp-server/pkg/driveapi"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+const (
+	// RootID is the ID of the root folder, which Drive also accepts as an alias.
+	RootID = "root"
+
+	folderMimeType  = "application/vnd.google-apps.folder"
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// Drive is an in-memory Drive. The zero value is not usable; call New.
+type Drive struct {
+	mu      sync.Mutex
+	files   map[string]*entry
+	nextID  int
+	lastNow time.Time
+}
+
+type entry struct {
+	meta    drive.File
+	content []byte
+	exports map[string][]byte // Export content by MIME type
+}
+
+var _ driveapi.DriveClient = (*Drive)(nil)
+
+// New returns an empty Drive containing only the root folder.
+func New() *Drive {
+	d := &Drive{files: make(map[string]*entry)}
+	now := d.now()
+	d.files[RootID] = &entry{meta: drive.File{
+		Id:           RootID,
+		Name:         "My Drive",
+		MimeType:     folderMimeType,
+		CreatedTime:  now,
+		ModifiedTime: now,
+	}}
+	return d
+}
+
+// now returns a strictly increasing timestamp, so files created one after
+// another sort by time in creation order. The caller must hold d.mu or own d.
+func (d *Drive) now() string {
+	t := time.Now().UTC()
+	if !t.After(d.lastNow) {
+		t = d.lastNow.Add(time.Millisecond)
+	}
+	d.lastNow = t
+	return t.Format(time.RFC3339Nano)
+}
+
+// ListFiles implements driveapi.DriveClient. Fields is ignored: every file
+// is returned with all of its metadata.
+func (d *Drive) ListFiles(ctx context.Context, opts driveapi.ListOptions) (*drive.FileList, error) {
+	match, err := parseQuery(opts.Query)
+	if err != nil {
+		return nil, &googleapi.Error{Code: http.StatusBadRequest, Message: fmt.Sprintf("Invalid Value: %v", err)}
+	}
+	less, err := parseOrderBy(opts.OrderBy)
+	if err != nil {
+		return nil, &googleapi.Error{Code: http.StatusBadRequest, Message: fmt.Sprintf("Invalid Value: %v", err)}
+	}
+	pageSize := int(opts.PageSize)
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	offset := 0
+	if opts.PageToken != "" {
+		offset, err = strconv.Atoi(opts.PageToken)
+		if err != nil || offset < 0 {
+			return nil, &googleapi.Error{Code: http.StatusBadRequest, Message: "Invalid Value: pageToken"}
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var matched []*drive.File
+	for id, e := range d.files {
+		if id == RootID {
+			continue
+		}
+		if match(&e.meta, e.content) {
+			matched = append(matched, copyFile(&e.meta))
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool { return less(matched[i], matched[j]) })
+
+	list := &drive.FileList{Kind: "drive#fileList", IncompleteSearch: false}
+	if offset < len(matched) {
+		end := min(offset+pageSize, len(matched))
+		list.Files = matched[offset:end]
+		if end < len(matched) {
+			list.NextPageToken = strconv.Itoa(end)
+		}
+	}
+	return list, nil
+}
+
+// GetFile implements driveapi.DriveClient.
+func (d *Drive) GetFile(ctx context.Context, fileID string, fields string) (*drive.File, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, err := d.lookup(fileID)
+	if err != nil {
+		return nil, err
+	}
+	return copyFile(&e.meta), nil
+}
+
+// CreateFile implements driveapi.DriveClient. A file without parents is
+// created in the root folder, and the MIME type defaults to one guessed from
+// the file name.
+func (d *Drive) CreateFile(ctx context.Context, file *drive.File, media io.Reader, fields string) (*drive.File, error) {
+	content, err := readMedia(media)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	meta := *copyFile(file)
+	if meta.Id == "" {
+		d.nextID++
+		meta.Id = fmt.Sprintf("file%d", d.nextID)
+	} else if _, ok := d.files[meta.Id]; ok {
+		return nil, &googleapi.Error{Code: http.StatusConflict, Message: fmt.Sprintf("A file already exists with the provided ID: %s.", meta.Id)}
+	}
+	if len(meta.Parents) == 0 {
+		meta.Parents = []string{RootID}
+	}
+	for _, parent := range meta.Parents {
+		p, err := d.lookup(parent)
+		if err != nil {
+			return nil, err
+		}
+		if p.meta.MimeType != folderMimeType {
+			return nil, &googleapi.Error{Code: http.StatusBadRequest, Message: fmt.Sprintf("The parent %s is not a folder.", parent)}
+		}
+	}
+	if meta.MimeType == "" {
+		meta.MimeType = guessMimeType(meta.Name)
+	}
+	now := d.now()
+	meta.CreatedTime = now
+	meta.ModifiedTime = now
+	meta.WebViewLink = "https://drive.google.com/file/d/" + meta.Id + "/view"
+	setContentMeta(&meta, content)
+	e := &entry{meta: meta, content: content}
+	d.files[meta.Id] = e
+	return copyFile(&e.meta), nil
+}
+
+// UpdateFile implements driveapi.DriveClient. Non-empty metadata fields
+// replace the stored ones; Trashed and Starred are applied when set or named
+// in ForceSendFields.
+func (d *Drive) UpdateFile(ctx context.Context, fileID string, file *drive.File, media io.Reader, fields string) (*drive.File, error) {
+	content, err := readMedia(media)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, err := d.lookup(fileID)
+	if err != nil {
+		return nil, err
+	}
+	if file != nil {
+		if file.Name != "" {
+			e.meta.Name = file.Name
+		}
+		if file.MimeType != "" {
+			e.meta.MimeType = file.MimeType
+		}
+		if file.Description != "" {
+			e.meta.Description = file.Description
+		}
+		if file.Trashed || forced(file, "Trashed") {
+			e.meta.Trashed = file.Trashed
+		}
+		if file.Starred || forced(file, "Starred") {
+			e.meta.Starred = file.Starred
+		}
+	}
+	if media != nil {
+		e.content = content
+		e.exports = nil
+		setContentMeta(&e.meta, content)
+	}
+	e.meta.ModifiedTime = d.now()
+	return copyFile(&e.meta), nil
+}
+
+// setContentMeta sets the size and checksum Drive reports for content.
+// Folders and other Google types have neither.
+func setContentMeta(meta *drive.File, content []byte) {
+	if strings.HasPrefix(meta.MimeType, "application/vnd.google-apps.") {
+		return
+	}
+	sum := md5.Sum(content)
+	meta.Size = int64(len(content))
+	meta.QuotaBytesUsed = meta.Size
+	meta.Md5Checksum = hex.EncodeToString(sum[:])
+}
+
+// ExportFile implements driveapi.DriveClient. It returns the content set
+// with SetExport for mimeType, falling back to the stored content. Only
+// Google Workspace files can be exported.
+func (d *Drive) ExportFile(ctx context.Context, fileID, mimeType string) (io.ReadCloser, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, err := d.lookup(fileID)
+	if err != nil {
+		return nil, err
+	}
+	if !isWorkspaceType(e.meta.MimeType) {
+		return nil, &googleapi.Error{Code: http.StatusForbidden, Message: "Export only supports Docs Editors files."}
+	}
+	if data, ok := e.exports[mimeType]; ok {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	return io.NopCloser(bytes.NewReader(e.content)), nil
+}
+
+// DownloadFile implements driveapi.DriveClient. Google Workspace files and
+// folders have no binary content and cannot be downloaded.
+func (d *Drive) DownloadFile(ctx context.Context, fileID string) (io.ReadCloser, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, err := d.lookup(fileID)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(e.meta.MimeType, "application/vnd.google-apps.") {
+		return nil, &googleapi.Error{Code: http.StatusForbidden, Message: "Only files with binary content can be downloaded. Use Export with Docs Editors files."}
+	}
+	return io.NopCloser(bytes.NewReader(e.content)), nil
+}
+
+// SetExport sets what ExportFile returns for fileID in mimeType.
+func (d *Drive) SetExport(fileID, mimeType string, data []byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, err := d.lookup(fileID)
+	if err != nil {
+		return err
+	}
+	if e.exports == nil {
+		e.exports = make(map[string][]byte)
+	}
+	e.exports[mimeType] = bytes.Clone(data)
+	return nil
+}
+
+// Content returns the stored content of fileID.
+func (d *Drive) Content(fileID string) ([]byte, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, ok := d.files[fileID]
+	if !ok {
+		return nil, false
+	}
+	return bytes.Clone(e.content), true
+}
+
+func (d *Drive) lookup(fileID string) (*entry, error) {
+	e, ok := d.files[fileID]
+	if !ok {
+		return nil, &googleapi.Error{Code: http.StatusNotFound, Message: fmt.Sprintf("File not found: %s.", fileID)}
+	}
+	return e, nil
+}
+
+func readMedia(media io.Reader) ([]byte, error) {
+	if media == nil {
+		return nil, nil
+	}
+	content, err := io.ReadAll(media)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read media: %w", err)
+	}
+	return content, nil
+}
+
+func copyFile(f *drive.File) *drive.File {
+	if f == nil {
+		return &drive.File{}
+	}
+	c := *f
+	c.Parents = append([]string(nil), f.Parents...)
+	c.Owners = append([]*drive.User(nil), f.Owners...)
+	c.ForceSendFields = nil
+	return &c
+}
+
+func forced(f *drive.File, field string) bool {
+	for _, name := range f.ForceSendFields {
+		if name == field {
+			return true
+		}
+	}
+	return false
+}
+
+func guessMimeType(name string) string {
+	if t := mime.TypeByExtension(filepath.Ext(name)); t != "" {
+		if base, _, err := mime.ParseMediaType(t); err == nil {
+			return base
+		}
+	}
+	return "application/octet-stream"
+}
+
+// isWorkspaceType reports whether mimeType is an exportable Google
+// Workspace document (Docs, Sheets, Slides, Drawings and so on).
+func isWorkspaceType(mimeType string) bool {
+	return strings.HasPrefix(mimeType, "application/vnd.google-apps.") &&
+		mimeType != folderMimeType && mimeType != "application/vnd.google-apps.shortcut"
+}
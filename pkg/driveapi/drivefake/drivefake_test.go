@@ -0,0 +1,70 @@
+package drivefake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"google-drive-mcp-server/pkg/driveapi"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+func TestListFilesPaging(t *testing.T) {
+	ctx := context.Background()
+	d := New()
+	for i := range 7 {
+		if _, err := d.CreateFile(ctx, &drive.File{Name: fmt.Sprintf("f%d.txt", i)}, nil, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := d.CreateFile(ctx, &drive.File{Name: "folder", MimeType: folderMimeType}, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	var sizes []int
+	opts := driveapi.ListOptions{Query: "mimeType != 'application/vnd.google-apps.folder'", PageSize: 3, OrderBy: "name desc"}
+	for {
+		r, err := d.ListFiles(ctx, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sizes = append(sizes, len(r.Files))
+		for _, f := range r.Files {
+			names = append(names, f.Name)
+		}
+		if r.NextPageToken == "" {
+			break
+		}
+		opts.PageToken = r.NextPageToken
+	}
+	if fmt.Sprint(sizes) != "[3 3 1]" || strings.Join(names, ",") != "f6.txt,f5.txt,f4.txt,f3.txt,f2.txt,f1.txt,f0.txt" {
+		t.Errorf("pages %v: %v", sizes, names)
+	}
+
+	// A token past the end returns nothing; page sizes are clamped.
+	if r, err := d.ListFiles(ctx, driveapi.ListOptions{PageToken: "100"}); err != nil || len(r.Files) != 0 || r.NextPageToken != "" {
+		t.Errorf("past the end: %+v, %v", r, err)
+	}
+	if r, err := d.ListFiles(ctx, driveapi.ListOptions{PageSize: 5000}); err != nil || len(r.Files) != 8 {
+		t.Errorf("page size over the maximum: %+v, %v", r, err)
+	}
+
+	for _, opts := range []driveapi.ListOptions{
+		{PageToken: "abc"},
+		{PageToken: "-1"},
+		{OrderBy: "size"},
+		{Query: "name = "},
+	} {
+		_, err := d.ListFiles(ctx, opts)
+		var gerr *googleapi.Error
+		if !errors.As(err, &gerr) || gerr.Code != http.StatusBadRequest {
+			t.Errorf("%+v: got error %v, want a 400", opts, err)
+		}
+	}
+}
@@ -0,0 +1,428 @@
+package drivefake
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// matcher reports whether a file and its content match a query.
+type matcher func(f *drive.File, content []byte) bool
+
+// parseQuery compiles a files.list q expression. It supports and, or, not
+// and parentheses over these terms:
+//
+//	'<id>' in parents | '<email>' in owners
+//	name = | != | contains '<text>'
+//	mimeType = | != | contains '<text>'
+//	fullText contains '<text>'
+//	trashed | starred | sharedWithMe = | != true | false
+//	modifiedTime | createdTime | viewedByMeTime = | != | < | <= | > | >= '<RFC 3339 time>'
+//
+// Unlike Drive, "contains" is a plain case-insensitive substring match.
+func parseQuery(q string) (matcher, error) {
+	if strings.TrimSpace(q) == "" {
+		return func(*drive.File, []byte) bool { return true }, nil
+	}
+	toks, err := tokenize(q)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	m, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected '%s' at offset %d", t.text, t.pos)
+	}
+	return m, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string // Unescaped value for strings
+	pos  int
+}
+
+// tokenize splits q into tokens. String literals are single-quoted, with
+// \' and \\ as the only escapes.
+func tokenize(q string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(q); {
+		c := q[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			toks = append(toks, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			toks = append(toks, token{tokRParen, ")", i})
+			i++
+		case c == '\'':
+			start := i
+			var sb strings.Builder
+			i++
+			for {
+				if i >= len(q) {
+					return nil, fmt.Errorf("unterminated string at offset %d", start)
+				}
+				if q[i] == '\\' {
+					if i+1 >= len(q) || (q[i+1] != '\'' && q[i+1] != '\\') {
+						return nil, fmt.Errorf("invalid escape at offset %d", i)
+					}
+					sb.WriteByte(q[i+1])
+					i += 2
+					continue
+				}
+				if q[i] == '\'' {
+					i++
+					break
+				}
+				sb.WriteByte(q[i])
+				i++
+			}
+			toks = append(toks, token{tokString, sb.String(), start})
+		case c == '=' || c == '!' || c == '<' || c == '>':
+			start := i
+			i++
+			if i < len(q) && q[i] == '=' {
+				i++
+			}
+			op := q[start:i]
+			if op == "!" {
+				return nil, fmt.Errorf("unexpected '!' at offset %d", start)
+			}
+			toks = append(toks, token{tokOp, op, start})
+		case isWordByte(c):
+			start := i
+			for i < len(q) && isWordByte(q[i]) {
+				i++
+			}
+			toks = append(toks, token{tokWord, q[start:i], start})
+		default:
+			return nil, fmt.Errorf("unexpected '%c' at offset %d", c, i)
+		}
+	}
+	return append(toks, token{tokEOF, "end of query", len(q)}), nil
+}
+
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.' || c == '-' || c == ':'
+}
+
+type parser struct {
+	toks []token
+	i    int
+}
+
+func (p *parser) peek() token { return p.toks[p.i] }
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+// keyword consumes the next token if it is the word kw, ignoring case.
+func (p *parser) keyword(kw string) bool {
+	if t := p.peek(); t.kind == tokWord && strings.EqualFold(t.text, kw) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr() (matcher, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(f *drive.File, c []byte) bool { return l(f, c) || right(f, c) }
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (matcher, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(f *drive.File, c []byte) bool { return l(f, c) && right(f, c) }
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (matcher, error) {
+	if p.keyword("not") {
+		m, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(f *drive.File, c []byte) bool { return !m(f, c) }, nil
+	}
+	if p.peek().kind == tokLParen {
+		p.next()
+		m, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.kind != tokRParen {
+			return nil, fmt.Errorf("expected ')' at offset %d, got '%s'", t.pos, t.text)
+		}
+		return m, nil
+	}
+	return p.parseTerm()
+}
+
+func (p *parser) parseTerm() (matcher, error) {
+	t := p.next()
+	switch t.kind {
+	case tokString:
+		// '<value>' in <collection>
+		if !p.keyword("in") {
+			return nil, fmt.Errorf("expected 'in' after string at offset %d", t.pos)
+		}
+		field := p.next()
+		switch {
+		case field.kind == tokWord && field.text == "parents":
+			return func(f *drive.File, _ []byte) bool { return contains(f.Parents, t.text) }, nil
+		case field.kind == tokWord && field.text == "owners":
+			return func(f *drive.File, _ []byte) bool {
+				for _, o := range f.Owners {
+					if o != nil && strings.EqualFold(o.EmailAddress, t.text) {
+						return true
+					}
+				}
+				return false
+			}, nil
+		default:
+			return nil, fmt.Errorf("unsupported collection '%s' at offset %d", field.text, field.pos)
+		}
+	case tokWord:
+		return p.parseComparison(t)
+	default:
+		return nil, fmt.Errorf("unexpected '%s' at offset %d", t.text, t.pos)
+	}
+}
+
+func (p *parser) parseComparison(field token) (matcher, error) {
+	var op string
+	if p.keyword("contains") {
+		op = "contains"
+	} else if t := p.next(); t.kind == tokOp {
+		op = t.text
+	} else {
+		return nil, fmt.Errorf("expected an operator after '%s' at offset %d", field.text, t.pos)
+	}
+	value := p.next()
+	if value.kind != tokString && value.kind != tokWord {
+		return nil, fmt.Errorf("expected a value at offset %d", value.pos)
+	}
+
+	switch field.text {
+	case "name":
+		return stringTerm(field.text, op, value, func(f *drive.File, _ []byte) string { return f.Name })
+	case "mimeType":
+		return stringTerm(field.text, op, value, func(f *drive.File, _ []byte) string { return f.MimeType })
+	case "fullText":
+		if op != "contains" || value.kind != tokString {
+			return nil, errors.New("fullText only supports contains with a string")
+		}
+		needle := strings.ToLower(value.text)
+		return func(f *drive.File, c []byte) bool {
+			return strings.Contains(strings.ToLower(f.Name), needle) ||
+				strings.Contains(strings.ToLower(f.Description), needle) ||
+				strings.Contains(strings.ToLower(string(c)), needle)
+		}, nil
+	case "trashed":
+		return boolTerm(field.text, op, value, func(f *drive.File) bool { return f.Trashed })
+	case "starred":
+		return boolTerm(field.text, op, value, func(f *drive.File) bool { return f.Starred })
+	case "sharedWithMe":
+		return boolTerm(field.text, op, value, func(f *drive.File) bool { return f.SharedWithMeTime != "" })
+	case "modifiedTime":
+		return timeTerm(field.text, op, value, func(f *drive.File) string { return f.ModifiedTime })
+	case "createdTime":
+		return timeTerm(field.text, op, value, func(f *drive.File) string { return f.CreatedTime })
+	case "viewedByMeTime":
+		return timeTerm(field.text, op, value, func(f *drive.File) string { return f.ViewedByMeTime })
+	default:
+		return nil, fmt.Errorf("unsupported field '%s' at offset %d", field.text, field.pos)
+	}
+}
+
+func stringTerm(field, op string, value token, get func(*drive.File, []byte) string) (matcher, error) {
+	if value.kind != tokString {
+		return nil, fmt.Errorf("%s must be compared with a string", field)
+	}
+	v := value.text
+	switch op {
+	case "=":
+		return func(f *drive.File, c []byte) bool { return get(f, c) == v }, nil
+	case "!=":
+		return func(f *drive.File, c []byte) bool { return get(f, c) != v }, nil
+	case "contains":
+		needle := strings.ToLower(v)
+		return func(f *drive.File, c []byte) bool { return strings.Contains(strings.ToLower(get(f, c)), needle) }, nil
+	default:
+		return nil, fmt.Errorf("operator '%s' is not supported for %s", op, field)
+	}
+}
+
+func boolTerm(field, op string, value token, get func(*drive.File) bool) (matcher, error) {
+	if value.kind != tokWord || (value.text != "true" && value.text != "false") {
+		return nil, fmt.Errorf("%s must be compared with true or false", field)
+	}
+	v := value.text == "true"
+	switch op {
+	case "=":
+		return func(f *drive.File, _ []byte) bool { return get(f) == v }, nil
+	case "!=":
+		return func(f *drive.File, _ []byte) bool { return get(f) != v }, nil
+	default:
+		return nil, fmt.Errorf("operator '%s' is not supported for %s", op, field)
+	}
+}
+
+func timeTerm(field, op string, value token, get func(*drive.File) string) (matcher, error) {
+	if value.kind != tokString {
+		return nil, fmt.Errorf("%s must be compared with a quoted time", field)
+	}
+	v, err := time.Parse(time.RFC3339, value.text)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", field, err)
+	}
+	var cmp func(int) bool
+	switch op {
+	case "=":
+		cmp = func(c int) bool { return c == 0 }
+	case "!=":
+		cmp = func(c int) bool { return c != 0 }
+	case "<":
+		cmp = func(c int) bool { return c < 0 }
+	case "<=":
+		cmp = func(c int) bool { return c <= 0 }
+	case ">":
+		cmp = func(c int) bool { return c > 0 }
+	case ">=":
+		cmp = func(c int) bool { return c >= 0 }
+	default:
+		return nil, fmt.Errorf("operator '%s' is not supported for %s", op, field)
+	}
+	return func(f *drive.File, _ []byte) bool {
+		t, err := time.Parse(time.RFC3339, get(f))
+		return err == nil && cmp(t.Compare(v))
+	}, nil
+}
+
+// parseOrderBy compiles a files.list orderBy value: a comma-separated list of
+// folder, name, createdTime, modifiedTime, viewedByMeTime or quotaBytesUsed,
+// each optionally followed by desc.
+// Ties are broken by name and then ID so results are stable.
+func parseOrderBy(orderBy string) (func(a, b *drive.File) bool, error) {
+	type key struct {
+		cmp  func(a, b *drive.File) int
+		desc bool
+	}
+	var keys []key
+	for _, part := range strings.Split(orderBy, ",") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) > 2 || (len(fields) == 2 && !strings.EqualFold(fields[1], "desc") && !strings.EqualFold(fields[1], "asc")) {
+			return nil, fmt.Errorf("invalid orderBy '%s'", strings.TrimSpace(part))
+		}
+		k := key{desc: len(fields) == 2 && strings.EqualFold(fields[1], "desc")}
+		switch fields[0] {
+		case "folder":
+			k.cmp = func(a, b *drive.File) int { return boolCmp(a.MimeType == folderMimeType, b.MimeType == folderMimeType) }
+		case "name", "name_natural":
+			k.cmp = func(a, b *drive.File) int { return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)) }
+		case "createdTime":
+			k.cmp = func(a, b *drive.File) int { return timeCmp(a.CreatedTime, b.CreatedTime) }
+		case "modifiedTime", "modifiedByMeTime", "recency":
+			k.cmp = func(a, b *drive.File) int { return timeCmp(a.ModifiedTime, b.ModifiedTime) }
+		case "viewedByMeTime":
+			k.cmp = func(a, b *drive.File) int { return timeCmp(a.ViewedByMeTime, b.ViewedByMeTime) }
+		case "quotaBytesUsed":
+			k.cmp = func(a, b *drive.File) int { return cmp.Compare(a.QuotaBytesUsed, b.QuotaBytesUsed) }
+		default:
+			return nil, fmt.Errorf("unsupported orderBy key '%s'", fields[0])
+		}
+		keys = append(keys, k)
+	}
+	return func(a, b *drive.File) bool {
+		for _, k := range keys {
+			c := k.cmp(a, b)
+			if k.desc {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		if c := strings.Compare(a.Name, b.Name); c != 0 {
+			return c < 0
+		}
+		return a.Id < b.Id
+	}, nil
+}
+
+// boolCmp sorts true before false, matching Drive's "folder" ordering.
+func boolCmp(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return -1
+	default:
+		return 1
+	}
+}
+
+func timeCmp(a, b string) int {
+	ta, _ := time.Parse(time.RFC3339, a)
+	tb, _ := time.Parse(time.RFC3339, b)
+	return ta.Compare(tb)
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
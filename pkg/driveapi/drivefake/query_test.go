@@ -0,0 +1,129 @@
+package drivefake
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/drive/v3"
+)
+
+var queryFiles = []*drive.File{
+	{Id: "1", Name: "Report.pdf", MimeType: "application/pdf", Parents: []string{"root"}, Starred: true,
+		ModifiedTime: "2024-03-01T10:00:00Z", Owners: []*drive.User{{EmailAddress: "me@example.com"}}},
+	{Id: "2", Name: "Budget 2024", MimeType: "application/vnd.google-apps.spreadsheet", Parents: []string{"f1"},
+		ModifiedTime: "2024-06-01T10:00:00Z", Description: "quarterly numbers", SharedWithMeTime: "2024-06-02T00:00:00Z"},
+	{Id: "3", Name: "it's notes.txt", MimeType: "text/plain", Parents: []string{"f1", "root"}, Trashed: true,
+		ModifiedTime: "2023-12-31T23:59:59Z", Owners: []*drive.User{{EmailAddress: "Other@Example.com"}}},
+	{Id: "f1", Name: "Projects", MimeType: folderMimeType, Parents: []string{"root"}, ModifiedTime: "2024-01-01T00:00:00Z"},
+}
+
+func TestParseQuery(t *testing.T) {
+	content := map[string][]byte{"3": []byte("Remember the Budget")}
+	tests := []struct {
+		q    string
+		want string // IDs of the matching files
+	}{
+		{"", "1,2,3,f1"},
+		{"'root' in parents", "1,3,f1"},
+		{"'f1' in parents and not trashed = true", "2"},
+		{"'other@example.com' in owners", "3"},
+		{"name = 'Report.pdf'", "1"},
+		{"name != 'Report.pdf'", "2,3,f1"},
+		{"name contains 'REPORT'", "1"},
+		{`name = 'it\'s notes.txt'`, "3"},
+		{"mimeType = 'application/vnd.google-apps.folder'", "f1"},
+		{"mimeType contains 'google-apps'", "2,f1"},
+		{"fullText contains 'budget'", "2,3"},
+		{"fullText contains 'quarterly'", "2"},
+		{"trashed = true", "3"},
+		{"trashed != true", "1,2,f1"},
+		{"starred = true or sharedWithMe = true", "1,2"},
+		{"modifiedTime > '2024-01-01T00:00:00Z'", "1,2"},
+		{"modifiedTime >= '2024-01-01T00:00:00Z'", "1,2,f1"},
+		{"modifiedTime < '2024-01-01T01:00:00+01:00'", "3"},
+		{"not (trashed = true or starred = true) and 'root' in parents", "f1"},
+		{"trashed = false AND (name contains 'p' OR name contains 'b')", "1,2,f1"},
+		{"not not starred = true", "1"},
+	}
+	for _, tt := range tests {
+		m, err := parseQuery(tt.q)
+		if err != nil {
+			t.Errorf("%q: %v", tt.q, err)
+			continue
+		}
+		var got []string
+		for _, f := range queryFiles {
+			if m(f, content[f.Id]) {
+				got = append(got, f.Id)
+			}
+		}
+		if strings.Join(got, ",") != tt.want {
+			t.Errorf("%q matched %v, want %s", tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	for q, want := range map[string]string{
+		"name = 'unterminated":        "unterminated string at offset 7",
+		`name = 'bad \n escape'`:      "invalid escape at offset 12",
+		"name ! 'x'":                  "unexpected '!' at offset 5",
+		"name = 'a' and":              "unexpected 'end of query'",
+		"(name = 'a'":                 "expected ')'",
+		"name = 'a') ":                "unexpected ')' at offset 10",
+		"'root' parents":              "expected 'in' after string",
+		"'root' in children":          "unsupported collection 'children'",
+		"size > '10'":                 "unsupported field 'size'",
+		"name > 'a'":                  "operator '>' is not supported for name",
+		"name = a":                    "name must be compared with a string",
+		"trashed = 'true'":            "trashed must be compared with true or false",
+		"starred contains true":       "operator 'contains' is not supported for starred",
+		"fullText = 'x'":              "fullText only supports contains",
+		"modifiedTime > '2024-01-01'": "modifiedTime: parsing time",
+		"modifiedTime contains '2024-01-01T00:00:00Z'": "operator 'contains' is not supported for modifiedTime",
+		"name = 'a' # comment":                         "unexpected '#' at offset 11",
+		"name":                                         "expected an operator after 'name'",
+		"name =":                                       "expected a value",
+	} {
+		_, err := parseQuery(q)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: got error %v, want '%s'", q, err, want)
+		}
+	}
+}
+
+func TestParseOrderBy(t *testing.T) {
+	tests := []struct {
+		orderBy string
+		want    string
+	}{
+		{"", "2,f1,1,3"},
+		{"folder", "f1,2,1,3"},
+		{"folder, name desc", "f1,1,3,2"},
+		{"modifiedTime", "3,f1,1,2"},
+		{"modifiedTime desc", "2,1,f1,3"},
+		{"name asc", "2,3,f1,1"},
+	}
+	for _, tt := range tests {
+		less, err := parseOrderBy(tt.orderBy)
+		if err != nil {
+			t.Errorf("%q: %v", tt.orderBy, err)
+			continue
+		}
+		files := append([]*drive.File(nil), queryFiles...)
+		sort.SliceStable(files, func(i, j int) bool { return less(files[i], files[j]) })
+		var got []string
+		for _, f := range files {
+			got = append(got, f.Id)
+		}
+		if strings.Join(got, ",") != tt.want {
+			t.Errorf("%q sorted %v, want %s", tt.orderBy, got, tt.want)
+		}
+	}
+	for _, orderBy := range []string{"size", "name up", "name desc extra"} {
+		if _, err := parseOrderBy(orderBy); err == nil {
+			t.Errorf("%q was accepted", orderBy)
+		}
+	}
+}
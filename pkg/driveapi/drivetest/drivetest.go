@@ -0,0 +1,257 @@
+// Package drivetest serves a drivefake.Drive over the Drive v3 REST API with
+// net/http/httptest, so the real google.golang.org/api/drive/v3 client can be
+// exercised end to end without network access.
+//
+// It implements files.list (with the query subset drivefake understands),
+// files.get (metadata and alt=media), files.create and files.update (simple
+// and multipart uploads), files.export and about.get.
+package drivetest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+
+	"google-drive-mcp-server/pkg/driveapi"
+	"google-drive-mcp-server/pkg/driveapi/drivefake"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+// UserEmail is the address about.get reports for the fake Drive's owner.
+const UserEmail = "tester@example.com"
+
+// Server is a running fake Drive REST API.
+type Server struct {
+	*httptest.Server
+	// Drive holds the server's files; tests may seed and inspect it directly.
+	Drive *drivefake.Drive
+}
+
+// NewServer starts a server backed by d, or by an empty drive when d is nil.
+// Call Close when done.
+func NewServer(d *drivefake.Drive) *Server {
+	if d == nil {
+		d = drivefake.New()
+	}
+	s := &Server{Drive: d}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /drive/v3/about", s.about)
+	mux.HandleFunc("GET /drive/v3/files", s.list)
+	mux.HandleFunc("POST /drive/v3/files", s.create)
+	mux.HandleFunc("POST /upload/drive/v3/files", s.create)
+	mux.HandleFunc("GET /drive/v3/files/{fileId}", s.get)
+	mux.HandleFunc("PATCH /drive/v3/files/{fileId}", s.update)
+	mux.HandleFunc("PATCH /upload/drive/v3/files/{fileId}", s.update)
+	mux.HandleFunc("GET /drive/v3/files/{fileId}/export", s.export)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, &googleapi.Error{Code: http.StatusNotFound, Message: fmt.Sprintf("drivetest: %s %s is not implemented", r.Method, r.URL.Path)})
+	})
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Service returns a Drive client that talks to the server without credentials.
+func (s *Server) Service(ctx context.Context) (*drive.Service, error) {
+	return drive.NewService(ctx,
+		option.WithEndpoint(s.URL+"/drive/v3/"),
+		option.WithHTTPClient(s.Client()),
+	)
+}
+
+func (s *Server) about(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, &drive.About{User: &drive.User{EmailAddress: UserEmail, DisplayName: "Tester", Me: true}})
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	opts := driveapi.ListOptions{
+		Query:     q.Get("q"),
+		OrderBy:   q.Get("orderBy"),
+		PageToken: q.Get("pageToken"),
+	}
+	if v := q.Get("pageSize"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			writeError(w, &googleapi.Error{Code: http.StatusBadRequest, Message: "Invalid Value: pageSize"})
+			return
+		}
+		opts.PageSize = n
+	}
+	list, err := s.Drive.ListFiles(r.Context(), opts)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, list)
+}
+
+func (s *Server) get(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("fileId")
+	if r.URL.Query().Get("alt") == "media" {
+		body, err := s.Drive.DownloadFile(r.Context(), id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		defer body.Close()
+		w.Header().Set("Content-Type", "application/octet-stream")
+		io.Copy(w, body)
+		return
+	}
+	f, err := s.Drive.GetFile(r.Context(), id, r.URL.Query().Get("fields"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, f)
+}
+
+func (s *Server) export(w http.ResponseWriter, r *http.Request) {
+	mimeType := r.URL.Query().Get("mimeType")
+	if mimeType == "" {
+		writeError(w, &googleapi.Error{Code: http.StatusBadRequest, Message: "Required parameter: mimeType"})
+		return
+	}
+	body, err := s.Drive.ExportFile(r.Context(), r.PathValue("fileId"), mimeType)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	defer body.Close()
+	w.Header().Set("Content-Type", mimeType)
+	io.Copy(w, body)
+}
+
+func (s *Server) create(w http.ResponseWriter, r *http.Request) {
+	meta, media, err := readUpload(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	f, err := s.Drive.CreateFile(r.Context(), meta, media, r.URL.Query().Get("fields"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, f)
+}
+
+func (s *Server) update(w http.ResponseWriter, r *http.Request) {
+	meta, media, err := readUpload(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	f, err := s.Drive.UpdateFile(r.Context(), r.PathValue("fileId"), meta, media, r.URL.Query().Get("fields"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, f)
+}
+
+// readUpload decodes the file metadata and optional media of a create or
+// update request according to its uploadType.
+func readUpload(r *http.Request) (*drive.File, io.Reader, error) {
+	meta := &drive.File{}
+	switch uploadType := r.URL.Query().Get("uploadType"); uploadType {
+	case "":
+		if err := decodeMetadata(r.Body, meta); err != nil {
+			return nil, nil, err
+		}
+		return meta, nil, nil
+	case "media":
+		media, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, nil, err
+		}
+		return meta, bytes.NewReader(media), nil
+	case "multipart":
+		mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+			return nil, nil, badRequest("multipart upload without a multipart body")
+		}
+		mr := multipart.NewReader(r.Body, params["boundary"])
+		part, err := mr.NextPart()
+		if err != nil {
+			return nil, nil, badRequest("missing metadata part")
+		}
+		if err := decodeMetadata(part, meta); err != nil {
+			return nil, nil, err
+		}
+		part, err = mr.NextPart()
+		if err != nil {
+			return nil, nil, badRequest("missing media part")
+		}
+		media, err := io.ReadAll(part)
+		if err != nil {
+			return nil, nil, err
+		}
+		return meta, bytes.NewReader(media), nil
+	default:
+		return nil, nil, &googleapi.Error{Code: http.StatusNotImplemented, Message: fmt.Sprintf("drivetest: uploadType '%s' is not implemented", uploadType)}
+	}
+}
+
+func decodeMetadata(body io.Reader, meta *drive.File) error {
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(b)) == 0 {
+		return nil
+	}
+	// Keep explicitly sent false values, which drive.File would drop.
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return badRequest("invalid metadata: " + err.Error())
+	}
+	if err := json.Unmarshal(b, meta); err != nil {
+		return badRequest("invalid metadata: " + err.Error())
+	}
+	for key, field := range map[string]string{"trashed": "Trashed", "starred": "Starred"} {
+		if _, ok := raw[key]; ok {
+			meta.ForceSendFields = append(meta.ForceSendFields, field)
+		}
+	}
+	return nil
+}
+
+func badRequest(msg string) error {
+	return &googleapi.Error{Code: http.StatusBadRequest, Message: msg}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError reports err in Drive's JSON error format. Errors that are not
+// googleapi errors become internal server errors.
+func writeError(w http.ResponseWriter, err error) {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		apiErr = &googleapi.Error{Code: http.StatusInternalServerError, Message: err.Error()}
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(apiErr.Code)
+	json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]any{
+			"code":    apiErr.Code,
+			"message": apiErr.Message,
+			"errors":  []map[string]string{{"message": apiErr.Message}},
+		},
+	})
+}
@@ -0,0 +1,196 @@
+package drivetest_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	"google-drive-mcp-server/pkg/driveapi/drivetest"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+const folderMimeType = "application/vnd.google-apps.folder"
+
+// newService starts a fake server and returns a real Drive client for it.
+func newService(t *testing.T) (*drivetest.Server, *drive.Service) {
+	t.Helper()
+	ts := drivetest.NewServer(nil)
+	t.Cleanup(ts.Close)
+	srv, err := ts.Service(context.Background())
+	if err != nil {
+		t.Fatalf("Service: %v", err)
+	}
+	return ts, srv
+}
+
+func create(t *testing.T, srv *drive.Service, f *drive.File, content string) *drive.File {
+	t.Helper()
+	call := srv.Files.Create(f)
+	if content != "" {
+		call = call.Media(strings.NewReader(content))
+	}
+	created, err := call.Do()
+	if err != nil {
+		t.Fatalf("create %q: %v", f.Name, err)
+	}
+	return created
+}
+
+func names(files []*drive.File) []string {
+	out := make([]string, len(files))
+	for i, f := range files {
+		out[i] = f.Name
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestListQuery(t *testing.T) {
+	_, srv := newService(t)
+	folder := create(t, srv, &drive.File{Name: "Projects", MimeType: folderMimeType}, "")
+	create(t, srv, &drive.File{Name: "plan.txt", Parents: []string{folder.Id}}, "the plan")
+	create(t, srv, &drive.File{Name: "Project notes.md", Parents: []string{folder.Id}}, "notes")
+	create(t, srv, &drive.File{Name: "O'Brien's report.txt"}, "report")
+	trashed := create(t, srv, &drive.File{Name: "old plan.txt", Parents: []string{folder.Id}}, "old")
+	if _, err := srv.Files.Update(trashed.Id, &drive.File{Trashed: true}).Do(); err != nil {
+		t.Fatalf("trash: %v", err)
+	}
+
+	tests := []struct {
+		q    string
+		want []string
+	}{
+		{"'" + folder.Id + "' in parents and trashed = false", []string{"Project notes.md", "plan.txt"}},
+		{"'" + folder.Id + "' in parents", []string{"Project notes.md", "old plan.txt", "plan.txt"}},
+		{"name = 'plan.txt'", []string{"plan.txt"}},
+		{"name contains 'plan' and not trashed = true", []string{"plan.txt"}},
+		{"mimeType = '" + folderMimeType + "'", []string{"Projects"}},
+		{"mimeType != '" + folderMimeType + "' and name contains 'PROJECT'", []string{"Project notes.md"}},
+		{`name = 'O\'Brien\'s report.txt'`, []string{"O'Brien's report.txt"}},
+		{"(name = 'plan.txt' or name = 'Projects') and trashed = false", []string{"Projects", "plan.txt"}},
+	}
+	for _, tt := range tests {
+		r, err := srv.Files.List().Q(tt.q).Do()
+		if err != nil {
+			t.Errorf("q %q: %v", tt.q, err)
+			continue
+		}
+		if got := names(r.Files); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("q %q = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestListRejectsBadQuery(t *testing.T) {
+	_, srv := newService(t)
+	for _, q := range []string{"name = 'unterminated", "name ~ 'x'", "bogus = 'x'", "(name = 'x'"} {
+		_, err := srv.Files.List().Q(q).Do()
+		var apiErr *googleapi.Error
+		if !errors.As(err, &apiErr) || apiErr.Code != http.StatusBadRequest {
+			t.Errorf("q %q: got %v, want a 400 error", q, err)
+		}
+	}
+}
+
+func TestListPagination(t *testing.T) {
+	_, srv := newService(t)
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		create(t, srv, &drive.File{Name: name}, "x")
+	}
+	var got []string
+	token := ""
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("pagination did not terminate")
+		}
+		r, err := srv.Files.List().PageSize(2).PageToken(token).OrderBy("name").Do()
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		for _, f := range r.Files {
+			got = append(got, f.Name)
+		}
+		if token = r.NextPageToken; token == "" {
+			break
+		}
+	}
+	if strings.Join(got, "") != "abcde" {
+		t.Errorf("pages = %v, want a..e in order", got)
+	}
+}
+
+func TestContentRoundTrip(t *testing.T) {
+	ts, srv := newService(t)
+	f := create(t, srv, &drive.File{Name: "hello.txt"}, "hello")
+	if f.MimeType != "text/plain" {
+		t.Errorf("MimeType = %q, want text/plain", f.MimeType)
+	}
+
+	if _, err := srv.Files.Update(f.Id, &drive.File{Name: "hi.txt"}).Media(strings.NewReader("hi there")).Do(); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	got, err := srv.Files.Get(f.Id).Do()
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Name != "hi.txt" {
+		t.Errorf("Name = %q, want hi.txt", got.Name)
+	}
+
+	resp, err := srv.Files.Get(f.Id).Download()
+	if err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	defer resp.Body.Close()
+	if b, _ := io.ReadAll(resp.Body); string(b) != "hi there" {
+		t.Errorf("content = %q, want %q", b, "hi there")
+	}
+	if b, _ := ts.Drive.Content(f.Id); string(b) != "hi there" {
+		t.Errorf("stored content = %q", b)
+	}
+}
+
+func TestExport(t *testing.T) {
+	ts, srv := newService(t)
+	doc := create(t, srv, &drive.File{Name: "Doc", MimeType: "application/vnd.google-apps.document"}, "")
+	if err := ts.Drive.SetExport(doc.Id, "text/plain", []byte("exported text")); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := srv.Files.Export(doc.Id, "text/plain").Download()
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	defer resp.Body.Close()
+	if b, _ := io.ReadAll(resp.Body); string(b) != "exported text" {
+		t.Errorf("export = %q", b)
+	}
+
+	// Workspace files have no binary content, and binary files cannot be exported.
+	var apiErr *googleapi.Error
+	if _, err := srv.Files.Get(doc.Id).Download(); !errors.As(err, &apiErr) || apiErr.Code != http.StatusForbidden {
+		t.Errorf("download of a Google Doc: got %v, want 403", err)
+	}
+	txt := create(t, srv, &drive.File{Name: "a.txt"}, "a")
+	if _, err := srv.Files.Export(txt.Id, "text/plain").Download(); !errors.As(err, &apiErr) || apiErr.Code != http.StatusForbidden {
+		t.Errorf("export of a text file: got %v, want 403", err)
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	_, srv := newService(t)
+	_, err := srv.Files.Get("missing").Do()
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusNotFound {
+		t.Errorf("get missing: got %v, want 404", err)
+	}
+	_, err = srv.Files.Create(&drive.File{Name: "x", Parents: []string{"missing"}}).Do()
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusNotFound {
+		t.Errorf("create under missing parent: got %v, want 404", err)
+	}
+}
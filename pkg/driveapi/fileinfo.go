@@ -0,0 +1,153 @@
+package driveapi
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// FileInfo is the metadata reported about a file. ID, Name and MimeType are
+// always set; the rest only when selected by FileFields.
+type FileInfo struct {
+	ID                string   `json:"id"`
+	Name              string   `json:"name"`
+	MimeType          string   `json:"mime_type"`
+	Size              *int64   `json:"size,omitempty"`
+	ModifiedTime      string   `json:"modified_time,omitempty"`
+	CreatedTime       string   `json:"created_time,omitempty"`
+	Owners            []Person `json:"owners,omitempty"`
+	LastModifyingUser *Person  `json:"last_modifying_user,omitempty"`
+	WebViewLink       string   `json:"web_view_link,omitempty"`
+	Parents           []string `json:"parents,omitempty"`
+	MD5Checksum       string   `json:"md5_checksum,omitempty"`
+	Starred           *bool    `json:"starred,omitempty"`
+	Shared            *bool    `json:"shared,omitempty"`
+}
+
+// Person is a Drive user.
+type Person struct {
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+}
+
+func newPerson(u *drive.User) Person {
+	return Person{Name: u.DisplayName, Email: u.EmailAddress}
+}
+
+// fileField is one optional FileInfo field.
+type fileField struct {
+	name  string // Drive API name, also accepted by ParseFileFields
+	alias string // FileInfo JSON name, also accepted by ParseFileFields
+	drive string // files.list field selector
+	set   func(info *FileInfo, f *drive.File)
+}
+
+var fileFields = []fileField{
+	{"size", "size", "size", func(info *FileInfo, f *drive.File) {
+		// Folders and Google Workspace files have no size.
+		if !strings.HasPrefix(f.MimeType, "application/vnd.google-apps.") {
+			info.Size = &f.Size
+		}
+	}},
+	{"modifiedTime", "modified_time", "modifiedTime", func(info *FileInfo, f *drive.File) { info.ModifiedTime = f.ModifiedTime }},
+	{"createdTime", "created_time", "createdTime", func(info *FileInfo, f *drive.File) { info.CreatedTime = f.CreatedTime }},
+	{"owners", "owners", "owners(displayName, emailAddress)", func(info *FileInfo, f *drive.File) {
+		for _, u := range f.Owners {
+			if u != nil {
+				info.Owners = append(info.Owners, newPerson(u))
+			}
+		}
+	}},
+	{"lastModifyingUser", "last_modifying_user", "lastModifyingUser(displayName, emailAddress)", func(info *FileInfo, f *drive.File) {
+		if f.LastModifyingUser != nil {
+			p := newPerson(f.LastModifyingUser)
+			info.LastModifyingUser = &p
+		}
+	}},
+	{"webViewLink", "web_view_link", "webViewLink", func(info *FileInfo, f *drive.File) { info.WebViewLink = f.WebViewLink }},
+	{"parents", "parents", "parents", func(info *FileInfo, f *drive.File) { info.Parents = f.Parents }},
+	{"md5Checksum", "md5_checksum", "md5Checksum", func(info *FileInfo, f *drive.File) { info.MD5Checksum = f.Md5Checksum }},
+	{"starred", "starred", "starred", func(info *FileInfo, f *drive.File) { info.Starred = &f.Starred }},
+	{"shared", "shared", "shared", func(info *FileInfo, f *drive.File) { info.Shared = &f.Shared }},
+}
+
+// DefaultFileFields are the optional fields reported when none are requested.
+var DefaultFileFields = FileFields{fields: []*fileField{&fileFields[0], &fileFields[1]}}
+
+// FileFieldNames returns the names ParseFileFields accepts.
+func FileFieldNames() []string {
+	names := make([]string, len(fileFields))
+	for i, f := range fileFields {
+		names[i] = f.name
+	}
+	return names
+}
+
+// FileFields selects the optional metadata reported about files.
+type FileFields struct {
+	fields []*fileField
+}
+
+// ParseFileFields selects fields by their Drive API names (e.g.
+// "modifiedTime") or FileInfo JSON names (e.g. "modified_time"). No names
+// selects DefaultFileFields.
+func ParseFileFields(names []string) (FileFields, error) {
+	if len(names) == 0 {
+		return DefaultFileFields, nil
+	}
+	var ff FileFields
+	seen := map[string]bool{}
+	for _, name := range names {
+		f := lookupFileField(strings.TrimSpace(name))
+		if f == nil {
+			return FileFields{}, fmt.Errorf("unknown file field '%s': use %s", name, strings.Join(FileFieldNames(), ", "))
+		}
+		if !seen[f.name] {
+			seen[f.name] = true
+			ff.fields = append(ff.fields, f)
+		}
+	}
+	return ff, nil
+}
+
+func lookupFileField(name string) *fileField {
+	for i := range fileFields {
+		if f := &fileFields[i]; f.name == name || f.alias == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// selector returns the files.list field selector for the fields.
+func (ff FileFields) selector() string {
+	return "files(" + ff.fileSelector() + ")"
+}
+
+// fileSelector returns the files.get field selector for the fields.
+func (ff FileFields) fileSelector() string {
+	parts := []string{"id", "name", "mimeType"}
+	for _, f := range ff.fields {
+		parts = append(parts, f.drive)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Info returns the selected metadata of f.
+func (ff FileFields) Info(f *drive.File) FileInfo {
+	info := FileInfo{ID: f.Id, Name: f.Name, MimeType: f.MimeType}
+	for _, field := range ff.fields {
+		field.set(&info, f)
+	}
+	return info
+}
+
+// Infos returns the selected metadata of each file.
+func (ff FileFields) Infos(files []*drive.File) []FileInfo {
+	infos := make([]FileInfo, len(files))
+	for i, f := range files {
+		infos[i] = ff.Info(f)
+	}
+	return infos
+}
@@ -0,0 +1,52 @@
+package driveapi_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"google-drive-mcp-server/pkg/driveapi"
+
+	"google.golang.org/api/drive/v3"
+)
+
+func TestFileFieldsInfo(t *testing.T) {
+	file := &drive.File{
+		Id:                "f1",
+		Name:              "a.txt",
+		MimeType:          "text/plain",
+		Size:              0,
+		ModifiedTime:      "2024-05-01T10:00:00.000Z",
+		Owners:            []*drive.User{{DisplayName: "Ann", EmailAddress: "ann@example.com"}},
+		LastModifyingUser: &drive.User{EmailAddress: "bob@example.com"},
+		Md5Checksum:       "d41d8cd98f00b204e9800998ecf8427e",
+	}
+	tests := []struct {
+		names []string
+		want  string
+	}{
+		{nil, `{"id":"f1","name":"a.txt","mime_type":"text/plain","size":0,"modified_time":"2024-05-01T10:00:00.000Z"}`},
+		{[]string{"owners", "last_modifying_user", "md5Checksum", "starred", "owners"},
+			`{"id":"f1","name":"a.txt","mime_type":"text/plain","owners":[{"name":"Ann","email":"ann@example.com"}],"last_modifying_user":{"email":"bob@example.com"},"md5_checksum":"d41d8cd98f00b204e9800998ecf8427e","starred":false}`},
+	}
+	for _, tt := range tests {
+		fields, err := driveapi.ParseFileFields(tt.names)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := json.Marshal(fields.Info(file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("%v:\ngot  %s\nwant %s", tt.names, got, tt.want)
+		}
+	}
+
+	folder := &drive.File{Id: "d1", Name: "d", MimeType: driveapi.FolderMimeType}
+	if info := driveapi.DefaultFileFields.Info(folder); info.Size != nil {
+		t.Errorf("folder has size %d", *info.Size)
+	}
+	if _, err := driveapi.ParseFileFields([]string{"thumbnailLink"}); err == nil {
+		t.Error("unknown field was accepted")
+	}
+}
@@ -0,0 +1,197 @@
+package driveapi
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// OAuthFlow selects how a user authorizes the server in AuthModeOAuth.
+type OAuthFlow string
+
+const (
+	// OAuthFlowLoopback opens a local callback listener and redirects the
+	// browser back to it (RFC 8252 loopback redirect with PKCE).
+	OAuthFlowLoopback OAuthFlow = "loopback"
+	// OAuthFlowDevice uses the device authorization grant for machines
+	// without a browser. It requires a "TVs and Limited Input devices" client.
+	OAuthFlowDevice OAuthFlow = "device"
+)
+
+const (
+	defaultOAuthCallbackAddr = "127.0.0.1:0" // Any free loopback port
+	oauthCallbackPath        = "/oauth2callback"
+	authorizationTimeout     = 5 * time.Minute // How long to wait for the user to finish authorizing
+)
+
+// deviceAuthURL is Google's device authorization endpoint, which client
+// secret files do not carry.
+var deviceAuthURL = google.Endpoint.DeviceAuthURL
+
+// authorization is an OAuth authorization the user still has to complete.
+type authorization struct {
+	// URL is where the user has to go to grant access.
+	URL string
+	// UserCode must be entered at URL in the device flow.
+	UserCode string
+
+	wait func(ctx context.Context) (*oauth2.Token, error)
+}
+
+// Instructions describes what the user has to do to complete the authorization.
+func (a *authorization) Instructions() string {
+	if a.UserCode != "" {
+		return fmt.Sprintf("Go to %s and enter the code %s", a.URL, a.UserCode)
+	}
+	return fmt.Sprintf("Go to the following link in your browser to authorize access: %s", a.URL)
+}
+
+// startAuthorization begins the configured OAuth flow and returns the pending authorization.
+func startAuthorization(ctx context.Context, config *oauth2.Config, flow OAuthFlow, callbackAddr string) (*authorization, error) {
+	switch flow {
+	case OAuthFlowLoopback, "":
+		return startLoopbackAuthorization(config, callbackAddr)
+	case OAuthFlowDevice:
+		return startDeviceAuthorization(ctx, config)
+	default:
+		return nil, fmt.Errorf("unknown oauth flow '%s'", flow)
+	}
+}
+
+// startLoopbackAuthorization listens on callbackAddr for the OAuth redirect.
+// The request is bound to a random state and a PKCE verifier.
+func startLoopbackAuthorization(config *oauth2.Config, callbackAddr string) (*authorization, error) {
+	if callbackAddr == "" {
+		callbackAddr = defaultOAuthCallbackAddr
+	}
+	ln, err := net.Listen("tcp", callbackAddr)
+	if err != nil {
+		return nil, fmt.Errorf("unable to listen for oauth callback on '%s': %w", callbackAddr, err)
+	}
+
+	cfg := *config
+	cfg.RedirectURL = fmt.Sprintf("http://127.0.0.1:%d%s", ln.Addr().(*net.TCPAddr).Port, oauthCallbackPath)
+
+	state, err := randomState()
+	if err != nil {
+		ln.Close()
+		return nil, err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	type callbackResult struct {
+		code string
+		err  error
+	}
+	results := make(chan callbackResult, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc(oauthCallbackPath, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("state") != state {
+			http.Error(w, "invalid state parameter", http.StatusBadRequest)
+			return
+		}
+		var res callbackResult
+		switch {
+		case q.Get("error") != "":
+			res.err = fmt.Errorf("authorization denied: %s", q.Get("error"))
+			http.Error(w, "Authorization failed. You can close this window.", http.StatusForbidden)
+		case q.Get("code") == "":
+			res.err = errors.New("authorization callback did not include a code")
+			http.Error(w, "Authorization failed. You can close this window.", http.StatusBadRequest)
+		default:
+			res.code = q.Get("code")
+			fmt.Fprintln(w, "Authorization complete. You can close this window.")
+		}
+		select {
+		case results <- res:
+		default:
+		}
+	})
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go srv.Serve(ln)
+
+	return &authorization{
+		URL: cfg.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.S256ChallengeOption(verifier)),
+		wait: func(ctx context.Context) (*oauth2.Token, error) {
+			defer func() {
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+				defer cancel()
+				srv.Shutdown(shutdownCtx)
+			}()
+			select {
+			case res := <-results:
+				if res.err != nil {
+					return nil, res.err
+				}
+				tok, err := cfg.Exchange(ctx, res.code, oauth2.VerifierOption(verifier))
+				if err != nil {
+					return nil, fmt.Errorf("unable to exchange authorization code: %w", err)
+				}
+				return tok, nil
+			case <-ctx.Done():
+				return nil, fmt.Errorf("authorization was not completed: %w", ctx.Err())
+			}
+		},
+	}, nil
+}
+
+// startDeviceAuthorization requests a device code the user enters on another machine.
+func startDeviceAuthorization(ctx context.Context, config *oauth2.Config) (*authorization, error) {
+	if config.Endpoint.DeviceAuthURL == "" {
+		cfg := *config
+		cfg.Endpoint.DeviceAuthURL = deviceAuthURL
+		config = &cfg
+	}
+	resp, err := config.DeviceAuth(ctx, oauth2.AccessTypeOffline)
+	if err != nil {
+		return nil, fmt.Errorf("unable to start device authorization: %w", err)
+	}
+	uri := resp.VerificationURIComplete
+	if uri == "" {
+		uri = resp.VerificationURI
+	}
+	return &authorization{
+		URL:      uri,
+		UserCode: resp.UserCode,
+		wait: func(ctx context.Context) (*oauth2.Token, error) {
+			tok, err := config.DeviceAccessToken(ctx, resp)
+			if err != nil {
+				return nil, fmt.Errorf("device authorization failed: %w", err)
+			}
+			return tok, nil
+		},
+	}, nil
+}
+
+// randomState returns an unguessable OAuth state value.
+func randomState() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("unable to generate oauth state: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// getTokenFromWeb runs the configured OAuth flow and blocks until the user
+// has authorized the server or authorizationTimeout elapses.
+func getTokenFromWeb(ctx context.Context, config *oauth2.Config, flow OAuthFlow, callbackAddr string) (*oauth2.Token, error) {
+	auth, err := startAuthorization(ctx, config, flow, callbackAddr)
+	if err != nil {
+		return nil, err
+	}
+	log.Print(auth.Instructions())
+
+	ctx, cancel := context.WithTimeout(ctx, authorizationTimeout)
+	defer cancel()
+	return auth.wait(ctx)
+}
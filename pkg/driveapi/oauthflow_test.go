@@ -0,0 +1,82 @@
+package driveapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/oauth2/google"
+)
+
+// deviceFlowServer serves Google's device authorization and token endpoints
+// for the client "client", and points the device flow at them. It returns
+// the client secret JSON and counts the token requests.
+func deviceFlowServer(t *testing.T, polls *atomic.Int32) string {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/device/code", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("client_id") != "client" || r.FormValue("scope") != "https://www.googleapis.com/auth/drive.file" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"device_code":      "device-code",
+			"user_code":        "ABCD-EFGH",
+			"verification_url": "https://www.google.com/device",
+			"expires_in":       60,
+			"interval":         1,
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		polls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		if r.FormValue("device_code") != "device-code" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"invalid_grant"}`)
+			return
+		}
+		fmt.Fprint(w, `{"access_token":"access","refresh_token":"refresh","token_type":"Bearer","expires_in":3600}`)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	saved := deviceAuthURL
+	deviceAuthURL = srv.URL + "/device/code"
+	t.Cleanup(func() { deviceAuthURL = saved })
+	return fmt.Sprintf(`{"installed":{"client_id":"client","client_secret":"secret","auth_uri":"%[1]s/auth","token_uri":"%[1]s/token","redirect_uris":["http://localhost"]}}`, srv.URL)
+}
+
+func TestDeviceAuthorization(t *testing.T) {
+	var polls atomic.Int32
+	secret := deviceFlowServer(t, &polls)
+
+	// A client secret file has no device endpoint; the flow must add Google's.
+	config, err := google.ConfigFromJSON([]byte(secret), "https://www.googleapis.com/auth/drive.file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Endpoint.DeviceAuthURL != "" {
+		t.Fatalf("client secret config has device endpoint '%s'", config.Endpoint.DeviceAuthURL)
+	}
+
+	ctx := context.Background()
+	auth, err := startAuthorization(ctx, config, OAuthFlowDevice, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth.URL != "https://www.google.com/device" || auth.UserCode != "ABCD-EFGH" {
+		t.Errorf("got URL '%s' and code '%s'", auth.URL, auth.UserCode)
+	}
+	tok, err := auth.wait(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.AccessToken != "access" || tok.RefreshToken != "refresh" || polls.Load() != 1 {
+		t.Errorf("got token %+v after %d polls", tok, polls.Load())
+	}
+}
@@ -0,0 +1,48 @@
+package driveapi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// orderKeys maps the sort keys ParseOrderBy accepts to their files.list
+// names. Drive names and their snake_case forms are both accepted.
+var orderKeys = map[string]string{
+	"modifiedTime":     "modifiedTime",
+	"modified_time":    "modifiedTime",
+	"createdTime":      "createdTime",
+	"created_time":     "createdTime",
+	"name":             "name",
+	"quotaBytesUsed":   "quotaBytesUsed",
+	"quota_bytes_used": "quotaBytesUsed",
+	"folder":           "folder",
+}
+
+// OrderKeys are the sort keys ParseOrderBy accepts, by their Drive names.
+var OrderKeys = []string{"modifiedTime", "createdTime", "name", "quotaBytesUsed", "folder"}
+
+// ParseOrderBy compiles a sort order such as "folder, modifiedTime desc" into
+// a files.list orderBy value. Each key may be followed by asc (the default)
+// or desc.
+func ParseOrderBy(s string) (string, error) {
+	var parts []string
+	for _, part := range strings.Split(s, ",") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		key, ok := orderKeys[fields[0]]
+		if !ok {
+			return "", fmt.Errorf("unknown sort key '%s': use %s", fields[0], strings.Join(OrderKeys, ", "))
+		}
+		switch {
+		case len(fields) == 1 || len(fields) == 2 && strings.EqualFold(fields[1], "asc"):
+			parts = append(parts, key)
+		case len(fields) == 2 && strings.EqualFold(fields[1], "desc"):
+			parts = append(parts, key+" desc")
+		default:
+			return "", fmt.Errorf("invalid sort order '%s': expected a key followed by asc or desc", strings.TrimSpace(part))
+		}
+	}
+	return strings.Join(parts, ","), nil
+}
@@ -0,0 +1,28 @@
+package driveapi_test
+
+import (
+	"testing"
+
+	"google-drive-mcp-server/pkg/driveapi"
+)
+
+func TestParseOrderBy(t *testing.T) {
+	tests := map[string]string{
+		"":                                    "",
+		"name":                                "name",
+		"modifiedTime desc":                   "modifiedTime desc",
+		"folder, modified_time DESC":          "folder,modifiedTime desc",
+		" quota_bytes_used asc ,createdTime ": "quotaBytesUsed,createdTime",
+	}
+	for in, want := range tests {
+		got, err := driveapi.ParseOrderBy(in)
+		if err != nil || got != want {
+			t.Errorf("ParseOrderBy(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"size", "name sideways", "name desc extra", "viewedByMeTime"} {
+		if got, err := driveapi.ParseOrderBy(in); err == nil {
+			t.Errorf("ParseOrderBy(%q) = %q", in, got)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package driveapi
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/api/drive/v3"
+)
+
+const (
+	// DefaultPageSize is the number of items requested from Drive at a time.
+	DefaultPageSize = 100
+	// MaxPageSize is the largest page Drive returns.
+	MaxPageSize = 1000
+	// DefaultMaxResults is the number of items a listing returns by default.
+	DefaultMaxResults = 100
+	// MaxMaxResults is the most items a single listing returns.
+	MaxMaxResults = 1000
+)
+
+// PageOptions bound a listing. Zero values select the defaults.
+type PageOptions struct {
+	// PageSize is the number of items requested from Drive at a time.
+	PageSize int64
+	// PageToken continues a previous listing from its NextPageToken.
+	PageToken string
+	// MaxResults is the most items to return.
+	MaxResults int
+	// OrderBy is a files.list orderBy value, see ParseOrderBy. A PageToken
+	// must be used with the order it was returned for.
+	OrderBy string
+}
+
+// Page is one bounded part of a listing.
+type Page struct {
+	Files []*drive.File
+	// NextPageToken continues the listing, or is empty after the last item.
+	NextPageToken string
+}
+
+// withDefaults validates opts and fills in the defaults.
+func (opts PageOptions) withDefaults() (PageOptions, error) {
+	switch {
+	case opts.PageSize < 0 || opts.PageSize > MaxPageSize:
+		return opts, fmt.Errorf("page_size must be between 1 and %d", MaxPageSize)
+	case opts.MaxResults < 0 || opts.MaxResults > MaxMaxResults:
+		return opts, fmt.Errorf("max_results must be between 1 and %d", MaxMaxResults)
+	}
+	if opts.PageSize == 0 {
+		opts.PageSize = DefaultPageSize
+	}
+	if opts.MaxResults == 0 {
+		opts.MaxResults = DefaultMaxResults
+	}
+	return opts, nil
+}
+
+// listPage runs a files.list query from opts.PageToken until it has
+// opts.MaxResults items or runs out of pages. No request asks for more items
+// than are still needed, so the returned token resumes right after the last
+// returned item.
+func listPage(ctx context.Context, client DriveClient, list ListOptions, opts PageOptions) (*Page, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	page := &Page{}
+	list.Fields = "nextPageToken, " + list.Fields
+	list.PageToken = opts.PageToken
+	list.OrderBy = opts.OrderBy
+	for {
+		list.PageSize = min(opts.PageSize, int64(opts.MaxResults-len(page.Files)))
+		r, err := client.ListFiles(ctx, list)
+		if err != nil {
+			return nil, err
+		}
+		page.Files = append(page.Files, r.Files...)
+		page.NextPageToken = r.NextPageToken
+		if page.NextPageToken == "" || len(page.Files) >= opts.MaxResults {
+			return page, nil
+		}
+		list.PageToken = page.NextPageToken
+	}
+}
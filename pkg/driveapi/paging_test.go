@@ -0,0 +1,104 @@
+package driveapi_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"google-drive-mcp-server/pkg/driveapi"
+	"google-drive-mcp-server/pkg/driveapi/drivefake"
+
+	"google.golang.org/api/drive/v3"
+)
+
+func TestPagingResumesWhereItStopped(t *testing.T) {
+	ctx := context.Background()
+	d := drivefake.New()
+	for i := range 25 {
+		if _, err := d.CreateFile(ctx, &drive.File{Name: fmt.Sprintf("f%02d.txt", i)}, nil, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	seen := map[string]bool{}
+	opts := driveapi.PageOptions{PageSize: 4, MaxResults: 10}
+	var sizes []int
+	for {
+		page, err := driveapi.SearchDriveItems(ctx, d, driveapi.NotTrashed().String(), opts, driveapi.DefaultFileFields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sizes = append(sizes, len(page.Files))
+		for _, f := range page.Files {
+			if seen[f.Id] {
+				t.Errorf("%s returned twice", f.Name)
+			}
+			seen[f.Id] = true
+		}
+		if page.NextPageToken == "" {
+			break
+		}
+		opts.PageToken = page.NextPageToken
+	}
+	if fmt.Sprint(sizes) != "[10 10 5]" || len(seen) != 25 {
+		t.Errorf("page sizes %v, %d distinct files", sizes, len(seen))
+	}
+}
+
+func TestListFolderBeyondOnePage(t *testing.T) {
+	ctx := context.Background()
+	d := drivefake.New()
+	for i := range driveapi.DefaultMaxResults + 20 {
+		if _, err := d.CreateFile(ctx, &drive.File{Name: fmt.Sprintf("f%03d.txt", i)}, nil, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	page, err := driveapi.ListFilesAndFoldersInFolder(ctx, d, "", driveapi.PageOptions{}, driveapi.DefaultFileFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Files) != driveapi.DefaultMaxResults || page.NextPageToken == "" {
+		t.Fatalf("first page has %d files, token %q", len(page.Files), page.NextPageToken)
+	}
+	page, err = driveapi.ListFilesAndFoldersInFolder(ctx, d, "", driveapi.PageOptions{PageToken: page.NextPageToken}, driveapi.DefaultFileFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Files) != 20 || page.NextPageToken != "" {
+		t.Errorf("second page has %d files, token %q", len(page.Files), page.NextPageToken)
+	}
+
+	for _, opts := range []driveapi.PageOptions{{PageSize: -1}, {PageSize: driveapi.MaxPageSize + 1}, {MaxResults: driveapi.MaxMaxResults + 1}} {
+		if _, err := driveapi.ListFilesAndFoldersInFolder(ctx, d, "", opts, driveapi.DefaultFileFields); err == nil {
+			t.Errorf("%+v was accepted", opts)
+		}
+	}
+}
+
+func TestListRootFoldersBeyondOnePage(t *testing.T) {
+	ctx := context.Background()
+	d := drivefake.New()
+	for i := range 5 {
+		if _, err := d.CreateFile(ctx, &drive.File{Name: fmt.Sprintf("folder%d", i), MimeType: driveapi.FolderMimeType}, nil, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := d.CreateFile(ctx, &drive.File{Name: "file.txt"}, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := driveapi.ListRootFolders(ctx, d, driveapi.PageOptions{MaxResults: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Files) != 3 || page.NextPageToken == "" {
+		t.Fatalf("first page has %d folders, token %q", len(page.Files), page.NextPageToken)
+	}
+	page, err = driveapi.ListRootFolders(ctx, d, driveapi.PageOptions{MaxResults: 3, PageToken: page.NextPageToken})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Files) != 2 || page.NextPageToken != "" {
+		t.Errorf("second page has %d folders, token %q", len(page.Files), page.NextPageToken)
+	}
+}
@@ -0,0 +1,196 @@
+package driveapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+// ErrPathNotFound is returned when no item has a given path.
+var ErrPathNotFound = errors.New("path not found")
+
+// maxPathDepth bounds the folder chains PathsOf follows.
+const maxPathDepth = 64
+
+// AmbiguousPathError reports a path that names several items.
+type AmbiguousPathError struct {
+	Path    string
+	Matches []*drive.File
+}
+
+func (e *AmbiguousPathError) Error() string {
+	ids := make([]string, len(e.Matches))
+	for i, f := range e.Matches {
+		ids[i] = fmt.Sprintf("%s (%s)", f.Id, f.MimeType)
+	}
+	return fmt.Sprintf("path '%s' matches %d items: %s; use an ID instead", e.Path, len(e.Matches), strings.Join(ids, ", "))
+}
+
+// splitPath returns the names in path, ignoring empty ones.
+func splitPath(path string) []string {
+	var names []string
+	for _, name := range strings.Split(path, "/") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ResolvePath returns every item at path, with the given fields. A path is
+// slash-separated folder and file names, like the paths the create functions
+// take; its first name is looked up at the root of My Drive and among the
+// items shared with the user. The empty path and "/" name the root folder.
+// Several items can match because Drive allows duplicate names in a folder.
+// Names that contain a slash cannot be addressed by path.
+func ResolvePath(ctx context.Context, client DriveClient, path string, fields FileFields) ([]*drive.File, error) {
+	names := splitPath(path)
+	if len(names) == 0 {
+		root, err := client.GetFile(ctx, "root", fields.fileSelector())
+		if err != nil {
+			return nil, fmt.Errorf("unable to get the root folder: %w", err)
+		}
+		return []*drive.File{root}, nil
+	}
+
+	where := Or(InParent("root"), SharedWithMe())
+	for i, name := range names[:len(names)-1] {
+		folders, err := findChildren(ctx, client, path, where, NameIs(name), IsFolder(), FileFields{})
+		if err != nil {
+			return nil, err
+		}
+		if len(folders) == 0 {
+			return nil, fmt.Errorf("%w: '/%s' has no folder named '%s'", ErrPathNotFound, strings.Join(names[:i], "/"), name)
+		}
+		in := make([]Term, len(folders))
+		for j, f := range folders {
+			in[j] = InParent(f.Id)
+		}
+		where = Or(in...)
+	}
+	name := names[len(names)-1]
+	files, err := findChildren(ctx, client, path, where, NameIs(name), Term{}, fields)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%w: '/%s' has no item named '%s'", ErrPathNotFound, strings.Join(names[:len(names)-1], "/"), name)
+	}
+	return files, nil
+}
+
+// findChildren lists the items matching where, name and kind while resolving path.
+func findChildren(ctx context.Context, client DriveClient, path string, where, name, kind Term, fields FileFields) ([]*drive.File, error) {
+	q := And(where, name, kind, NotTrashed())
+	page, err := listPage(ctx, client, ListOptions{Query: q.String(), Fields: fields.selector()}, PageOptions{MaxResults: MaxMaxResults})
+	if err != nil {
+		log.Printf("Unable to resolve path '%s': %v", path, err)
+		return nil, fmt.Errorf("unable to resolve path '%s': %w", path, err)
+	}
+	return page.Files, nil
+}
+
+// ResolvePathOne returns the single item at path, or an *AmbiguousPathError
+// if there are several.
+func ResolvePathOne(ctx context.Context, client DriveClient, path string, fields FileFields) (*drive.File, error) {
+	files, err := ResolvePath(ctx, client, path, fields)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) > 1 {
+		return nil, &AmbiguousPathError{Path: path, Matches: files}
+	}
+	return files[0], nil
+}
+
+// PathsOf returns the paths of an item, one for each chain of parents it
+// has. Paths in My Drive start with "/"; paths of items outside it, such as
+// files shared with the user, start at the topmost folder the user can see.
+func PathsOf(ctx context.Context, client DriveClient, fileID string) ([]string, error) {
+	root, err := client.GetFile(ctx, "root", "id")
+	if err != nil {
+		return nil, fmt.Errorf("unable to get the root folder: %w", err)
+	}
+	file, err := client.GetFile(ctx, fileID, "id, name, parents")
+	if err != nil {
+		log.Printf("Unable to get file '%s': %v", fileID, err)
+		return nil, fmt.Errorf("unable to get file '%s': %w", fileID, err)
+	}
+	if file.Id == root.Id {
+		return []string{"/"}, nil
+	}
+
+	w := &pathWalker{ctx: ctx, client: client, rootID: root.Id, files: map[string]*drive.File{}}
+	chains, err := w.chains(file, 0)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, len(chains))
+	for i, names := range chains {
+		paths[i] = strings.Join(names, "/")
+	}
+	return paths, nil
+}
+
+// pathWalker follows parent links, fetching each folder once.
+type pathWalker struct {
+	ctx    context.Context
+	client DriveClient
+	rootID string
+	files  map[string]*drive.File // nil for folders the user cannot see
+}
+
+// chains returns the names from the top of each parent chain down to f. A
+// chain that reaches My Drive starts with an empty name, so it joins to an
+// absolute path.
+func (w *pathWalker) chains(f *drive.File, depth int) ([][]string, error) {
+	var chains [][]string
+	for _, parentID := range f.Parents {
+		if parentID == w.rootID {
+			chains = append(chains, []string{"", f.Name})
+			continue
+		}
+		parent, err := w.get(parentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent == nil || depth >= maxPathDepth {
+			continue
+		}
+		parentChains, err := w.chains(parent, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range parentChains {
+			chains = append(chains, append(c, f.Name))
+		}
+	}
+	if len(chains) == 0 {
+		// No visible parent: f is the top of what the user can see.
+		chains = [][]string{{f.Name}}
+	}
+	return chains, nil
+}
+
+// get returns the folder with the given ID, or nil if the user cannot see it.
+func (w *pathWalker) get(id string) (*drive.File, error) {
+	if f, ok := w.files[id]; ok {
+		return f, nil
+	}
+	f, err := w.client.GetFile(w.ctx, id, "id, name, parents")
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && (apiErr.Code == http.StatusNotFound || apiErr.Code == http.StatusForbidden) {
+		f, err = nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to get folder '%s': %w", id, err)
+	}
+	w.files[id] = f
+	return f, nil
+}
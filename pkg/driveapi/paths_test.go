@@ -0,0 +1,84 @@
+package driveapi_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"google-drive-mcp-server/pkg/driveapi"
+	"google-drive-mcp-server/pkg/driveapi/drivefake"
+
+	"google.golang.org/api/drive/v3"
+)
+
+func TestResolvePathAndPathsOf(t *testing.T) {
+	ctx := context.Background()
+	d := drivefake.New()
+	create := func(name string, folder bool, parents ...string) string {
+		t.Helper()
+		f := &drive.File{Name: name, Parents: parents}
+		if folder {
+			f.MimeType = driveapi.FolderMimeType
+		}
+		created, err := d.CreateFile(ctx, f, nil, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return created.Id
+	}
+	a := create("A", true)
+	b := create("Bob's", true, a)
+	c := create("c.txt", false, b)
+	other := create("Other", true)
+	both := create("both.txt", false, b, other)
+	create("f", false, a)
+	for range 2 {
+		create("x.txt", false, create("Dup", true))
+	}
+
+	resolve := func(path string) ([]*drive.File, error) {
+		return driveapi.ResolvePath(ctx, d, path, driveapi.FileFields{})
+	}
+	for path, want := range map[string]string{
+		"A/Bob's/c.txt":    c,
+		"/A//Bob's/c.txt/": c,
+		"/":                drivefake.RootID,
+		"Other/both.txt":   both,
+		"A/Bob's/both.txt": both,
+		"A/Bob's":          b,
+	} {
+		files, err := resolve(path)
+		if err != nil || len(files) != 1 || files[0].Id != want {
+			t.Errorf("ResolvePath(%q) = %v, %v, want %s", path, files, err, want)
+		}
+	}
+	for _, path := range []string{"A/missing.txt", "A/f/x", "Nope/c.txt", "A/Bob/c.txt"} {
+		if files, err := resolve(path); !errors.Is(err, driveapi.ErrPathNotFound) {
+			t.Errorf("ResolvePath(%q) = %v, %v, want ErrPathNotFound", path, files, err)
+		}
+	}
+
+	if files, err := resolve("Dup/x.txt"); err != nil || len(files) != 2 {
+		t.Errorf("Dup/x.txt resolved to %v, %v", files, err)
+	}
+	var ambiguous *driveapi.AmbiguousPathError
+	if _, err := driveapi.ResolvePathOne(ctx, d, "Dup/x.txt", driveapi.FileFields{}); !errors.As(err, &ambiguous) || len(ambiguous.Matches) != 2 {
+		t.Errorf("ResolvePathOne(Dup/x.txt) error = %v", err)
+	}
+
+	for id, want := range map[string]string{
+		c:                "[/A/Bob's/c.txt]",
+		both:             "[/A/Bob's/both.txt /Other/both.txt]",
+		a:                "[/A]",
+		drivefake.RootID: "[/]",
+	} {
+		paths, err := driveapi.PathsOf(ctx, d, id)
+		if err != nil || fmt.Sprint(paths) != want {
+			t.Errorf("PathsOf(%s) = %v, %v, want %s", id, paths, err, want)
+		}
+	}
+	if _, err := driveapi.PathsOf(ctx, d, "missing"); err == nil {
+		t.Error("PathsOf(missing) succeeded")
+	}
+}
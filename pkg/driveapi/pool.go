@@ -0,0 +1,143 @@
+package driveapi
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// maxImpersonatedUsers bounds the services a pool keeps for impersonated
+// users. The least recently used one is dropped to make room for another.
+const maxImpersonatedUsers = 100
+
+// ServicePool hands out Drive services for the configured identity and, when
+// the credentials support domain-wide delegation, for impersonated users on
+// the allowlist. Each impersonated user gets its own *drive.Service, created
+// on first use.
+type ServicePool struct {
+	ctx       context.Context
+	source    CredentialSource
+	scopes    []string
+	allowlist []string // Lowercased emails and "@domain" entries
+
+	mu         sync.Mutex
+	defaultSrv *drive.Service
+	byUser     map[string]*impersonatedService
+}
+
+type impersonatedService struct {
+	srv      *drive.Service
+	lastUsed time.Time
+}
+
+// NewServicePool builds the default Drive service described by cfg and
+// prepares the pool for per-user impersonation.
+func NewServicePool(ctx context.Context, cfg AuthConfig) (*ServicePool, error) {
+	source, err := NewCredentialSource(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure credentials: %w", err)
+	}
+	// Token sources outlive the call that created them, so detach them from
+	// the caller's cancellation.
+	ctx = context.WithoutCancel(ctx)
+	srv, err := newDriveService(ctx, source, cfg.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	allowlist := make([]string, len(cfg.AsUserAllowlist))
+	for i, entry := range cfg.AsUserAllowlist {
+		allowlist[i] = strings.ToLower(entry)
+	}
+	return &ServicePool{
+		ctx:        ctx,
+		source:     source,
+		scopes:     cfg.Scopes,
+		allowlist:  allowlist,
+		defaultSrv: srv,
+		byUser:     make(map[string]*impersonatedService),
+	}, nil
+}
+
+// Service returns the Drive service acting as asUser, or the default
+// identity when asUser is empty.
+func (p *ServicePool) Service(ctx context.Context, asUser string) (*drive.Service, error) {
+	if asUser == "" {
+		return p.defaultSrv, nil
+	}
+	imp, ok := p.source.(impersonator)
+	if !ok {
+		return nil, fmt.Errorf("as_user requires service account credentials with domain-wide delegation")
+	}
+	addr, err := mail.ParseAddress(asUser)
+	if err != nil || addr.Address != asUser {
+		return nil, fmt.Errorf("as_user '%s' is not a valid email address", asUser)
+	}
+	user := strings.ToLower(asUser)
+	if !p.allowed(user) {
+		return nil, fmt.Errorf("as_user '%s' is not in the account's as_user_allowlist", asUser)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if s, ok := p.byUser[user]; ok {
+		s.lastUsed = time.Now()
+		return s.srv, nil
+	}
+	srv, err := newDriveService(p.ctx, imp.impersonate(user), p.scopes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to impersonate '%s': %w", user, err)
+	}
+	if len(p.byUser) >= maxImpersonatedUsers {
+		p.evictLocked()
+	}
+	p.byUser[user] = &impersonatedService{srv: srv, lastUsed: time.Now()}
+	return srv, nil
+}
+
+// allowed reports whether the allowlist names user or its domain.
+func (p *ServicePool) allowed(user string) bool {
+	for _, entry := range p.allowlist {
+		if entry == user || strings.HasPrefix(entry, "@") && strings.HasSuffix(user, entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// evictLocked drops the least recently used impersonated service.
+func (p *ServicePool) evictLocked() {
+	var oldest string
+	for user, s := range p.byUser {
+		if oldest == "" || s.lastUsed.Before(p.byUser[oldest].lastUsed) {
+			oldest = user
+		}
+	}
+	delete(p.byUser, oldest)
+}
+
+// Reauthorization tells the user how to grant the server access again.
+type Reauthorization struct {
+	URL          string `json:"auth_url"`
+	UserCode     string `json:"user_code,omitempty"`
+	Instructions string `json:"instructions"`
+}
+
+// Reauthorize starts a new OAuth authorization for the pool's identity and
+// returns immediately. The running server picks up the new token as soon as
+// the user completes the flow.
+func (p *ServicePool) Reauthorize(ctx context.Context) (*Reauthorization, error) {
+	r, ok := p.source.(reauthorizer)
+	if !ok {
+		return nil, fmt.Errorf("reauthorization is only available for oauth credentials")
+	}
+	auth, err := r.reauthorize(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to start reauthorization: %w", err)
+	}
+	return &Reauthorization{URL: auth.URL, UserCode: auth.UserCode, Instructions: auth.Instructions()}, nil
+}
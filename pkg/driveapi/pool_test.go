@@ -0,0 +1,117 @@
+package driveapi
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/drive/v3"
+)
+
+// staticSource hands out a fixed token and records who it impersonated.
+type staticSource struct {
+	subject string
+	created *[]string
+}
+
+func (s *staticSource) impersonate(subject string) CredentialSource {
+	*s.created = append(*s.created, subject)
+	return &staticSource{subject: subject, created: s.created}
+}
+
+func (s *staticSource) TokenSource(ctx context.Context, scopes ...string) (oauth2.TokenSource, error) {
+	return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token"}), nil
+}
+
+func TestServicePoolAllowlist(t *testing.T) {
+	var created []string
+	p := &ServicePool{
+		ctx:        context.Background(),
+		source:     &staticSource{created: &created},
+		allowlist:  []string{"boss@example.com", "@eng.example.com"},
+		defaultSrv: &drive.Service{},
+		byUser:     make(map[string]*impersonatedService),
+	}
+	tests := []struct {
+		asUser  string
+		wantErr string
+	}{
+		{"", ""},
+		{"boss@example.com", ""},
+		{"Boss@Example.com", ""},
+		{"dev@eng.example.com", ""},
+		{"other@example.com", "not in the account's as_user_allowlist"},
+		{"dev@evil-eng.example.com", "not in the account's as_user_allowlist"},
+		{"dev@sub.eng.example.com", "not in the account's as_user_allowlist"},
+		{"Boss <boss@example.com>", "not a valid email address"},
+	}
+	for _, tt := range tests {
+		srv, err := p.Service(context.Background(), tt.asUser)
+		switch {
+		case tt.wantErr == "" && (err != nil || srv == nil):
+			t.Errorf("'%s': %v", tt.asUser, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("'%s': got error %v, want '%s'", tt.asUser, err, tt.wantErr)
+		}
+	}
+	if want := []string{"boss@example.com", "dev@eng.example.com"}; fmt.Sprint(created) != fmt.Sprint(want) {
+		t.Errorf("impersonated %v, want %v", created, want)
+	}
+}
+
+func TestServicePoolEvictsImpersonatedServices(t *testing.T) {
+	var created []string
+	p := &ServicePool{
+		ctx:       context.Background(),
+		source:    &staticSource{created: &created},
+		allowlist: []string{"@example.com"},
+		byUser:    make(map[string]*impersonatedService),
+	}
+	user := func(i int) string { return fmt.Sprintf("user%d@example.com", i) }
+	for i := range maxImpersonatedUsers + 10 {
+		if _, err := p.Service(context.Background(), user(i)); err != nil {
+			t.Fatal(err)
+		}
+		// Keep the first user in use.
+		if _, err := p.Service(context.Background(), user(0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(p.byUser) != maxImpersonatedUsers {
+		t.Errorf("pool keeps %d services, want %d", len(p.byUser), maxImpersonatedUsers)
+	}
+	if _, ok := p.byUser[user(0)]; !ok {
+		t.Error("the most used service was evicted")
+	}
+	if _, ok := p.byUser[user(1)]; ok {
+		t.Error("the least recently used service was kept")
+	}
+	if len(created) != maxImpersonatedUsers+10 {
+		t.Errorf("created %d services, want %d", len(created), maxImpersonatedUsers+10)
+	}
+}
+
+func TestADCImpersonationRequiresServiceAccountKey(t *testing.T) {
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", writeTestFile(t, "adc.json", `{"type":"authorized_user","client_id":"id","client_secret":"secret","refresh_token":"refresh"}`))
+	_, err := (&adcSource{subject: "user@example.com"}).TokenSource(context.Background(), drive.DriveScope)
+	if err == nil || !strings.Contains(err.Error(), "not 'authorized_user' credentials") {
+		t.Errorf("got error %v", err)
+	}
+	if _, err := (&adcSource{}).TokenSource(context.Background(), drive.DriveScope); err != nil {
+		t.Errorf("without a subject: %v", err)
+	}
+}
+
+// writeTestFile creates a file in a temporary directory and returns its path.
+func writeTestFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
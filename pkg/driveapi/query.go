@@ -0,0 +1,147 @@
+package driveapi
+
+import (
+	"strings"
+	"time"
+)
+
+// FolderMimeType is the MIME type of Drive folders.
+const FolderMimeType = "application/vnd.google-apps.folder"
+
+// ShortcutMimeType is the MIME type of Drive shortcuts.
+const ShortcutMimeType = "application/vnd.google-apps.shortcut"
+
+// Term is one compiled files.list search condition. Build terms with the
+// functions below rather than by formatting strings, so that names and IDs
+// taken from users are always escaped.
+type Term struct {
+	expr     string
+	or       bool // Top-level "or", which needs parentheses inside "and"
+	compound bool // Joins several terms, which needs parentheses after "not"
+}
+
+// String returns the query text.
+func (t Term) String() string {
+	return t.expr
+}
+
+// Quote returns s as a query string literal. Backslashes and single quotes
+// are escaped, so s can never end the literal early.
+func Quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `'`, `\'`)
+	return "'" + s + "'"
+}
+
+// NameIs matches items named exactly name.
+func NameIs(name string) Term { return Term{expr: "name = " + Quote(name)} }
+
+// NameContains matches items whose name contains s.
+func NameContains(s string) Term { return Term{expr: "name contains " + Quote(s)} }
+
+// FullTextContains matches items whose name, description or content contains s.
+func FullTextContains(s string) Term { return Term{expr: "fullText contains " + Quote(s)} }
+
+// InParent matches items directly inside the folder with the given ID.
+func InParent(folderID string) Term { return Term{expr: Quote(folderID) + " in parents"} }
+
+// MimeTypeIs matches items of the given MIME type.
+func MimeTypeIs(mimeType string) Term { return Term{expr: "mimeType = " + Quote(mimeType)} }
+
+// MimeTypeIsNot matches items not of the given MIME type.
+func MimeTypeIsNot(mimeType string) Term { return Term{expr: "mimeType != " + Quote(mimeType)} }
+
+// MimeTypeContains matches items whose MIME type contains s, e.g. "image/".
+func MimeTypeContains(s string) Term { return Term{expr: "mimeType contains " + Quote(s)} }
+
+// IsFolder matches folders.
+func IsFolder() Term { return MimeTypeIs(FolderMimeType) }
+
+// IsNotFolder matches everything but folders.
+func IsNotFolder() Term { return MimeTypeIsNot(FolderMimeType) }
+
+// OwnedBy matches items owned by the user with the given email.
+func OwnedBy(email string) Term { return Term{expr: Quote(email) + " in owners"} }
+
+// Trashed matches items in the trash.
+func Trashed() Term { return Term{expr: "trashed = true"} }
+
+// NotTrashed matches items that are not in the trash.
+func NotTrashed() Term { return Term{expr: "trashed = false"} }
+
+// Starred matches starred items.
+func Starred() Term { return Term{expr: "starred = true"} }
+
+// NotStarred matches items that are not starred.
+func NotStarred() Term { return Term{expr: "starred = false"} }
+
+// SharedWithMe matches items in the "Shared with me" collection.
+func SharedWithMe() Term { return Term{expr: "sharedWithMe = true"} }
+
+// NotSharedWithMe matches items that are not in the "Shared with me" collection.
+func NotSharedWithMe() Term { return Term{expr: "sharedWithMe = false"} }
+
+// ModifiedAfter matches items modified after t.
+func ModifiedAfter(t time.Time) Term { return timeTerm("modifiedTime >", t) }
+
+// ModifiedBefore matches items modified before t.
+func ModifiedBefore(t time.Time) Term { return timeTerm("modifiedTime <", t) }
+
+// ViewedByMeAfter matches items the user last viewed after t.
+func ViewedByMeAfter(t time.Time) Term { return timeTerm("viewedByMeTime >", t) }
+
+func timeTerm(prefix string, t time.Time) Term {
+	return Term{expr: prefix + " " + Quote(t.UTC().Format(time.RFC3339))}
+}
+
+// And matches items that match every term.
+func And(terms ...Term) Term {
+	terms = nonEmpty(terms)
+	if len(terms) == 1 {
+		return terms[0]
+	}
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		if t.or {
+			parts[i] = "(" + t.expr + ")"
+		} else {
+			parts[i] = t.expr
+		}
+	}
+	return Term{expr: strings.Join(parts, " and "), compound: len(parts) > 1}
+}
+
+// Or matches items that match any term.
+func Or(terms ...Term) Term {
+	terms = nonEmpty(terms)
+	if len(terms) == 1 {
+		return terms[0]
+	}
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		parts[i] = t.expr
+	}
+	return Term{expr: strings.Join(parts, " or "), or: len(parts) > 1, compound: len(parts) > 1}
+}
+
+// Not matches items that do not match t.
+func Not(t Term) Term {
+	if t.expr == "" {
+		return t
+	}
+	if t.compound {
+		return Term{expr: "not (" + t.expr + ")"}
+	}
+	return Term{expr: "not " + t.expr}
+}
+
+// nonEmpty drops zero-value terms, so optional conditions can be passed as Term{}.
+func nonEmpty(terms []Term) []Term {
+	out := terms[:0:0]
+	for _, t := range terms {
+		if t.expr != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
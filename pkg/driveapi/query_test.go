@@ -0,0 +1,158 @@
+package driveapi_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"google-drive-mcp-server/pkg/driveapi"
+	"google-drive-mcp-server/pkg/driveapi/drivefake"
+
+	"google.golang.org/api/drive/v3"
+)
+
+func TestQuote(t *testing.T) {
+	tests := map[string]string{
+		"plain":       `'plain'`,
+		"Bob's Notes": `'Bob\'s Notes'`,
+		`back\slash`:  `'back\\slash'`,
+		`\'`:          `'\\\''`,
+		"":            `''`,
+		`"double"`:    `'"double"'`,
+		"emoji 📁 ünï": `'emoji 📁 ünï'`,
+	}
+	for in, want := range tests {
+		if got := driveapi.Quote(in); got != want {
+			t.Errorf("Quote(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestTermComposition(t *testing.T) {
+	a, b, c := driveapi.NameIs("a"), driveapi.NameIs("b"), driveapi.NotTrashed()
+	tests := []struct {
+		term driveapi.Term
+		want string
+	}{
+		{driveapi.And(a, c), "name = 'a' and trashed = false"},
+		{driveapi.And(driveapi.Or(a, b), c), "(name = 'a' or name = 'b') and trashed = false"},
+		{driveapi.Or(driveapi.And(a, c), b), "name = 'a' and trashed = false or name = 'b'"},
+		{driveapi.And(driveapi.And(driveapi.Or(a, b)), c), "(name = 'a' or name = 'b') and trashed = false"},
+		{driveapi.And(a, driveapi.Term{}, c), "name = 'a' and trashed = false"},
+		{driveapi.Not(a), "not name = 'a'"},
+		{driveapi.Not(driveapi.Or(a, b)), "not (name = 'a' or name = 'b')"},
+		{driveapi.InParent("root"), "'root' in parents"},
+		{driveapi.ModifiedAfter(time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*3600))), "modifiedTime > '2024-05-01T10:00:00Z'"},
+	}
+	for _, tt := range tests {
+		if got := tt.term.String(); got != tt.want {
+			t.Errorf("got %s, want %s", got, tt.want)
+		}
+	}
+}
+
+// adversarialNames try to end the string literal early, inject extra
+// conditions or confuse the escaping itself.
+var adversarialNames = []string{
+	"Bob's Notes",
+	"'",
+	"''",
+	`\`,
+	`\'`,
+	`trailing\\`,
+	`Reports\`,
+	`x' or name contains 'Rep`,
+	`x' or trashed = true or name = 'y`,
+	`') or ('root' in parents`,
+	`name = 'Reports'`,
+	`a\' or name = \'Reports`,
+	`"double" quotes`,
+	"%s %d %v",
+	"  spaced  ",
+	"tab\tname",
+	"emoji 📁 ünïcödé",
+}
+
+func TestAdversarialFolderNames(t *testing.T) {
+	ctx := context.Background()
+	d := drivefake.New()
+	// Decoys that an injected condition would match.
+	decoy, err := d.CreateFile(ctx, &drive.File{Name: "Reports", MimeType: driveapi.FolderMimeType}, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.CreateFile(ctx, &drive.File{Name: "y", MimeType: driveapi.FolderMimeType}, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range adversarialNames {
+		if _, err := driveapi.FindFolderIDByName(ctx, d, name, ""); err == nil {
+			t.Errorf("%q: found a folder before one was created", name)
+		}
+
+		first, err := driveapi.CreateFileInPath(ctx, d, name+"/one.txt", "1")
+		if err != nil {
+			t.Errorf("%q: create: %v", name, err)
+			continue
+		}
+		second, err := driveapi.CreateFileInPath(ctx, d, name+"/two.txt", "2")
+		if err != nil {
+			t.Errorf("%q: second create: %v", name, err)
+			continue
+		}
+		if first.Parents[0] != second.Parents[0] {
+			t.Errorf("%q: the folder was created twice", name)
+		}
+		if first.Parents[0] == decoy.Id {
+			t.Errorf("%q: the file was created in the decoy folder", name)
+		}
+
+		folderID, err := driveapi.FindFolderIDByName(ctx, d, name, "")
+		if err != nil || folderID != first.Parents[0] {
+			t.Errorf("%q: FindFolderIDByName = %q, %v; want %q", name, folderID, err, first.Parents[0])
+		}
+		fileID, err := driveapi.FindFileIDByName(ctx, d, "one.txt", folderID)
+		if err != nil || fileID != first.Id {
+			t.Errorf("%q: FindFileIDByName = %q, %v; want %q", name, fileID, err, first.Id)
+		}
+	}
+
+	// Every adversarial folder, the decoys and nothing else sit in the root.
+	list, err := d.ListFiles(ctx, driveapi.ListOptions{Query: driveapi.And(driveapi.InParent("root"), driveapi.IsFolder()).String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := len(adversarialNames) + 2; len(list.Files) != want {
+		var names []string
+		for _, f := range list.Files {
+			names = append(names, f.Name)
+		}
+		t.Errorf("root has %d folders, want %d: %s", len(list.Files), want, strings.Join(names, " | "))
+	}
+}
+
+func TestAdversarialFileNames(t *testing.T) {
+	ctx := context.Background()
+	d := drivefake.New()
+	if _, err := driveapi.CreateFileInPath(ctx, d, "Reports.txt", "decoy"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range adversarialNames {
+		if _, err := driveapi.FindFileIDByName(ctx, d, name, "root"); err == nil {
+			t.Errorf("%q: matched a file before one was created", name)
+		}
+		created, err := driveapi.CreateFileInPath(ctx, d, name, "content")
+		if err != nil {
+			t.Errorf("%q: create: %v", name, err)
+			continue
+		}
+		if created.Name != name {
+			t.Errorf("created %q, want %q", created.Name, name)
+		}
+		id, err := driveapi.FindFileIDByName(ctx, d, name, "root")
+		if err != nil || id != created.Id {
+			t.Errorf("%q: FindFileIDByName = %q, %v; want %q", name, id, err, created.Id)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package driveapi
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// mimeTypeAliases are the short names search criteria accept in place of
+// full MIME types.
+var mimeTypeAliases = map[string]string{
+	"folder":       FolderMimeType,
+	"document":     GoogleDocMimeType,
+	"spreadsheet":  GoogleSheetMimeType,
+	"presentation": GoogleSlidesMimeType,
+	"drawing":      GoogleDrawingMimeType,
+	"form":         "application/vnd.google-apps.form",
+	"shortcut":     ShortcutMimeType,
+	"pdf":          PDFMimeType,
+	"docx":         DocxMimeType,
+	"xlsx":         XLSXMimeType,
+	"pptx":         PPTXMimeType,
+	"text":         "text/plain",
+	"csv":          "text/csv",
+	"markdown":     "text/markdown",
+	"image":        "image/",
+}
+
+// MimeTypeAliases returns the short MIME type names ResolveMimeType accepts, sorted.
+func MimeTypeAliases() []string {
+	names := make([]string, 0, len(mimeTypeAliases))
+	for name := range mimeTypeAliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResolveMimeType returns the MIME type for a short alias such as "pdf" or
+// "spreadsheet". Full MIME types are returned unchanged. A result ending in
+// "/" is a prefix, e.g. "image/" for every image type.
+func ResolveMimeType(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if mimeType, ok := mimeTypeAliases[strings.ToLower(s)]; ok {
+		return mimeType, nil
+	}
+	if strings.Contains(s, "/") {
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown MIME type '%s': use a full MIME type or one of %s", s, strings.Join(MimeTypeAliases(), ", "))
+}
+
+// SearchCriteria are the structured filters of a file search. Every set
+// field must match; zero values are ignored.
+type SearchCriteria struct {
+	// NameContains matches items whose name contains the text.
+	NameContains string
+	// FullText matches items whose name, description or content contains the text.
+	FullText string
+	// MimeTypes matches items of any of the types, given as MIME types or aliases.
+	MimeTypes []string
+	// ParentID matches items directly inside the folder.
+	ParentID string
+	// Owner matches items owned by the user with this email, or "me".
+	Owner string
+	// ModifiedAfter and ModifiedBefore bound the modification time.
+	ModifiedAfter, ModifiedBefore time.Time
+	// Starred and SharedWithMe match items with or without the flag when set.
+	Starred, SharedWithMe *bool
+	// Trashed searches the trash instead of everything outside it.
+	Trashed bool
+}
+
+// Query compiles the criteria into a files.list query. Every value is
+// escaped, so the query matches exactly the given criteria.
+func (c *SearchCriteria) Query() (Term, error) {
+	if !c.ModifiedAfter.IsZero() && !c.ModifiedBefore.IsZero() && !c.ModifiedAfter.Before(c.ModifiedBefore) {
+		return Term{}, fmt.Errorf("modified_after must be earlier than modified_before")
+	}
+	var mimeTypes []Term
+	for _, m := range c.MimeTypes {
+		mimeType, err := ResolveMimeType(m)
+		if err != nil {
+			return Term{}, err
+		}
+		if strings.HasSuffix(mimeType, "/") {
+			mimeTypes = append(mimeTypes, MimeTypeContains(mimeType))
+		} else {
+			mimeTypes = append(mimeTypes, MimeTypeIs(mimeType))
+		}
+	}
+
+	terms := []Term{Or(mimeTypes...)}
+	if c.NameContains != "" {
+		terms = append(terms, NameContains(c.NameContains))
+	}
+	if c.FullText != "" {
+		terms = append(terms, FullTextContains(c.FullText))
+	}
+	if c.ParentID != "" {
+		terms = append(terms, InParent(c.ParentID))
+	}
+	if c.Owner != "" {
+		terms = append(terms, OwnedBy(c.Owner))
+	}
+	if !c.ModifiedAfter.IsZero() {
+		terms = append(terms, ModifiedAfter(c.ModifiedAfter))
+	}
+	if !c.ModifiedBefore.IsZero() {
+		terms = append(terms, ModifiedBefore(c.ModifiedBefore))
+	}
+	if c.Starred != nil {
+		terms = append(terms, pick(*c.Starred, Starred(), NotStarred()))
+	}
+	if c.SharedWithMe != nil {
+		terms = append(terms, pick(*c.SharedWithMe, SharedWithMe(), NotSharedWithMe()))
+	}
+	terms = append(terms, pick(c.Trashed, Trashed(), NotTrashed()))
+	return And(terms...), nil
+}
+
+func pick(cond bool, ifTrue, ifFalse Term) Term {
+	if cond {
+		return ifTrue
+	}
+	return ifFalse
+}
+
+// Recency selects what makes a file recent.
+type Recency string
+
+const (
+	// RecentlyModified orders files by when anyone last modified them.
+	RecentlyModified Recency = "modified"
+	// RecentlyViewed orders files by when the user last viewed them.
+	RecentlyViewed Recency = "viewed"
+)
+
+// RecentFiles lists the files across the whole Drive that were most recently
+// modified or viewed, newest first. Folders and trashed files are left out,
+// and so are files the user never viewed when ordering by views.
+func RecentFiles(ctx context.Context, client DriveClient, by Recency, page PageOptions, fields FileFields) (*Page, error) {
+	q := And(IsNotFolder(), NotTrashed())
+	switch by {
+	case RecentlyModified, "":
+		page.OrderBy = "modifiedTime desc"
+	case RecentlyViewed:
+		q = And(q, ViewedByMeAfter(time.Unix(0, 0)))
+		page.OrderBy = "viewedByMeTime desc"
+	default:
+		return nil, fmt.Errorf("unknown recency '%s': use %s or %s", by, RecentlyModified, RecentlyViewed)
+	}
+	r, err := listPage(ctx, client, ListOptions{Query: q.String(), Fields: fields.selector()}, page)
+	if err != nil {
+		log.Printf("Unable to list recent files: %v", err)
+		return nil, fmt.Errorf("unable to list recent files: %w", err)
+	}
+	return r, nil
+}
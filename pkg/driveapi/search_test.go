@@ -0,0 +1,95 @@
+package driveapi_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google-drive-mcp-server/pkg/driveapi"
+	"google-drive-mcp-server/pkg/driveapi/drivefake"
+
+	"google.golang.org/api/drive/v3"
+)
+
+func TestSearchCriteriaQuery(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		criteria driveapi.SearchCriteria
+		want     string
+	}{
+		{driveapi.SearchCriteria{}, "trashed = false"},
+		{driveapi.SearchCriteria{Trashed: true}, "trashed = true"},
+		{
+			driveapi.SearchCriteria{NameContains: "Bob's", MimeTypes: []string{"pdf", "image"}},
+			"(mimeType = 'application/pdf' or mimeType contains 'image/') and name contains 'Bob\\'s' and trashed = false",
+		},
+		{
+			driveapi.SearchCriteria{FullText: "budget", ParentID: "abc", Owner: "me", Starred: &yes, SharedWithMe: &no},
+			"fullText contains 'budget' and 'abc' in parents and 'me' in owners and starred = true and sharedWithMe = false and trashed = false",
+		},
+		{
+			driveapi.SearchCriteria{
+				MimeTypes:      []string{"text/csv"},
+				ModifiedAfter:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				ModifiedBefore: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			},
+			"mimeType = 'text/csv' and modifiedTime > '2024-01-01T00:00:00Z' and modifiedTime < '2024-02-01T00:00:00Z' and trashed = false",
+		},
+	}
+	for _, tt := range tests {
+		q, err := tt.criteria.Query()
+		if err != nil {
+			t.Errorf("%+v: %v", tt.criteria, err)
+			continue
+		}
+		if got := q.String(); got != tt.want {
+			t.Errorf("got  %s\nwant %s", got, tt.want)
+		}
+	}
+}
+
+func TestSearchCriteriaErrors(t *testing.T) {
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, c := range []driveapi.SearchCriteria{
+		{MimeTypes: []string{"spreadsheets"}},
+		{ModifiedAfter: day, ModifiedBefore: day},
+	} {
+		if q, err := c.Query(); err == nil {
+			t.Errorf("%+v compiled to %s", c, q)
+		}
+	}
+}
+
+func TestSearchCriteriaAdversarialNames(t *testing.T) {
+	ctx := context.Background()
+	d := drivefake.New()
+	if _, err := d.CreateFile(ctx, &drive.File{Name: "Reports", Starred: true}, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range adversarialNames {
+		if _, err := d.CreateFile(ctx, &drive.File{Name: name + ".txt"}, nil, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range adversarialNames {
+		c := driveapi.SearchCriteria{NameContains: name + ".txt", Starred: new(bool)}
+		q, err := c.Query()
+		if err != nil {
+			t.Fatal(err)
+		}
+		page, err := driveapi.SearchDriveItems(ctx, d, q.String(), driveapi.PageOptions{}, driveapi.DefaultFileFields)
+		if err != nil {
+			t.Errorf("%q: %v", name, err)
+			continue
+		}
+		files := page.Files
+		for _, f := range files {
+			if f.Name == "Reports" {
+				t.Errorf("%q: query %s matched the decoy", name, q)
+			}
+		}
+		if len(files) == 0 {
+			t.Errorf("%q: query %s matched nothing", name, q)
+		}
+	}
+}
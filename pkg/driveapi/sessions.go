@@ -0,0 +1,264 @@
+package driveapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+)
+
+// ErrSessionNotAuthorized is returned for MCP sessions whose user has not
+// completed the OAuth authorization yet.
+var ErrSessionNotAuthorized = errors.New("this session is not authorized with Google Drive: call the authorize tool and open the returned link")
+
+// errSessionCaller is returned when a caller uses a session that another
+// caller authorized.
+var errSessionCaller = errors.New("this session belongs to another caller")
+
+// sessionIdleTimeout drops sessions that were never unregistered by the
+// transport, e.g. streamable HTTP clients that go away without a DELETE.
+const sessionIdleTimeout = 24 * time.Hour
+
+// SessionAuth gives every MCP session its own Google identity. Each session
+// runs the OAuth web flow, redirecting back to the server itself, and keeps
+// its token in memory until the session ends. A session is bound to the
+// authenticated caller that authorized it, and only that caller can use it.
+type SessionAuth struct {
+	ctx     context.Context
+	config  *oauth2.Config
+	options []option.ClientOption // Extra options of the sessions' Drive services
+
+	mu       sync.Mutex
+	sessions map[string]*sessionIdentity
+	pending  map[string]*pendingSessionAuth // By OAuth state
+}
+
+type sessionIdentity struct {
+	caller   string
+	srv      *drive.Service
+	email    string
+	lastUsed time.Time
+}
+
+type pendingSessionAuth struct {
+	sessionID  string
+	caller     string
+	verifier   string
+	expires    time.Time
+	exchanging bool // The callback arrived and its code is being exchanged
+}
+
+// NewSessionAuth reads the OAuth client secret (a "Web application" client
+// that allows redirectURL) and prepares per-session authorization.
+func NewSessionAuth(ctx context.Context, clientSecretFile, redirectURL string, scopes []string) (*SessionAuth, error) {
+	if len(scopes) == 0 {
+		scopes = []string{drive.DriveScope}
+	}
+	b, err := os.ReadFile(clientSecretFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read client secret file '%s': %w", clientSecretFile, err)
+	}
+	config, err := google.ConfigFromJSON(b, scopes...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse client secret file to config: %w", err)
+	}
+	config.RedirectURL = redirectURL
+	return &SessionAuth{
+		ctx:      context.WithoutCancel(ctx),
+		config:   config,
+		sessions: make(map[string]*sessionIdentity),
+		pending:  make(map[string]*pendingSessionAuth),
+	}, nil
+}
+
+// Authorize starts an authorization of sessionID for caller, the
+// authenticated principal of the request. Completing it replaces any
+// identity the session already had.
+func (a *SessionAuth) Authorize(sessionID, caller string) (*Reauthorization, error) {
+	if sessionID == "" {
+		return nil, errors.New("per-session authorization requires an MCP session")
+	}
+	if caller == "" {
+		return nil, errors.New("per-session authorization requires an authenticated caller")
+	}
+	state, err := randomState()
+	if err != nil {
+		return nil, err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	a.mu.Lock()
+	a.pruneLocked(time.Now())
+	if err := a.checkCallerLocked(sessionID, caller); err != nil {
+		a.mu.Unlock()
+		return nil, err
+	}
+	a.pending[state] = &pendingSessionAuth{
+		sessionID: sessionID,
+		caller:    caller,
+		verifier:  verifier,
+		expires:   time.Now().Add(authorizationTimeout),
+	}
+	a.mu.Unlock()
+
+	auth := &authorization{
+		URL: a.config.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.S256ChallengeOption(verifier),
+			oauth2.SetAuthURLParam("prompt", "select_account consent")),
+	}
+	return &Reauthorization{URL: auth.URL, Instructions: auth.Instructions()}, nil
+}
+
+// Service returns the Drive service of the user who authorized sessionID.
+// caller must be the principal that authorized it.
+func (a *SessionAuth) Service(sessionID, caller string) (*drive.Service, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	identity, ok := a.sessions[sessionID]
+	if !ok {
+		return nil, ErrSessionNotAuthorized
+	}
+	if identity.caller != caller {
+		log.Printf("Session %s authorized by '%s' was used by '%s'", sessionID, identity.caller, caller)
+		return nil, errSessionCaller
+	}
+	identity.lastUsed = time.Now()
+	return identity.srv, nil
+}
+
+// checkCallerLocked fails if sessionID is authorized, or being authorized,
+// by a caller other than caller.
+func (a *SessionAuth) checkCallerLocked(sessionID, caller string) error {
+	if identity, ok := a.sessions[sessionID]; ok && identity.caller != caller {
+		return errSessionCaller
+	}
+	for _, p := range a.pending {
+		if p.sessionID == sessionID && p.caller != caller {
+			return errSessionCaller
+		}
+	}
+	return nil
+}
+
+// Email returns the Google account that authorized sessionID, if any.
+func (a *SessionAuth) Email(sessionID string) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if identity, ok := a.sessions[sessionID]; ok {
+		return identity.email
+	}
+	return ""
+}
+
+// Forget drops the identity and any pending authorization of sessionID.
+func (a *SessionAuth) Forget(sessionID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.sessions, sessionID)
+	for state, p := range a.pending {
+		if p.sessionID == sessionID {
+			delete(a.pending, state)
+		}
+	}
+}
+
+// pruneLocked drops expired authorizations and idle sessions.
+func (a *SessionAuth) pruneLocked(now time.Time) {
+	for state, p := range a.pending {
+		if now.After(p.expires) && !p.exchanging {
+			delete(a.pending, state)
+		}
+	}
+	for id, identity := range a.sessions {
+		if now.Sub(identity.lastUsed) > sessionIdleTimeout {
+			delete(a.sessions, id)
+		}
+	}
+}
+
+// CallbackHandler completes authorizations when Google redirects the
+// browser back to the redirect URL. It must be served without inbound
+// authentication, since the browser does not carry the client's credentials;
+// the single-use state ties each callback to the session that started it.
+// The authorization stays pending while its code is exchanged, so that a
+// session forgotten meanwhile does not get a token.
+func (a *SessionAuth) CallbackHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		state := q.Get("state")
+		a.mu.Lock()
+		p, ok := a.pending[state]
+		ok = ok && !p.exchanging && !time.Now().After(p.expires)
+		if ok {
+			p.exchanging = true
+		}
+		a.mu.Unlock()
+		if !ok {
+			http.Error(w, "Unknown or expired authorization. Call the authorize tool again.", http.StatusBadRequest)
+			return
+		}
+		defer func() {
+			a.mu.Lock()
+			defer a.mu.Unlock()
+			if a.pending[state] == p {
+				delete(a.pending, state)
+			}
+		}()
+		if e := q.Get("error"); e != "" {
+			log.Printf("Session %s authorization denied: %s", p.sessionID, e)
+			http.Error(w, "Authorization failed. You can close this window.", http.StatusForbidden)
+			return
+		}
+
+		email, err := a.complete(r.Context(), state, p, q.Get("code"))
+		if err != nil {
+			log.Printf("Session %s authorization failed: %v", p.sessionID, err)
+			http.Error(w, "Authorization failed. You can close this window.", http.StatusBadGateway)
+			return
+		}
+		log.Printf("Session %s authorized as %s", p.sessionID, email)
+		fmt.Fprintf(w, "Authorized as %s. You can close this window.\n", html.EscapeString(email))
+	})
+}
+
+// complete exchanges the authorization code and stores the session's
+// identity, unless the session was forgotten in the meantime.
+func (a *SessionAuth) complete(ctx context.Context, state string, p *pendingSessionAuth, code string) (string, error) {
+	if code == "" {
+		return "", errors.New("authorization callback did not include a code")
+	}
+	tok, err := a.config.Exchange(ctx, code, oauth2.VerifierOption(p.verifier))
+	if err != nil {
+		return "", fmt.Errorf("unable to exchange authorization code: %w", err)
+	}
+	srv, err := drive.NewService(a.ctx, append([]option.ClientOption{option.WithTokenSource(a.config.TokenSource(a.ctx, tok))}, a.options...)...)
+	if err != nil {
+		return "", fmt.Errorf("unable to retrieve Drive client: %w", err)
+	}
+	about, err := srv.About.Get().Fields("user(emailAddress)").Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("unable to look up the authorized user: %w", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.pending[state] != p {
+		// Dropping the service drops the token with it.
+		return "", errors.New("the session ended during authorization")
+	}
+	delete(a.pending, state)
+	if identity, ok := a.sessions[p.sessionID]; ok && identity.caller != p.caller {
+		return "", errSessionCaller
+	}
+	a.sessions[p.sessionID] = &sessionIdentity{caller: p.caller, srv: srv, email: about.User.EmailAddress, lastUsed: time.Now()}
+	return about.User.EmailAddress, nil
+}
@@ -0,0 +1,125 @@
+package driveapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+)
+
+// newTestSessionAuth returns a SessionAuth whose OAuth client exchanges codes
+// at tokenURL.
+func newTestSessionAuth(t *testing.T, tokenURL string) *SessionAuth {
+	t.Helper()
+	secret := writeTestFile(t, "client.json", fmt.Sprintf(`{"web": {"client_id": "id", "client_secret": "secret", "auth_uri": "https://accounts.example.com/auth", "token_uri": %q, "redirect_uris": ["https://mcp.example.com/oauth/callback"]}}`, tokenURL))
+	a, err := NewSessionAuth(t.Context(), secret, "https://mcp.example.com/oauth/callback", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestSessionAuthCaller(t *testing.T) {
+	a := newTestSessionAuth(t, "https://accounts.example.com/token")
+
+	if _, err := a.Authorize("s1", ""); err == nil {
+		t.Error("an unauthenticated caller started an authorization")
+	}
+	if _, err := a.Authorize("s1", "api_key:alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Authorize("s1", "api_key:mallory"); !errors.Is(err, errSessionCaller) {
+		t.Errorf("another caller authorizing a pending session: got error %v", err)
+	}
+
+	srv := &drive.Service{}
+	a.mu.Lock()
+	a.sessions["s1"] = &sessionIdentity{caller: "api_key:alice", srv: srv, lastUsed: time.Now()}
+	a.mu.Unlock()
+	if got, err := a.Service("s1", "api_key:alice"); err != nil || got != srv {
+		t.Errorf("Service for the authorizing caller: %v, %v", got, err)
+	}
+	if _, err := a.Service("s1", "api_key:mallory"); !errors.Is(err, errSessionCaller) {
+		t.Errorf("Service for another caller: got error %v", err)
+	}
+	if _, err := a.Authorize("s1", "api_key:mallory"); !errors.Is(err, errSessionCaller) {
+		t.Errorf("another caller reauthorizing the session: got error %v", err)
+	}
+	if _, err := a.Service("s2", "api_key:alice"); !errors.Is(err, ErrSessionNotAuthorized) {
+		t.Errorf("Service for an unknown session: got error %v", err)
+	}
+
+	a.Forget("s1")
+	if _, err := a.Authorize("s1", "api_key:mallory"); err != nil {
+		t.Errorf("authorizing a forgotten session: %v", err)
+	}
+}
+
+func TestSessionAuthCallback(t *testing.T) {
+	var a *SessionAuth
+	var forget bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token":"access","refresh_token":"refresh","token_type":"Bearer","expires_in":3600}`)
+	})
+	mux.HandleFunc("/about", func(w http.ResponseWriter, r *http.Request) {
+		if forget {
+			// The session closes while its code is being exchanged.
+			a.Forget("s1")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"user":{"emailAddress":"alice@example.com"}}`)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	a = newTestSessionAuth(t, srv.URL+"/token")
+	a.options = []option.ClientOption{option.WithEndpoint(srv.URL + "/")}
+
+	callback := func() int {
+		t.Helper()
+		auth, err := a.Authorize("s1", "api_key:alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+		u, err := url.Parse(auth.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		state := u.Query().Get("state")
+		w := httptest.NewRecorder()
+		a.CallbackHandler().ServeHTTP(w, httptest.NewRequest("GET", "/oauth/callback?code=code&state="+url.QueryEscape(state), nil))
+		// The state is single-use.
+		again := httptest.NewRecorder()
+		a.CallbackHandler().ServeHTTP(again, httptest.NewRequest("GET", "/oauth/callback?code=code&state="+url.QueryEscape(state), nil))
+		if again.Code != http.StatusBadRequest {
+			t.Errorf("reused state: got status %d", again.Code)
+		}
+		return w.Code
+	}
+
+	forget = true
+	if code := callback(); code != http.StatusBadGateway {
+		t.Errorf("session forgotten during the exchange: got status %d", code)
+	}
+	a.mu.Lock()
+	sessions, pending := len(a.sessions), len(a.pending)
+	a.mu.Unlock()
+	if sessions != 0 || pending != 0 {
+		t.Errorf("forgotten session kept %d identities and %d pending authorizations", sessions, pending)
+	}
+
+	forget = false
+	if code := callback(); code != http.StatusOK {
+		t.Errorf("callback: got status %d", code)
+	}
+	if email := a.Email("s1"); email != "alice@example.com" {
+		t.Errorf("session authorized as '%s'", email)
+	}
+}
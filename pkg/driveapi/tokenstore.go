@@ -0,0 +1,148 @@
+package driveapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// ErrReauthorizationRequired is returned by Drive calls once Google has
+// rejected the stored refresh token (revoked, expired or password changed).
+// Run the reauthorize tool to grant access again without restarting.
+var ErrReauthorizationRequired = errors.New("reauthorization required: the stored OAuth refresh token was revoked or has expired; run the reauthorize tool")
+
+// persistentTokenSource hands out tokens for an OAuth user and writes every
+// refreshed token back to the token file, so restarts pick up the latest one.
+type persistentTokenSource struct {
+	ctx    context.Context
+	config *oauth2.Config
+	path   string
+
+	mu   sync.Mutex
+	base oauth2.TokenSource
+	last *oauth2.Token
+}
+
+func newPersistentTokenSource(ctx context.Context, config *oauth2.Config, path string, tok *oauth2.Token) *persistentTokenSource {
+	return &persistentTokenSource{
+		ctx:    ctx,
+		config: config,
+		path:   path,
+		base:   config.TokenSource(ctx, tok),
+		last:   tok,
+	}
+}
+
+// Token implements oauth2.TokenSource.
+func (s *persistentTokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	base := s.base
+	s.mu.Unlock()
+
+	tok, err := base.Token()
+	if err != nil {
+		var re *oauth2.RetrieveError
+		if errors.As(err, &re) && re.ErrorCode == "invalid_grant" {
+			return nil, fmt.Errorf("%w (%v)", ErrReauthorizationRequired, err)
+		}
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.last == nil || tok.AccessToken != s.last.AccessToken {
+		// A failed write must not fail the API call; the token is still valid.
+		if err := saveToken(s.path, tok, s.config.Scopes); err != nil {
+			log.Printf("Unable to persist refreshed OAuth token: %v", err)
+		}
+		s.last = tok
+	}
+	return tok, nil
+}
+
+// reset replaces the current token, e.g. after the user reauthorized.
+func (s *persistentTokenSource) reset(tok *oauth2.Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.base = s.config.TokenSource(s.ctx, tok)
+	s.last = tok
+	return saveToken(s.path, tok, s.config.Scopes)
+}
+
+// storedToken is the token file: the token and the scopes it was granted for.
+type storedToken struct {
+	*oauth2.Token
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// tokenFromFile retrieves a token from a local file. A token granted for
+// other scopes than these is an error, so that e.g. read-only mode never
+// reuses a full-access token.
+func tokenFromFile(file string, scopes []string) (*oauth2.Token, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var stored storedToken
+	if err := json.NewDecoder(f).Decode(&stored); err != nil {
+		return nil, err
+	}
+	if stored.Token == nil {
+		return nil, fmt.Errorf("token file '%s' holds no token", file)
+	}
+	if !sameScopes(stored.Scopes, scopes) {
+		return nil, fmt.Errorf("the token in '%s' was granted for scopes %v, not %v", file, stored.Scopes, scopes)
+	}
+	return stored.Token, nil
+}
+
+// sameScopes reports whether a and b hold the same scopes in any order.
+func sameScopes(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(slices.Compact(a), slices.Compact(b))
+}
+
+// saveToken atomically saves a token and the scopes it was granted for to a
+// file path. The token is written to a temporary file in the same directory
+// and renamed over the old one, so a crash never leaves a truncated token
+// behind.
+func saveToken(path string, token *oauth2.Token, scopes []string) error {
+	log.Printf("Saving credential file to: %s", path)
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("unable to cache OAuth client token: %w", err)
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp) // No-op once renamed
+
+	if err := f.Chmod(0600); err != nil {
+		f.Close()
+		return fmt.Errorf("unable to cache OAuth client token: %w", err)
+	}
+	if err := json.NewEncoder(f).Encode(storedToken{Token: token, Scopes: scopes}); err != nil {
+		f.Close()
+		return fmt.Errorf("unable to cache OAuth client token: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("unable to cache OAuth client token: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("unable to cache OAuth client token: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("unable to cache OAuth client token: %w", err)
+	}
+	return nil
+}
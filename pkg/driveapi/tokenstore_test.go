@@ -0,0 +1,39 @@
+package driveapi
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/drive/v3"
+)
+
+func TestTokenFileScopes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token.json")
+	tok := &oauth2.Token{AccessToken: "access", RefreshToken: "refresh", TokenType: "Bearer", Expiry: time.Now().Add(time.Hour).Round(time.Second)}
+	full := []string{drive.DriveScope, drive.DriveMetadataReadonlyScope}
+	if err := saveToken(path, tok, full); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := tokenFromFile(path, []string{drive.DriveMetadataReadonlyScope, drive.DriveScope})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.AccessToken != tok.AccessToken || got.RefreshToken != tok.RefreshToken || !got.Expiry.Equal(tok.Expiry) {
+		t.Errorf("got token %+v, want %+v", got, tok)
+	}
+
+	// A read-only server must not reuse the full-access token.
+	if _, err := tokenFromFile(path, []string{drive.DriveReadonlyScope}); err == nil || !strings.Contains(err.Error(), "was granted for scopes") {
+		t.Errorf("got error %v for other scopes", err)
+	}
+
+	// Token files written before scopes were recorded are not trusted.
+	legacy := writeTestFile(t, "legacy.json", `{"access_token":"access","refresh_token":"refresh"}`)
+	if _, err := tokenFromFile(legacy, full); err == nil {
+		t.Error("a token without scopes was accepted")
+	}
+}
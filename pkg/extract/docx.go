@@ -0,0 +1,386 @@
+package extract
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DOCX extracts the text of a Word document. It keeps paragraphs, headings,
+// numbered and bulleted lists, tables, hyperlinks, footnotes and endnotes;
+// images, field codes and tracked deletions are left out.
+func DOCX(data []byte, format Format) (string, error) {
+	pkg, err := openPackage(data)
+	if err != nil {
+		return "", err
+	}
+	doc, err := pkg.readXML("word/document.xml")
+	if err != nil {
+		return "", fmt.Errorf("not a Word document: %w", err)
+	}
+	d := &docxReader{format: format, counters: map[string][]int{}, referenced: map[*node]bool{}}
+	if d.rels, err = pkg.relationships("word/document.xml"); err != nil {
+		return "", err
+	}
+	if d.headings, err = readHeadingStyles(pkg); err != nil {
+		return "", err
+	}
+	if d.numFormats, err = readNumbering(pkg); err != nil {
+		return "", err
+	}
+	if d.footnotes, err = readNotes(pkg, "word/footnotes.xml", "footnote"); err != nil {
+		return "", err
+	}
+	if d.endnotes, err = readNotes(pkg, "word/endnotes.xml", "endnote"); err != nil {
+		return "", err
+	}
+
+	if body := doc.child("body"); body != nil {
+		d.blocks(body)
+	}
+	for _, note := range d.notes {
+		d.block(note, "")
+	}
+	return finish(d.out.String()), nil
+}
+
+type docxReader struct {
+	format     Format
+	rels       map[string]relationship
+	headings   map[string]int      // Heading level by paragraph style ID
+	numFormats map[string][]string // Number format of each level by numbering ID
+	footnotes  map[string]*node    // Footnote bodies by ID
+	endnotes   map[string]*node    // Endnote bodies by ID
+	counters   map[string][]int    // Next list item number of each level by numbering ID
+	notes      []string            // Rendered notes, in reference order
+	referenced map[*node]bool      // Notes already referenced, which are rendered only once
+	out        strings.Builder
+	list       string // Numbering ID of the last block, if it was a list item
+}
+
+// block writes one block. Items of the same list, given by its numbering ID,
+// follow each other directly; other blocks are separated by a blank line.
+func (d *docxReader) block(s, list string) {
+	if d.out.Len() > 0 {
+		if list != "" && list == d.list {
+			d.out.WriteString("\n")
+		} else {
+			d.out.WriteString("\n\n")
+		}
+	}
+	d.out.WriteString(s)
+	d.list = list
+}
+
+// blocks writes the paragraphs and tables in n.
+func (d *docxReader) blocks(n *node) {
+	for i := range n.Nodes {
+		c := &n.Nodes[i]
+		switch c.XMLName.Local {
+		case "p":
+			d.paragraph(c)
+		case "tbl":
+			d.table(c)
+		case "sectPr", "del", "moveFrom":
+		default:
+			// Content controls, custom XML and tracked insertions wrap ordinary content.
+			d.blocks(c)
+		}
+	}
+}
+
+func (d *docxReader) paragraph(p *node) {
+	text := strings.TrimRight(d.inline(p), " \t\n")
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	pPr := p.child("pPr")
+	if level := d.headingLevel(pPr); level > 0 {
+		if d.format == Markdown {
+			text = strings.Repeat("#", level) + " " + text
+		}
+		d.block(text, "")
+		return
+	}
+	if numID, marker, depth, ok := d.listMarker(pPr); ok {
+		d.block(strings.Repeat("  ", depth)+marker+text, numID)
+		return
+	}
+	d.block(text, "")
+}
+
+// headingLevel returns the heading level of a paragraph, or 0.
+func (d *docxReader) headingLevel(pPr *node) int {
+	if pPr == nil {
+		return 0
+	}
+	if lvl := pPr.child("outlineLvl"); lvl != nil {
+		if n, err := strconv.Atoi(lvl.attr("val")); err == nil && n < 9 {
+			return n + 1
+		}
+	}
+	if style := pPr.child("pStyle"); style != nil {
+		return d.headings[style.attr("val")]
+	}
+	return 0
+}
+
+// listMarker returns the numbering ID, list item marker and nesting depth of
+// a paragraph, and numbers the item.
+func (d *docxReader) listMarker(pPr *node) (string, string, int, bool) {
+	numPr := pPr.path("numPr")
+	if numPr == nil {
+		return "", "", 0, false
+	}
+	numID := numPr.path("numId").attrOrEmpty("val")
+	if numID == "" || numID == "0" {
+		return "", "", 0, false
+	}
+	depth, _ := strconv.Atoi(numPr.path("ilvl").attrOrEmpty("val"))
+	depth = max(0, min(depth, 8))
+
+	counts := d.counters[numID]
+	for len(counts) <= depth {
+		counts = append(counts, 0)
+	}
+	counts[depth]++
+	counts = counts[:depth+1]
+	d.counters[numID] = counts
+
+	var format string
+	if formats := d.numFormats[numID]; depth < len(formats) {
+		format = formats[depth]
+	}
+	switch format {
+	case "bullet", "":
+		return numID, "- ", depth, true
+	case "none":
+		return numID, "", depth, true
+	default:
+		return numID, strconv.Itoa(counts[depth]) + ". ", depth, true
+	}
+}
+
+// inline returns the text of the runs in n.
+func (d *docxReader) inline(n *node) string {
+	var b strings.Builder
+	for i := range n.Nodes {
+		c := &n.Nodes[i]
+		switch c.XMLName.Local {
+		case "t":
+			b.WriteString(c.Text)
+		case "tab":
+			b.WriteString("\t")
+		case "br", "cr":
+			b.WriteString("\n")
+		case "noBreakHyphen":
+			b.WriteString("-")
+		case "hyperlink":
+			b.WriteString(d.hyperlink(c))
+		case "footnoteReference":
+			b.WriteString(d.noteReference(d.footnotes, c.attr("id"), ""))
+		case "endnoteReference":
+			b.WriteString(d.noteReference(d.endnotes, c.attr("id"), "e"))
+		case "p":
+			// Paragraphs inside text boxes run on after the surrounding text.
+			if s := strings.TrimSpace(d.inline(c)); s != "" {
+				b.WriteString(" " + s + " ")
+			}
+		case "pPr", "rPr", "del", "moveFrom", "Fallback", "instrText", "delText":
+		default:
+			b.WriteString(d.inline(c))
+		}
+	}
+	return b.String()
+}
+
+func (d *docxReader) hyperlink(h *node) string {
+	text := d.inline(h)
+	rel, ok := d.rels[h.relID()]
+	if !ok || !rel.External || strings.TrimSpace(text) == "" {
+		return text
+	}
+	if d.format == Markdown {
+		return "[" + text + "](" + rel.Target + ")"
+	}
+	if text == rel.Target {
+		return text
+	}
+	return text + " (" + rel.Target + ")"
+}
+
+// noteReference renders a footnote or endnote reference and queues the
+// note's text. Notes are numbered in the order they are referenced. A note
+// is rendered once: later references to it, including from its own text or
+// another note's, are left out.
+func (d *docxReader) noteReference(notes map[string]*node, id, prefix string) string {
+	body, ok := notes[id]
+	if !ok || d.referenced[body] {
+		return ""
+	}
+	d.referenced[body] = true
+	i := len(d.notes)
+	label := prefix + strconv.Itoa(i+1)
+	d.notes = append(d.notes, "") // Notes referenced from this one follow it
+	var parts []string
+	for _, p := range body.all("p") {
+		if s := strings.TrimSpace(d.inline(p)); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	text := strings.Join(parts, " ")
+	if d.format == Markdown {
+		d.notes[i] = "[^" + label + "]: " + text
+		return "[^" + label + "]"
+	}
+	d.notes[i] = "[" + label + "] " + text
+	return "[" + label + "]"
+}
+
+func (d *docxReader) table(tbl *node) {
+	var rows [][]string
+	width := 0
+	for _, tr := range tbl.all("tr") {
+		var cells []string
+		for _, tc := range tr.all("tc") {
+			var parts []string
+			for _, p := range tc.all("p") {
+				if s := strings.TrimSpace(d.inline(p)); s != "" {
+					parts = append(parts, s)
+				}
+			}
+			cells = append(cells, strings.Join(parts, " "))
+		}
+		rows = append(rows, cells)
+		width = max(width, len(cells))
+	}
+	if len(rows) == 0 || width == 0 {
+		return
+	}
+	d.block(renderTable(rows, width, d.format), "")
+}
+
+// renderTable renders rows as a Markdown table with the first row as the
+// header, or as tab-separated lines.
+func renderTable(rows [][]string, width int, format Format) string {
+	var b strings.Builder
+	for i, row := range rows {
+		cells := make([]string, width)
+		copy(cells, row)
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		if format != Markdown {
+			for j := range cells {
+				cells[j] = strings.Join(strings.Fields(cells[j]), " ")
+			}
+			b.WriteString(strings.TrimRight(strings.Join(cells, "\t"), "\t"))
+			continue
+		}
+		for j := range cells {
+			cells[j] = markdownCell(cells[j])
+		}
+		b.WriteString("| " + strings.Join(cells, " | ") + " |")
+		if i == 0 {
+			b.WriteString("\n|" + strings.Repeat(" --- |", width))
+		}
+	}
+	return b.String()
+}
+
+// markdownCell makes s safe inside a Markdown table cell.
+func markdownCell(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+var headingStyleName = regexp.MustCompile(`^heading\s*([1-9])$`)
+
+// readHeadingStyles returns the heading level of each paragraph style,
+// following basedOn links so custom styles derived from headings count.
+func readHeadingStyles(pkg *ooxmlPackage) (map[string]int, error) {
+	styles, err := pkg.readOptionalXML("word/styles.xml")
+	if err != nil || styles == nil {
+		return nil, err
+	}
+	direct := map[string]int{}
+	basedOn := map[string]string{}
+	for _, s := range styles.all("style") {
+		if s.attr("type") != "paragraph" {
+			continue
+		}
+		id := s.attr("styleId")
+		name := strings.ToLower(s.path("name").attrOrEmpty("val"))
+		switch {
+		case name == "title":
+			direct[id] = 1
+		case headingStyleName.MatchString(name):
+			direct[id], _ = strconv.Atoi(headingStyleName.FindStringSubmatch(name)[1])
+		default:
+			if lvl, err := strconv.Atoi(s.path("pPr", "outlineLvl").attrOrEmpty("val")); err == nil && lvl < 9 {
+				direct[id] = lvl + 1
+			}
+		}
+		if base := s.path("basedOn").attrOrEmpty("val"); base != "" {
+			basedOn[id] = base
+		}
+	}
+	levels := map[string]int{}
+	for id := range basedOn {
+		for cur, hops := id, 0; cur != "" && hops < 16; cur, hops = basedOn[cur], hops+1 {
+			if lvl, ok := direct[cur]; ok {
+				levels[id] = lvl
+				break
+			}
+		}
+	}
+	for id, lvl := range direct {
+		levels[id] = lvl
+	}
+	return levels, nil
+}
+
+// readNumbering returns the number format of each list level by numbering ID.
+func readNumbering(pkg *ooxmlPackage) (map[string][]string, error) {
+	numbering, err := pkg.readOptionalXML("word/numbering.xml")
+	if err != nil || numbering == nil {
+		return nil, err
+	}
+	abstract := map[string][]string{}
+	for _, a := range numbering.all("abstractNum") {
+		var formats []string
+		for _, lvl := range a.all("lvl") {
+			i, err := strconv.Atoi(lvl.attr("ilvl"))
+			if err != nil || i < 0 || i > 8 {
+				continue
+			}
+			for len(formats) <= i {
+				formats = append(formats, "")
+			}
+			formats[i] = lvl.path("numFmt").attrOrEmpty("val")
+		}
+		abstract[a.attr("abstractNumId")] = formats
+	}
+	formats := map[string][]string{}
+	for _, num := range numbering.all("num") {
+		formats[num.attr("numId")] = abstract[num.path("abstractNumId").attrOrEmpty("val")]
+	}
+	return formats, nil
+}
+
+// readNotes returns the footnotes or endnotes of a document by ID, leaving out
+// the separators Word stores alongside them.
+func readNotes(pkg *ooxmlPackage, part, element string) (map[string]*node, error) {
+	n, err := pkg.readOptionalXML(part)
+	if err != nil || n == nil {
+		return nil, err
+	}
+	notes := map[string]*node{}
+	for _, note := range n.all(element) {
+		if t := note.attr("type"); t == "" || t == "normal" {
+			notes[note.attr("id")] = note
+		}
+	}
+	return notes, nil
+}
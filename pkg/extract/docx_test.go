@@ -0,0 +1,175 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+)
+
+// buildPackage zips parts into an OOXML package.
+func buildPackage(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+const wordNS = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+
+var testDOCX = map[string]string{
+	"word/document.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<w:document ` + wordNS + `><w:body>
+<w:p><w:pPr><w:pStyle w:val="Titel"/></w:pPr><w:r><w:t>Quarterly Report</w:t></w:r></w:p>
+<w:p><w:pPr><w:pStyle w:val="MyHeading"/></w:pPr><w:r><w:t>Summary</w:t></w:r></w:p>
+<w:p><w:r><w:t xml:space="preserve">Revenue grew </w:t></w:r><w:r><w:rPr><w:b/></w:rPr><w:t>12%</w:t></w:r><w:r><w:footnoteReference w:id="2"/></w:r><w:r><w:t>. See </w:t></w:r><w:hyperlink r:id="rId9"><w:r><w:t>the dashboard</w:t></w:r></w:hyperlink><w:r><w:t>.</w:t></w:r></w:p>
+<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>First</w:t></w:r></w:p>
+<w:p><w:pPr><w:numPr><w:ilvl w:val="1"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>Nested</w:t></w:r></w:p>
+<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>Second</w:t></w:r></w:p>
+<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/><w:numId w:val="2"/></w:numPr></w:pPr><w:r><w:t>Bullet</w:t></w:r></w:p>
+<w:tbl><w:tr><w:tc><w:p><w:r><w:t>Region</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Sales</w:t></w:r></w:p></w:tc></w:tr>
+<w:tr><w:tc><w:p><w:r><w:t>North | East</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>10</w:t></w:r></w:p><w:p><w:r><w:t>units</w:t></w:r></w:p></w:tc></w:tr></w:tbl>
+<w:p><w:r><w:t xml:space="preserve">Kept </w:t></w:r><w:del><w:r><w:delText>removed </w:delText></w:r></w:del><w:ins><w:r><w:t>added</w:t></w:r></w:ins></w:p>
+<w:p/>
+<w:sectPr/>
+</w:body></w:document>`,
+	"word/_rels/document.xml.rels": `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId9" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="https://example.com/dash" TargetMode="External"/>
+</Relationships>`,
+	"word/styles.xml": `<w:styles ` + wordNS + `>
+<w:style w:type="paragraph" w:styleId="Titel"><w:name w:val="Title"/></w:style>
+<w:style w:type="paragraph" w:styleId="berschrift1"><w:name w:val="heading 1"/></w:style>
+<w:style w:type="paragraph" w:styleId="MyHeading"><w:name w:val="My Heading"/><w:basedOn w:val="berschrift1"/></w:style>
+</w:styles>`,
+	"word/numbering.xml": `<w:numbering ` + wordNS + `>
+<w:abstractNum w:abstractNumId="0"><w:lvl w:ilvl="0"><w:numFmt w:val="decimal"/></w:lvl><w:lvl w:ilvl="1"><w:numFmt w:val="bullet"/></w:lvl></w:abstractNum>
+<w:abstractNum w:abstractNumId="1"><w:lvl w:ilvl="0"><w:numFmt w:val="bullet"/></w:lvl></w:abstractNum>
+<w:num w:numId="1"><w:abstractNumId w:val="0"/></w:num>
+<w:num w:numId="2"><w:abstractNumId w:val="1"/></w:num>
+</w:numbering>`,
+	"word/footnotes.xml": `<w:footnotes ` + wordNS + `>
+<w:footnote w:type="separator" w:id="-1"><w:p><w:r><w:separator/></w:r></w:p></w:footnote>
+<w:footnote w:id="2"><w:p><w:r><w:footnoteRef/></w:r><w:r><w:t xml:space="preserve"> Year over year.</w:t></w:r></w:p></w:footnote>
+</w:footnotes>`,
+}
+
+func TestDOCX(t *testing.T) {
+	data := buildPackage(t, testDOCX)
+	tests := []struct {
+		format Format
+		want   string
+	}{
+		{Markdown, `# Quarterly Report
+
+# Summary
+
+Revenue grew 12%[^1]. See [the dashboard](https://example.com/dash).
+
+1. First
+  - Nested
+2. Second
+
+- Bullet
+
+| Region | Sales |
+| --- | --- |
+| North \| East | 10 units |
+
+Kept added
+
+[^1]: Year over year.
+`},
+		{Text, `Quarterly Report
+
+Summary
+
+Revenue grew 12%[1]. See the dashboard (https://example.com/dash).
+
+1. First
+  - Nested
+2. Second
+
+- Bullet
+
+Region	Sales
+North | East	10 units
+
+Kept added
+
+[1] Year over year.
+`},
+	}
+	for _, tt := range tests {
+		got, err := DOCX(data, tt.format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%s:\n%s\nwant:\n%s", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestDOCXRejectsOtherFiles(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"plain text":    []byte("just text"),
+		"zip, not docx": buildPackage(t, map[string]string{"xl/workbook.xml": "<workbook/>"}),
+	} {
+		if _, err := DOCX(data, Text); err == nil {
+			t.Errorf("%s was accepted", name)
+		}
+	}
+}
+
+func TestDOCXNotesReferencingNotes(t *testing.T) {
+	data := buildPackage(t, map[string]string{
+		"word/document.xml": `<w:document ` + wordNS + `><w:body>
+<w:p><w:r><w:t>Claim</w:t></w:r><w:r><w:footnoteReference w:id="1"/></w:r><w:r><w:t> and more</w:t></w:r><w:r><w:endnoteReference w:id="1"/></w:r></w:p>
+</w:body></w:document>`,
+		"word/footnotes.xml": `<w:footnotes ` + wordNS + `>
+<w:footnote w:id="1"><w:p><w:r><w:t>Itself</w:t></w:r><w:r><w:footnoteReference w:id="1"/></w:r><w:r><w:t>, see</w:t></w:r><w:r><w:footnoteReference w:id="2"/></w:r></w:p></w:footnote>
+<w:footnote w:id="2"><w:p><w:r><w:t>Back</w:t></w:r><w:r><w:footnoteReference w:id="1"/></w:r></w:p></w:footnote>
+</w:footnotes>`,
+		"word/endnotes.xml": `<w:endnotes ` + wordNS + `>
+<w:endnote w:id="1"><w:p><w:r><w:t>End</w:t></w:r><w:r><w:endnoteReference w:id="1"/></w:r></w:p></w:endnote>
+</w:endnotes>`,
+	})
+	got, err := DOCX(data, Text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Claim[1] and more[e3]\n\n[1] Itself, see[2]\n\n[2] Back\n\n[e3] End\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDOCXHyperlinkNeedsRelationshipID(t *testing.T) {
+	data := buildPackage(t, map[string]string{
+		"word/document.xml": `<w:document ` + wordNS + `><w:body>
+<w:p><w:hyperlink w:id="rId1"><w:r><w:t>plain id</w:t></w:r></w:hyperlink></w:p>
+</w:body></w:document>`,
+		"word/_rels/document.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="https://example.com/" TargetMode="External"/>
+</Relationships>`,
+	})
+	got, err := DOCX(data, Markdown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "plain id\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
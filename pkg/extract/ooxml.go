@@ -0,0 +1,244 @@
+// Package extract converts office documents and PDFs to plain text or
+// Markdown that LLM clients can read.
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// Format selects what an extractor produces.
+type Format string
+
+const (
+	// Text is plain text.
+	Text Format = "text"
+	// Markdown keeps headings, lists, tables and links as Markdown.
+	Markdown Format = "markdown"
+)
+
+// ParseFormat returns the format named s. The empty string selects Text.
+func ParseFormat(s string) (Format, error) {
+	switch Format(strings.ToLower(s)) {
+	case "", Text:
+		return Text, nil
+	case Markdown:
+		return Markdown, nil
+	default:
+		return "", fmt.Errorf("unknown format '%s': use %s or %s", s, Text, Markdown)
+	}
+}
+
+// finish trims extracted text and ends it with a newline, unless it is empty.
+func finish(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return ""
+	}
+	return s + "\n"
+}
+
+// ErrNotPackage is returned for data that is not an Office Open XML package.
+var ErrNotPackage = errors.New("not an Office Open XML package")
+
+// maxPartSize bounds the uncompressed size of a package part, so a small
+// crafted file cannot expand into gigabytes.
+const maxPartSize = 64 << 20
+
+// ooxmlPackage is an Office Open XML package: a ZIP archive of XML parts.
+type ooxmlPackage struct {
+	files map[string]*zip.File
+}
+
+func openPackage(data []byte) (*ooxmlPackage, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotPackage, err)
+	}
+	p := &ooxmlPackage{files: make(map[string]*zip.File, len(zr.File))}
+	for _, f := range zr.File {
+		p.files[strings.TrimPrefix(f.Name, "/")] = f
+	}
+	return p, nil
+}
+
+// open returns a reader of a part that fails once it has read more than
+// maxPartSize bytes. A missing part is fs.ErrNotExist.
+func (p *ooxmlPackage) open(name string) (io.ReadCloser, error) {
+	f, ok := p.files[name]
+	if !ok {
+		return nil, fmt.Errorf("part '%s': %w", name, fs.ErrNotExist)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("unable to open part '%s': %w", name, err)
+	}
+	return &partReader{ReadCloser: rc, name: name, left: maxPartSize + 1}, nil
+}
+
+// partReader reads a part, failing past maxPartSize bytes.
+type partReader struct {
+	io.ReadCloser
+	name string
+	left int64
+}
+
+func (r *partReader) Read(b []byte) (int, error) {
+	if int64(len(b)) > r.left {
+		b = b[:r.left]
+	}
+	n, err := r.ReadCloser.Read(b)
+	if r.left -= int64(n); r.left == 0 {
+		return n, fmt.Errorf("part '%s' is larger than %d bytes", r.name, maxPartSize)
+	}
+	if err != nil && err != io.EOF {
+		return n, fmt.Errorf("unable to read part '%s': %w", r.name, err)
+	}
+	return n, err
+}
+
+// read returns the content of a part. A missing part is fs.ErrNotExist.
+func (p *ooxmlPackage) read(name string) ([]byte, error) {
+	rc, err := p.open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// readXML parses a part into a node tree. A missing part is fs.ErrNotExist.
+func (p *ooxmlPackage) readXML(name string) (*node, error) {
+	data, err := p.read(name)
+	if err != nil {
+		return nil, err
+	}
+	var n node
+	if err := xml.Unmarshal(data, &n); err != nil {
+		return nil, fmt.Errorf("unable to parse part '%s': %w", name, err)
+	}
+	return &n, nil
+}
+
+// readOptionalXML is readXML for parts a document may leave out; a missing
+// part is returned as nil.
+func (p *ooxmlPackage) readOptionalXML(name string) (*node, error) {
+	n, err := p.readXML(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return n, err
+}
+
+// relationship is one entry of a part's relationships.
+type relationship struct {
+	Type     string
+	Target   string // Resolved to a part name unless External
+	External bool
+}
+
+// relationships returns the relationships of a part by ID.
+func (p *ooxmlPackage) relationships(part string) (map[string]relationship, error) {
+	dir, file := path.Split(part)
+	n, err := p.readOptionalXML(dir + "_rels/" + file + ".rels")
+	if err != nil || n == nil {
+		return nil, err
+	}
+	rels := map[string]relationship{}
+	for _, r := range n.all("Relationship") {
+		rel := relationship{Type: r.attr("Type"), Target: r.attr("Target"), External: r.attr("TargetMode") == "External"}
+		if !rel.External {
+			if strings.HasPrefix(rel.Target, "/") {
+				rel.Target = strings.TrimPrefix(rel.Target, "/")
+			} else {
+				rel.Target = path.Join(dir, rel.Target)
+			}
+		}
+		rels[r.attr("Id")] = rel
+	}
+	return rels, nil
+}
+
+// node is a generic XML element. Elements are matched by local name only, as
+// the OOXML namespaces never reuse a local name within one part type.
+type node struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Nodes   []node     `xml:",any"`
+	Text    string     `xml:",chardata"`
+}
+
+// attr returns the value of the attribute with the given local name.
+func (n *node) attr(local string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// relID returns the r:id attribute of an element, which names one of the
+// part's relationships. Unlike attr it is not confused by a plain id.
+func (n *node) relID() string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == "id" && strings.HasSuffix(a.Name.Space, "/relationships") {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// attrOrEmpty is attr on a node that may be nil.
+func (n *node) attrOrEmpty(local string) string {
+	if n == nil {
+		return ""
+	}
+	return n.attr(local)
+}
+
+// child returns the first child element with the given local name.
+func (n *node) child(local string) *node {
+	for i := range n.Nodes {
+		if n.Nodes[i].XMLName.Local == local {
+			return &n.Nodes[i]
+		}
+	}
+	return nil
+}
+
+// path follows a chain of first children, returning nil if one is missing.
+func (n *node) path(locals ...string) *node {
+	for _, local := range locals {
+		if n == nil {
+			return nil
+		}
+		n = n.child(local)
+	}
+	return n
+}
+
+// all returns every descendant with the given local name, in document order,
+// without descending into matches. A nil node has none.
+func (n *node) all(local string) []*node {
+	if n == nil {
+		return nil
+	}
+	var out []*node
+	for i := range n.Nodes {
+		c := &n.Nodes[i]
+		if c.XMLName.Local == local {
+			out = append(out, c)
+		} else {
+			out = append(out, c.all(local)...)
+		}
+	}
+	return out
+}
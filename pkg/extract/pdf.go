@@ -0,0 +1,83 @@
+package extract
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PDFOptions selects what PDF extracts.
+type PDFOptions struct {
+	Pages Ranges // The pages to read; all of them if empty
+}
+
+// PDFResult is the text of a PDF.
+type PDFResult struct {
+	// Text has each selected page under a "--- Page N ---" marker. The
+	// marker of a page without a text layer says it is image-only.
+	Text           string
+	PageCount      int
+	ImageOnlyPages []int // Selected pages that draw images but have no text layer
+}
+
+// PDF extracts the text of the pages of a PDF in reading order. It reads
+// plain, compressed and cross-reference-stream files, files encrypted with an
+// empty user password and files with damaged cross-reference data. Pages
+// whose text is only in images, such as scans, are listed in ImageOnlyPages:
+// reading them needs OCR.
+func PDF(data []byte, opts PDFOptions) (result *PDFResult, err error) {
+	defer func() {
+		// The parser is lenient with damaged files; one it still trips over
+		// must fail the request, not the server.
+		if p := recover(); p != nil {
+			result, err = nil, fmt.Errorf("%w: %v", errPDFSyntax, p)
+		}
+	}()
+
+	f, err := openPDF(data)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := f.pages()
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.Pages.check(len(pages), "page"); err != nil {
+		return nil, err
+	}
+
+	result = &PDFResult{PageCount: len(pages)}
+	var b strings.Builder
+	for i, page := range pages {
+		n := i + 1
+		if !opts.Pages.Contains(n) {
+			continue
+		}
+		content, err := f.contents(page.dict)
+		if err != nil {
+			return nil, err
+		}
+		r := newPageReader(f, page.rotate)
+		r.run(content, page.resources, identity, 0)
+		if r.err != nil {
+			return nil, r.err
+		}
+		text := layout(r.chars)
+
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		switch {
+		case text != "":
+			fmt.Fprintf(&b, "--- Page %d ---\n%s", n, text)
+		case r.images || r.unmapped:
+			// Glyphs without a Unicode mapping cannot be read either, short
+			// of OCR.
+			result.ImageOnlyPages = append(result.ImageOnlyPages, n)
+			fmt.Fprintf(&b, "--- Page %d (image-only: no text layer, needs OCR) ---", n)
+		default:
+			fmt.Fprintf(&b, "--- Page %d (blank) ---", n)
+		}
+	}
+	result.Text = finish(b.String())
+	return result, nil
+}
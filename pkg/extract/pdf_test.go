@@ -0,0 +1,373 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/md5"
+	"crypto/rc4"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// pdfWriter builds PDF files for tests.
+type pdfWriter struct {
+	objs     []string
+	streams  map[int][]byte // Stream data by object number; the dictionary is in objs
+	compress bool
+	encrypt  []byte // RC4 file key; strings in dictionaries are not encrypted
+}
+
+func (w *pdfWriter) add(obj string) int {
+	w.objs = append(w.objs, obj)
+	return len(w.objs)
+}
+
+func (w *pdfWriter) addStream(dict string, data []byte) int {
+	if w.streams == nil {
+		w.streams = map[int][]byte{}
+	}
+	n := w.add(dict)
+	w.streams[n] = data
+	return n
+}
+
+// document adds a catalog and a page for each content stream. Every page
+// can use Helvetica as /F1, the Type0 font of type0Font as /F2 and an image
+// as /Im1.
+func (w *pdfWriter) document(contents ...string) int {
+	helvetica := w.add(`<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>`)
+	toUnicode := w.addStream(`<< >>`, []byte(type0CMap))
+	descendant := w.add(`<< /Type /Font /Subtype /CIDFontType2 /BaseFont /Demo /DW 600 /W [1 [700 500]] >>`)
+	type0 := w.add(fmt.Sprintf(`<< /Type /Font /Subtype /Type0 /BaseFont /Demo /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>`, descendant, toUnicode))
+	image := w.addStream(`<< /Type /XObject /Subtype /Image /Width 1 /Height 1 /ColorSpace /DeviceGray /BitsPerComponent 8 >>`, []byte{0x80})
+	resources := w.add(fmt.Sprintf(`<< /Font << /F1 %d 0 R /F2 %d 0 R >> /XObject << /Im1 %d 0 R >> >>`, helvetica, type0, image))
+
+	pages := len(w.objs) + 1
+	w.add("") // Filled in below, once the pages are known
+	var kids []string
+	for _, c := range contents {
+		content := w.addStream(`<< >>`, []byte(c))
+		kids = append(kids, fmt.Sprintf("%d 0 R", w.add(fmt.Sprintf(`<< /Type /Page /Parent %d 0 R /MediaBox [0 0 612 792] /Resources %d 0 R /Contents %d 0 R >>`, pages, resources, content))))
+	}
+	w.objs[pages-1] = fmt.Sprintf(`<< /Type /Pages /Kids [%s] /Count %d >>`, strings.Join(kids, " "), len(kids))
+	return w.add(fmt.Sprintf(`<< /Type /Catalog /Pages %d 0 R >>`, pages))
+}
+
+const type0CMap = `/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+1 begincodespacerange
+<0000> <FFFF>
+endcodespacerange
+2 beginbfchar
+<0001> <0048>
+<0002> <00E9>
+endbfchar
+1 beginbfrange
+<0003> <0005> <0041>
+endbfrange
+endcmap
+end end`
+
+// object returns the bytes of object n.
+func (w *pdfWriter) object(n int) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%d 0 obj\n", n)
+	data, isStream := w.streams[n]
+	if !isStream {
+		b.WriteString(w.objs[n-1])
+		b.WriteString("\nendobj\n")
+		return b.Bytes()
+	}
+	dict := strings.TrimSuffix(w.objs[n-1], ">>")
+	if w.compress {
+		var z bytes.Buffer
+		zw := zlib.NewWriter(&z)
+		zw.Write(data)
+		zw.Close()
+		data = z.Bytes()
+		dict += "/Filter /FlateDecode "
+	}
+	if w.encrypt != nil {
+		key := md5.Sum(append(append([]byte(nil), w.encrypt...), byte(n), byte(n>>8), byte(n>>16), 0, 0))
+		c, _ := rc4.NewCipher(key[:])
+		c.XORKeyStream(data, bytes.Clone(data))
+	}
+	fmt.Fprintf(&b, "%s/Length %d >>\nstream\n", dict, len(data))
+	b.Write(data)
+	b.WriteString("\nendstream\nendobj\n")
+	return b.Bytes()
+}
+
+// file writes the objects with a classic cross-reference table.
+func (w *pdfWriter) file(root int, trailer string) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(w.objs)+1)
+	for n := 1; n <= len(w.objs); n++ {
+		offsets[n] = b.Len()
+		b.Write(w.object(n))
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(w.objs)+1)
+	for n := 1; n <= len(w.objs); n++ {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offsets[n])
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root %d 0 R %s >>\nstartxref\n%d\n%%%%EOF\n", len(w.objs)+1, root, trailer, xref)
+	return b.Bytes()
+}
+
+// fileWithStreams writes the objects that are not streams into an object
+// stream, with a cross-reference stream.
+func (w *pdfWriter) fileWithStreams(root int) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.5\n")
+	objStm := len(w.objs) + 1
+	xrefNum := objStm + 1
+	type entry struct{ kind, field2, field3 int }
+	entries := make([]entry, xrefNum+1)
+
+	var header, body bytes.Buffer
+	index := 0
+	for n := 1; n <= len(w.objs); n++ {
+		if _, ok := w.streams[n]; ok {
+			entries[n] = entry{1, b.Len(), 0}
+			b.Write(w.object(n))
+			continue
+		}
+		fmt.Fprintf(&header, "%d %d ", n, body.Len())
+		body.WriteString(w.objs[n-1] + "\n")
+		entries[n] = entry{2, objStm, index}
+		index++
+	}
+	w.streams[objStm] = append(header.Bytes(), body.Bytes()...)
+	w.objs = append(w.objs, fmt.Sprintf(`<< /Type /ObjStm /N %d /First %d >>`, index, header.Len()))
+	entries[objStm] = entry{1, b.Len(), 0}
+	b.Write(w.object(objStm))
+
+	entries[xrefNum] = entry{1, b.Len(), 0}
+	var rows []byte
+	for _, e := range entries {
+		rows = append(rows, byte(e.kind), byte(e.field2>>24), byte(e.field2>>16), byte(e.field2>>8), byte(e.field2), byte(e.field3>>8), byte(e.field3))
+	}
+	w.streams[xrefNum] = rows
+	w.objs = append(w.objs, fmt.Sprintf(`<< /Type /XRef /Size %d /W [1 4 2] /Root %d 0 R >>`, xrefNum+1, root))
+	xref := b.Len()
+	b.Write(w.object(xrefNum))
+	fmt.Fprintf(&b, "startxref\n%d\n%%%%EOF\n", xref)
+	return b.Bytes()
+}
+
+const (
+	// Three blocks drawn out of order: the right column, the title and the
+	// left column.
+	columnsPage = `BT /F1 12 Tf 320 700 Td (Right one) Tj 0 -14 Td (Right two) Tj ET
+BT /F1 18 Tf 72 750 Td (Title) Tj ET
+BT /F1 12 Tf 72 700 Td (Left one) Tj 0 -14 Td (Left two) Tj ET`
+	// Kerning must not split words; a wide TJ gap is a space.
+	kerningPage = `BT /F1 12 Tf 72 700 Td [(Hel) -20 (lo) -300 (World)] TJ ET
+BT /F2 12 Tf 72 650 Td <000100020003000400050001> Tj ET`
+	imagePage = `q 612 0 0 792 0 0 cm /Im1 Do Q`
+	blankPage = `q 0 0 1 rg 0 0 10 10 re f Q`
+)
+
+const wantColumns = "Title\n\nLeft one\nLeft two\n\nRight one\nRight two"
+
+func TestPDF(t *testing.T) {
+	w := &pdfWriter{}
+	root := w.document(columnsPage, kerningPage, imagePage, blankPage)
+	data := w.file(root, "")
+
+	r, err := PDF(data, PDFOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "--- Page 1 ---\n" + wantColumns + "\n\n" +
+		"--- Page 2 ---\nHello World\n\nHéABCH\n\n" +
+		"--- Page 3 (image-only: no text layer, needs OCR) ---\n\n" +
+		"--- Page 4 (blank) ---\n"
+	if r.Text != want {
+		t.Errorf("text:\n%s\nwant:\n%s", r.Text, want)
+	}
+	if r.PageCount != 4 || !reflect.DeepEqual(r.ImageOnlyPages, []int{3}) {
+		t.Errorf("page count %d, image-only pages %v", r.PageCount, r.ImageOnlyPages)
+	}
+}
+
+func TestPDFPages(t *testing.T) {
+	w := &pdfWriter{}
+	data := w.file(w.document(columnsPage, kerningPage, imagePage), "")
+
+	pages, err := ParseRanges("2-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := PDF(data, PDFOptions{Pages: pages})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(r.Text, "Page 1") || !strings.HasPrefix(r.Text, "--- Page 2 ---\n") || !strings.Contains(r.Text, "--- Page 3 (image-only") {
+		t.Errorf("text:\n%s", r.Text)
+	}
+
+	pages, _ = ParseRanges("2,5")
+	if _, err := PDF(data, PDFOptions{Pages: pages}); err == nil || !strings.Contains(err.Error(), "page 5 is out of range") {
+		t.Errorf("error for a page past the end: %v", err)
+	}
+}
+
+func TestPDFFileStructures(t *testing.T) {
+	shifted := func() []byte {
+		w := &pdfWriter{}
+		data := w.file(w.document(columnsPage), "")
+		// Offsets in the xref table are now all wrong.
+		return bytes.Replace(data, []byte("%PDF-1.4\n"), []byte("%PDF-1.4\n% junk\n"), 1)
+	}
+	noXref := func() []byte {
+		w := &pdfWriter{}
+		data := w.file(w.document(columnsPage), "")
+		return data[:bytes.Index(data, []byte("xref\n0 "))]
+	}
+	objectStreams := func() []byte {
+		w := &pdfWriter{compress: true}
+		return w.fileWithStreams(w.document(columnsPage))
+	}
+	encrypted := func() []byte {
+		w := &pdfWriter{compress: true}
+		root := w.document(columnsPage)
+		o := bytes.Repeat([]byte{0x42}, 32)
+		id := []byte("0123456789abcdef")
+		w.encrypt, w.objs = rc4FileKey(o, -4, id), append(w.objs, "")
+		u := rc4UserCheck(w.encrypt, id)
+		w.objs[len(w.objs)-1] = fmt.Sprintf(`<< /Filter /Standard /V 2 /R 3 /Length 128 /P -4 /O <%x> /U <%x> >>`, o, append(u, make([]byte, 16)...))
+		return w.file(root, fmt.Sprintf("/Encrypt %d 0 R /ID [<%x> <%x>]", len(w.objs), id, id))
+	}
+
+	for name, build := range map[string]func() []byte{
+		"wrong xref offsets": shifted,
+		"no xref":            noXref,
+		"object streams":     objectStreams,
+		"RC4 encryption":     encrypted,
+	} {
+		t.Run(name, func(t *testing.T) {
+			r, err := PDF(build(), PDFOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := "--- Page 1 ---\n" + wantColumns + "\n"; r.Text != want {
+				t.Errorf("text:\n%s\nwant:\n%s", r.Text, want)
+			}
+		})
+	}
+}
+
+// rc4FileKey computes the key of algorithm 2 for the empty user password.
+func rc4FileKey(o []byte, p int32, id []byte) []byte {
+	in := append(append(append([]byte(nil), pdfPadding...), o...), byte(p), byte(p>>8), byte(p>>16), byte(p>>24))
+	sum := md5.Sum(append(in, id...))
+	key := sum[:]
+	for i := 0; i < 50; i++ {
+		sum = md5.Sum(key)
+		key = sum[:]
+	}
+	return key
+}
+
+// rc4UserCheck computes the first 16 bytes of U with algorithm 5.
+func rc4UserCheck(key, id []byte) []byte {
+	sum := md5.Sum(append(append([]byte(nil), pdfPadding...), id...))
+	out := sum[:]
+	for i := 0; i < 20; i++ {
+		k := make([]byte, len(key))
+		for j := range key {
+			k[j] = key[j] ^ byte(i)
+		}
+		c, _ := rc4.NewCipher(k)
+		c.XORKeyStream(out, bytes.Clone(out))
+	}
+	return out
+}
+
+func TestPDFErrors(t *testing.T) {
+	if _, err := PDF([]byte("hello"), PDFOptions{}); !errors.Is(err, ErrNotPDF) {
+		t.Errorf("plain text: %v", err)
+	}
+	w := &pdfWriter{}
+	root := w.document(columnsPage)
+	enc := w.add(`<< /Filter /Standard /V 2 /R 3 /Length 128 /P -4 /O <00> /U <0102030405060708090a0b0c0d0e0f10> >>`)
+	data := w.file(root, fmt.Sprintf("/Encrypt %d 0 R /ID [<00> <00>]", enc))
+	if _, err := PDF(data, PDFOptions{}); !errors.Is(err, ErrPDFPassword) {
+		t.Errorf("password protected: %v", err)
+	}
+}
+
+func TestParseRanges(t *testing.T) {
+	r, err := ParseRanges(" 1-3, 5,8- ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Ranges{{1, 3}, {5, 5}, {8, 0}}); !reflect.DeepEqual(r, want) {
+		t.Errorf("ranges %v, want %v", r, want)
+	}
+	for n, want := range map[int]bool{1: true, 3: true, 4: false, 5: true, 7: false, 8: true, 100: true} {
+		if r.Contains(n) != want {
+			t.Errorf("Contains(%d) = %v", n, !want)
+		}
+	}
+	for _, bad := range []string{"0", "3-1", "a", "1-b", "-"} {
+		if _, err := ParseRanges(bad); err == nil {
+			t.Errorf("ParseRanges(%q) succeeded", bad)
+		}
+	}
+}
+
+// singlePage adds a catalog and one page with the given resources and
+// contents, which are object syntax.
+func (w *pdfWriter) singlePage(resources, contents string) int {
+	pages := w.add("")
+	page := w.add(fmt.Sprintf(`<< /Type /Page /Parent %d 0 R /MediaBox [0 0 612 792] /Resources %s /Contents %s >>`, pages, resources, contents))
+	w.objs[pages-1] = fmt.Sprintf(`<< /Type /Pages /Kids [%d 0 R] /Count 1 >>`, page)
+	return w.add(fmt.Sprintf(`<< /Type /Catalog /Pages %d 0 R >>`, pages))
+}
+
+func TestPDFRepeatedContents(t *testing.T) {
+	w := &pdfWriter{compress: true}
+	stream := w.addStream(`<< >>`, bytes.Repeat([]byte(" "), 1<<20))
+	refs := strings.Repeat(fmt.Sprintf("%d 0 R ", stream), maxPageContent>>20+1)
+	data := w.file(w.singlePage(`<< >>`, "["+refs+"]"), "")
+	if _, err := PDF(data, PDFOptions{}); !errors.Is(err, errPDFTooLarge) {
+		t.Errorf("one stream listed %d times: got error %v", maxPageContent>>20+1, err)
+	}
+}
+
+func TestPDFForms(t *testing.T) {
+	// A form drawn at two places shows its text at both.
+	w := &pdfWriter{}
+	font := w.add(`<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>`)
+	form := w.addStream(fmt.Sprintf(`<< /Type /XObject /Subtype /Form /BBox [0 0 100 20] /Resources << /Font << /F1 %d 0 R >> >> >>`, font), []byte(`BT /F1 12 Tf 0 0 Td (Logo) Tj ET`))
+	content := w.addStream(`<< >>`, []byte(`q 1 0 0 1 72 700 cm /Fm Do Q q 1 0 0 1 72 600 cm /Fm Do Q`))
+	data := w.file(w.singlePage(fmt.Sprintf(`<< /XObject << /Fm %d 0 R >> >>`, form), fmt.Sprintf("%d 0 R", content)), "")
+	r, err := PDF(data, PDFOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "--- Page 1 ---\nLogo\n\nLogo\n"; r.Text != want {
+		t.Errorf("text:\n%s\nwant:\n%s", r.Text, want)
+	}
+
+	// Six forms, each drawing the next 50 times, would take 50^6 drawings.
+	w = &pdfWriter{}
+	draws := []byte(strings.Repeat("/F Do ", 50))
+	next := w.addStream(`<< /Type /XObject /Subtype /Form /BBox [0 0 1 1] >>`, []byte("0 0 1 1 re f"))
+	for range 6 {
+		next = w.addStream(fmt.Sprintf(`<< /Type /XObject /Subtype /Form /BBox [0 0 1 1] /Resources << /XObject << /F %d 0 R >> >> >>`, next), draws)
+	}
+	content = w.addStream(`<< >>`, draws)
+	data = w.file(w.singlePage(fmt.Sprintf(`<< /XObject << /F %d 0 R >> >>`, next), fmt.Sprintf("%d 0 R", content)), "")
+	if _, err := PDF(data, PDFOptions{}); !errors.Is(err, errPDFTooLarge) {
+		t.Errorf("nested forms: got error %v", err)
+	}
+}
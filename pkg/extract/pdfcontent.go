@@ -0,0 +1,394 @@
+package extract
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// matrix is a PDF transformation matrix [a b c d e f].
+type matrix [6]float64
+
+var identity = matrix{1, 0, 0, 1, 0, 0}
+
+// mul returns m × n: the transformation m followed by n.
+func (m matrix) mul(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+func translate(x, y float64) matrix {
+	return matrix{1, 0, 0, 1, x, y}
+}
+
+// maxFormDepth bounds how deeply form XObjects may draw each other.
+const maxFormDepth = 16
+
+// Limits on the content interpreted for a page and for the whole document,
+// counting every drawing of a form again. Forms drawing each other many
+// times can otherwise make a small file take forever.
+const (
+	maxPageContent     = maxStreamSize
+	maxDocumentContent = 4 * maxStreamSize
+	maxPageOps         = 1 << 20
+	maxDocumentOps     = 16 << 20
+)
+
+// errPDFTooLarge is returned for files whose content exceeds the limits.
+var errPDFTooLarge = errors.New("PDF content is too large to extract")
+
+// contentBudget counts the content bytes and operators interpreted.
+type contentBudget struct {
+	bytes, ops int
+}
+
+// pdfChar is a character placed on the page. Coordinates are in points, with
+// y growing upwards, after the page's rotation.
+type pdfChar struct {
+	x, y    float64 // Origin on the baseline
+	w       float64 // Advance width
+	size    float64 // Font size
+	text    string
+	upright bool // Written left to right along the x axis
+}
+
+type textState struct {
+	font      *pdfFont
+	size      float64
+	charSpace float64
+	wordSpace float64
+	scale     float64
+	leading   float64
+	rise      float64
+}
+
+type graphicsState struct {
+	ctm  matrix
+	text textState
+}
+
+// pageReader runs a page's content streams and collects its characters.
+type pageReader struct {
+	f        *pdfFile
+	rotation matrix
+	fonts    map[pdfRef]*pdfFont
+	forms    map[pdfRef]bool // Forms being drawn, to stop cycles
+	budget   contentBudget   // Content interpreted for the page
+	err      error           // Set when a limit is exceeded, which ends the page
+	chars    []pdfChar
+	seen     map[charKey]bool
+	images   bool // The page draws an image
+	unmapped bool // The page shows glyphs that have no Unicode mapping
+}
+
+// charKey identifies a character drawn twice at the same place, as some
+// writers do to fake bold text.
+type charKey struct {
+	text string
+	x, y int
+}
+
+func newPageReader(f *pdfFile, rotate int) *pageReader {
+	r := &pageReader{f: f, rotation: identity, fonts: map[pdfRef]*pdfFont{}, forms: map[pdfRef]bool{}, seen: map[charKey]bool{}}
+	switch (rotate%360 + 360) % 360 {
+	case 90:
+		r.rotation = matrix{0, -1, 1, 0, 0, 0}
+	case 180:
+		r.rotation = matrix{-1, 0, 0, -1, 0, 0}
+	case 270:
+		r.rotation = matrix{0, 1, -1, 0, 0, 0}
+	}
+	return r
+}
+
+// spend counts content against the page's and the document's limits, and
+// records an error once either is exceeded.
+func (r *pageReader) spend(bytes, ops int) bool {
+	if r.err != nil {
+		return false
+	}
+	doc := &r.f.budget
+	r.budget.bytes += bytes
+	r.budget.ops += ops
+	doc.bytes += bytes
+	doc.ops += ops
+	switch {
+	case r.budget.bytes > maxPageContent:
+		r.err = fmt.Errorf("%w: a page has more than %d bytes of content", errPDFTooLarge, maxPageContent)
+	case r.budget.ops > maxPageOps:
+		r.err = fmt.Errorf("%w: a page has more than %d operators", errPDFTooLarge, maxPageOps)
+	case doc.bytes > maxDocumentContent:
+		r.err = fmt.Errorf("%w: the document has more than %d bytes of content", errPDFTooLarge, maxDocumentContent)
+	case doc.ops > maxDocumentOps:
+		r.err = fmt.Errorf("%w: the document has more than %d operators", errPDFTooLarge, maxDocumentOps)
+	}
+	return r.err == nil
+}
+
+// run interprets a content stream. Errors in the stream end it early, keeping
+// what was read; exceeding a limit ends the page with r.err set.
+func (r *pageReader) run(content []byte, resources pdfDict, ctm matrix, depth int) {
+	if !r.spend(len(content), 0) {
+		return
+	}
+	l := &pdfLexer{data: content}
+	gs := graphicsState{ctm: ctm, text: textState{scale: 1}}
+	var stack []graphicsState
+	var tm, tlm matrix
+	var ops []any
+	for l.pos < len(l.data) && r.err == nil {
+		start := l.pos
+		obj, err := l.object()
+		if err != nil {
+			if l.pos == start {
+				l.pos++
+			}
+			ops = ops[:0]
+			continue
+		}
+		op, ok := obj.(pdfKeyword)
+		if !ok {
+			ops = append(ops, obj)
+			continue
+		}
+		if !r.spend(0, 1) {
+			return
+		}
+		t := &gs.text
+		switch op {
+		case "q":
+			if len(stack) < 256 {
+				stack = append(stack, gs)
+			}
+		case "Q":
+			if len(stack) > 0 {
+				gs = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case "cm":
+			if m, ok := operandMatrix(ops); ok {
+				gs.ctm = m.mul(gs.ctm)
+			}
+		case "BT":
+			tm, tlm = identity, identity
+		case "Tc":
+			t.charSpace = operand(ops, 0)
+		case "Tw":
+			t.wordSpace = operand(ops, 0)
+		case "Tz":
+			t.scale = operand(ops, 0) / 100
+		case "TL":
+			t.leading = operand(ops, 0)
+		case "Ts":
+			t.rise = operand(ops, 0)
+		case "Tf":
+			if len(ops) >= 2 {
+				name, _ := ops[0].(pdfName)
+				t.font = r.font(resources, name)
+				t.size = operand(ops, 1)
+			}
+		case "Td", "TD":
+			if len(ops) >= 2 {
+				if op == "TD" {
+					t.leading = -operand(ops, 1)
+				}
+				tlm = translate(operand(ops, 0), operand(ops, 1)).mul(tlm)
+				tm = tlm
+			}
+		case "Tm":
+			if m, ok := operandMatrix(ops); ok {
+				tm, tlm = m, m
+			}
+		case "T*":
+			tlm = translate(0, -t.leading).mul(tlm)
+			tm = tlm
+		case "Tj", "'", "\"":
+			if op != "Tj" {
+				if op == "\"" && len(ops) == 3 {
+					t.wordSpace, t.charSpace = operand(ops, 0), operand(ops, 1)
+				}
+				tlm = translate(0, -t.leading).mul(tlm)
+				tm = tlm
+			}
+			if len(ops) > 0 {
+				if s, ok := ops[len(ops)-1].(pdfString); ok {
+					tm = r.show(s, t, tm, gs.ctm)
+				}
+			}
+		case "TJ":
+			if len(ops) > 0 {
+				arr, _ := ops[len(ops)-1].(pdfArray)
+				for _, e := range arr {
+					switch e := e.(type) {
+					case pdfString:
+						tm = r.show(e, t, tm, gs.ctm)
+					case int64, float64:
+						tm = translate(-operand([]any{e}, 0)/1000*t.size*t.scale, 0).mul(tm)
+					}
+				}
+			}
+		case "Do":
+			if len(ops) > 0 {
+				name, _ := ops[0].(pdfName)
+				r.xobject(resources, name, gs.ctm, depth)
+			}
+		case "BI":
+			r.images = true
+			skipInlineImage(l)
+		}
+		ops = ops[:0]
+	}
+}
+
+func operand(ops []any, i int) float64 {
+	if i >= len(ops) {
+		return 0
+	}
+	switch n := ops[i].(type) {
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	}
+	return 0
+}
+
+func operandMatrix(ops []any) (matrix, bool) {
+	if len(ops) < 6 {
+		return matrix{}, false
+	}
+	var m matrix
+	for i := range m {
+		m[i] = operand(ops, len(ops)-6+i)
+	}
+	return m, true
+}
+
+// skipInlineImage moves past the data of an inline image, from after BI to
+// after EI.
+func skipInlineImage(l *pdfLexer) {
+	i := bytes.Index(l.data[l.pos:], []byte("ID"))
+	if i < 0 {
+		l.pos = len(l.data)
+		return
+	}
+	l.pos += i + len("ID") + 1
+	for l.pos < len(l.data) {
+		j := bytes.Index(l.data[l.pos:], []byte("EI"))
+		if j < 0 {
+			l.pos = len(l.data)
+			return
+		}
+		l.pos += j + len("EI")
+		before := l.data[l.pos-len("EI")-1]
+		if isPDFSpace(before) && (l.pos == len(l.data) || isPDFSpace(l.data[l.pos])) {
+			return
+		}
+	}
+}
+
+// font returns the font named in the resources, loading each font once.
+func (r *pageReader) font(resources pdfDict, name pdfName) *pdfFont {
+	v := r.f.dict(resources["Font"])[name]
+	ref, isRef := v.(pdfRef)
+	if isRef {
+		if font, ok := r.fonts[ref]; ok {
+			return font
+		}
+	}
+	d := r.f.dict(v)
+	if d == nil {
+		return nil
+	}
+	font := r.f.loadFont(d)
+	if isRef {
+		r.fonts[ref] = font
+	}
+	return font
+}
+
+// show places the glyphs of s and returns the text matrix after them.
+func (r *pageReader) show(s pdfString, t *textState, tm, ctm matrix) matrix {
+	if t.font == nil {
+		return tm
+	}
+	for _, g := range t.font.decode(s) {
+		trm := matrix{t.size * t.scale, 0, 0, t.size, 0, t.rise}.mul(tm).mul(ctm).mul(r.rotation)
+		advance := g.width*t.size + t.charSpace
+		if g.wordSpace {
+			advance += t.wordSpace
+		}
+		advance *= t.scale
+		end := translate(advance, 0).mul(tm).mul(ctm).mul(r.rotation)
+		tm = translate(advance, 0).mul(tm)
+
+		if g.text == "" {
+			r.unmapped = true
+			continue
+		}
+		// The glyph's baseline direction and height on the page.
+		dx, dy := trm[0], trm[1]
+		size := math.Hypot(trm[2], trm[3])
+		c := pdfChar{
+			x:       trm[4],
+			y:       trm[5],
+			w:       end[4] - trm[4],
+			size:    size,
+			text:    g.text,
+			upright: dx > 0 && math.Abs(dy) <= 0.1*dx,
+		}
+		if size <= 0 {
+			continue
+		}
+		key := charKey{c.text, int(math.Round(c.x)), int(math.Round(c.y))}
+		if r.seen[key] {
+			continue
+		}
+		r.seen[key] = true
+		r.chars = append(r.chars, c)
+	}
+	return tm
+}
+
+// xobject draws the image or form XObject named in the resources.
+func (r *pageReader) xobject(resources pdfDict, name pdfName, ctm matrix, depth int) {
+	v := r.f.dict(resources["XObject"])[name]
+	s, ok := r.f.resolve(v).(*pdfStream)
+	if !ok {
+		return
+	}
+	switch r.f.resolve(s.dict["Subtype"]) {
+	case pdfName("Image"):
+		r.images = true
+	case pdfName("Form"):
+		ref, _ := v.(pdfRef)
+		if depth >= maxFormDepth || r.forms[ref] {
+			return
+		}
+		r.forms[ref] = true
+		defer delete(r.forms, ref)
+
+		content, err := r.f.formContent(s)
+		if err != nil {
+			return
+		}
+		m := identity
+		if arr := r.f.array(s.dict["Matrix"]); len(arr) == 6 {
+			for i := range m {
+				m[i], _ = r.f.number(arr[i])
+			}
+		}
+		formResources := r.f.dict(s.dict["Resources"])
+		if formResources == nil {
+			formResources = resources
+		}
+		r.run(content, formResources, m.mul(ctm), depth+1)
+	}
+}
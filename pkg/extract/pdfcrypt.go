@@ -0,0 +1,295 @@
+package extract
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/rc4"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+)
+
+// ErrPDFPassword is returned for encrypted PDFs that cannot be opened
+// without a password.
+var ErrPDFPassword = errors.New("the PDF is protected by a password")
+
+// pdfPadding pads passwords in the standard security handler.
+var pdfPadding = []byte{
+	0x28, 0xbf, 0x4e, 0x5e, 0x4e, 0x75, 0x8a, 0x41, 0x64, 0x00, 0x4e, 0x56, 0xff, 0xfa, 0x01, 0x08,
+	0x2e, 0x2e, 0x00, 0xb6, 0xd0, 0x68, 0x3e, 0x80, 0x2f, 0x0c, 0xa9, 0xfe, 0x64, 0x53, 0x69, 0x7a,
+}
+
+// Crypt methods.
+const (
+	cryptNone  = "None"
+	cryptRC4   = "V2"
+	cryptAESV2 = "AESV2"
+	cryptAESV3 = "AESV3"
+)
+
+// pdfCrypt decrypts files encrypted by the standard security handler. Only
+// the empty user password is tried, which opens files that restrict printing
+// or copying but can be viewed by anyone.
+type pdfCrypt struct {
+	key       []byte
+	stmMethod string
+	strMethod string
+}
+
+func newPDFCrypt(enc, trailer pdfDict) (*pdfCrypt, error) {
+	if enc == nil {
+		return nil, fmt.Errorf("%w: missing encryption dictionary", errPDFSyntax)
+	}
+	if filter, _ := enc["Filter"].(pdfName); filter != "Standard" {
+		return nil, fmt.Errorf("unsupported PDF security handler '%s'", filter)
+	}
+	v, _ := enc["V"].(int64)
+	r, _ := enc["R"].(int64)
+	c := &pdfCrypt{stmMethod: cryptRC4, strMethod: cryptRC4}
+	if v == 4 || v == 5 {
+		filters, _ := enc["CF"].(pdfDict)
+		method := func(key pdfName) string {
+			name, _ := enc[key].(pdfName)
+			if name == "" || name == "Identity" {
+				return cryptNone
+			}
+			cf, _ := filters[name].(pdfDict)
+			cfm, _ := cf["CFM"].(pdfName)
+			switch cfm {
+			case "V2", "AESV2", "AESV3":
+				return string(cfm)
+			case "None":
+				return cryptNone
+			}
+			return "unsupported " + string(cfm)
+		}
+		c.stmMethod, c.strMethod = method("StmF"), method("StrF")
+	}
+	for _, m := range []string{c.stmMethod, c.strMethod} {
+		if m != cryptNone && m != cryptRC4 && m != cryptAESV2 && m != cryptAESV3 {
+			return nil, fmt.Errorf("unsupported PDF encryption method '%s'", m)
+		}
+	}
+
+	o, _ := enc["O"].(pdfString)
+	u, _ := enc["U"].(pdfString)
+	var err error
+	switch {
+	case v == 5 && (r == 5 || r == 6):
+		ue, _ := enc["UE"].(pdfString)
+		c.key, err = userKeyV5([]byte(u), []byte(ue), r)
+	case v >= 1 && v <= 4 && r >= 2 && r <= 4:
+		c.key, err = userKeyV4(enc, trailer, []byte(o), []byte(u), v, r)
+	default:
+		err = fmt.Errorf("unsupported PDF encryption version %d revision %d", v, r)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// userKeyV4 computes the file key for the empty user password with
+// algorithm 2 of the PDF specification, and checks it against U.
+func userKeyV4(enc, trailer pdfDict, o, u []byte, v, r int64) ([]byte, error) {
+	n := 5
+	if length, ok := enc["Length"].(int64); ok && v >= 2 && length >= 40 && length <= 128 {
+		n = int(length / 8)
+	}
+	if v == 4 {
+		n = 16
+	}
+	p, _ := enc["P"].(int64)
+	var id []byte
+	if ids, ok := trailer["ID"].(pdfArray); ok && len(ids) > 0 {
+		s, _ := ids[0].(pdfString)
+		id = []byte(s)
+	}
+	encryptMetadata := true
+	if b, ok := enc["EncryptMetadata"].(bool); ok {
+		encryptMetadata = b
+	}
+
+	h := md5.New()
+	h.Write(pdfPadding)
+	h.Write(o[:min(len(o), 32)])
+	h.Write([]byte{byte(p), byte(p >> 8), byte(p >> 16), byte(p >> 24)})
+	h.Write(id)
+	if r >= 4 && !encryptMetadata {
+		h.Write([]byte{0xff, 0xff, 0xff, 0xff})
+	}
+	key := h.Sum(nil)[:n]
+	if r >= 3 {
+		for i := 0; i < 50; i++ {
+			sum := md5.Sum(key)
+			key = sum[:n]
+		}
+	}
+
+	// Check the key by computing U from it.
+	var check []byte
+	if r == 2 {
+		check = rc4Crypt(key, pdfPadding)
+	} else {
+		sum := md5.Sum(append(append([]byte(nil), pdfPadding...), id...))
+		check = sum[:]
+		for i := 0; i < 20; i++ {
+			k := make([]byte, len(key))
+			for j := range key {
+				k[j] = key[j] ^ byte(i)
+			}
+			check = rc4Crypt(k, check)
+		}
+		u = u[:min(len(u), 16)]
+	}
+	if !bytes.HasPrefix(check, u) || len(u) == 0 {
+		return nil, ErrPDFPassword
+	}
+	return key, nil
+}
+
+// userKeyV5 computes the AES-256 file key for the empty user password.
+func userKeyV5(u, ue []byte, r int64) ([]byte, error) {
+	if len(u) < 48 || len(ue) < 32 {
+		return nil, fmt.Errorf("%w: bad AES-256 encryption dictionary", errPDFSyntax)
+	}
+	validationSalt, keySalt := u[32:40], u[40:48]
+	if !bytes.Equal(hashV5(validationSalt, r), u[:32]) {
+		return nil, ErrPDFPassword
+	}
+	block, err := aes.NewCipher(hashV5(keySalt, r))
+	if err != nil {
+		return nil, err
+	}
+	key := make([]byte, 32)
+	cipher.NewCBCDecrypter(block, make([]byte, aes.BlockSize)).CryptBlocks(key, ue[:32])
+	return key, nil
+}
+
+// hashV5 hashes the empty password with salt: SHA-256 for revision 5, and
+// algorithm 2.B of ISO 32000-2 for revision 6.
+func hashV5(salt []byte, r int64) []byte {
+	sum := sha256.Sum256(salt)
+	k := sum[:]
+	if r == 5 {
+		return k
+	}
+	for i := 0; ; i++ {
+		k1 := bytes.Repeat(k, 64)
+		block, _ := aes.NewCipher(k[:16])
+		e := make([]byte, len(k1))
+		cipher.NewCBCEncrypter(block, k[16:32]).CryptBlocks(e, k1)
+		mod := 0
+		for _, b := range e[:16] {
+			mod += int(b)
+		}
+		switch mod % 3 {
+		case 0:
+			s := sha256.Sum256(e)
+			k = s[:]
+		case 1:
+			s := sha512.Sum384(e)
+			k = s[:]
+		default:
+			s := sha512.Sum512(e)
+			k = s[:]
+		}
+		if i >= 63 && int(e[len(e)-1]) <= i-31 {
+			return k[:32]
+		}
+	}
+}
+
+func rc4Crypt(key, data []byte) []byte {
+	c, err := rc4.NewCipher(key)
+	if err != nil {
+		return nil
+	}
+	out := make([]byte, len(data))
+	c.XORKeyStream(out, data)
+	return out
+}
+
+// objectKey returns the key for one object's strings and streams.
+func (c *pdfCrypt) objectKey(ref pdfRef, method string) []byte {
+	if method == cryptAESV3 {
+		return c.key
+	}
+	b := append([]byte(nil), c.key...)
+	b = append(b, byte(ref.num), byte(ref.num>>8), byte(ref.num>>16), byte(ref.gen), byte(ref.gen>>8))
+	if method == cryptAESV2 {
+		b = append(b, "sAlT"...)
+	}
+	sum := md5.Sum(b)
+	return sum[:min(len(c.key)+5, 16)]
+}
+
+func (c *pdfCrypt) decrypt(ref pdfRef, method string, data []byte) ([]byte, error) {
+	switch method {
+	case cryptNone:
+		return data, nil
+	case cryptRC4:
+		return rc4Crypt(c.objectKey(ref, method), data), nil
+	}
+	// AES: a random IV, then CBC with PKCS#5 padding.
+	if len(data) < 2*aes.BlockSize || len(data)%aes.BlockSize != 0 {
+		if len(data) == 0 {
+			return data, nil
+		}
+		return nil, fmt.Errorf("%w: bad AES data in object %d", errPDFSyntax, ref.num)
+	}
+	block, err := aes.NewCipher(c.objectKey(ref, method))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(data)-aes.BlockSize)
+	cipher.NewCBCDecrypter(block, data[:aes.BlockSize]).CryptBlocks(out, data[aes.BlockSize:])
+	if pad := int(out[len(out)-1]); pad >= 1 && pad <= aes.BlockSize {
+		out = out[:len(out)-pad]
+	}
+	return out, nil
+}
+
+func (c *pdfCrypt) decryptStream(s *pdfStream, data []byte) ([]byte, error) {
+	return c.decrypt(s.ref, c.stmMethod, data)
+}
+
+// decryptValue decrypts the strings in an object read from the file.
+// Streams are decrypted when they are decoded.
+func (c *pdfCrypt) decryptValue(v any, ref pdfRef) any {
+	switch o := v.(type) {
+	case pdfString:
+		b, err := c.decrypt(ref, c.strMethod, []byte(o))
+		if err != nil {
+			return o
+		}
+		return pdfString(b)
+	case pdfArray:
+		out := make(pdfArray, len(o))
+		for i, e := range o {
+			out[i] = c.decryptValue(e, ref)
+		}
+		return out
+	case pdfDict:
+		out := make(pdfDict, len(o))
+		for k, e := range o {
+			out[k] = c.decryptValue(e, ref)
+		}
+		return out
+	case *pdfStream:
+		return &pdfStream{dict: c.decryptValue(o.dict, ref).(pdfDict), raw: o.raw, ref: o.ref}
+	}
+	return v
+}
+
+// decryptObject decrypts the strings of an object read from the file. The
+// encryption dictionary itself is never encrypted.
+func (f *pdfFile) decryptObject(obj any, ref pdfRef) any {
+	if f.crypt == nil || ref.num == f.encryptID {
+		return obj
+	}
+	return f.crypt.decryptValue(obj, ref)
+}
@@ -0,0 +1,187 @@
+package extract
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Simple font encodings, as the Unicode character of each code. Zero means
+// the code is not defined.
+var (
+	standardEncoding [256]rune
+	winAnsiEncoding  [256]rune
+	macRomanEncoding [256]rune
+	latin1Encoding   [256]rune
+)
+
+// glyphNames maps glyph names, as used in encoding Differences, to Unicode.
+var glyphNames = map[string]rune{}
+
+// asciiGlyphNames names the printable ASCII characters from 0x20.
+var asciiGlyphNames = strings.Fields(`space exclam quotedbl numbersign dollar percent ampersand quotesingle
+	parenleft parenright asterisk plus comma hyphen period slash
+	zero one two three four five six seven eight nine colon semicolon less equal greater question
+	at A B C D E F G H I J K L M N O P Q R S T U V W X Y Z bracketleft backslash bracketright asciicircum underscore
+	grave a b c d e f g h i j k l m n o p q r s t u v w x y z braceleft bar braceright asciitilde`)
+
+// latin1GlyphNames names the Latin-1 characters from 0xA0.
+var latin1GlyphNames = strings.Fields(`nbspace exclamdown cent sterling currency yen brokenbar section
+	dieresis copyright ordfeminine guillemotleft logicalnot sfthyphen registered macron
+	degree plusminus twosuperior threesuperior acute mu paragraph periodcentered
+	cedilla onesuperior ordmasculine guillemotright onequarter onehalf threequarters questiondown
+	Agrave Aacute Acircumflex Atilde Adieresis Aring AE Ccedilla Egrave Eacute Ecircumflex Edieresis Igrave Iacute Icircumflex Idieresis
+	Eth Ntilde Ograve Oacute Ocircumflex Otilde Odieresis multiply Oslash Ugrave Uacute Ucircumflex Udieresis Yacute Thorn germandbls
+	agrave aacute acircumflex atilde adieresis aring ae ccedilla egrave eacute ecircumflex edieresis igrave iacute icircumflex idieresis
+	eth ntilde ograve oacute ocircumflex otilde odieresis divide oslash ugrave uacute ucircumflex udieresis yacute thorn ydieresis`)
+
+// otherGlyphNames are the remaining names of the standard Latin character
+// set, plus ligatures and symbols that are common in Differences.
+var otherGlyphNames = map[string]rune{
+	"Euro": '€', "quotesinglbase": '‚', "florin": 'ƒ', "quotedblbase": '„', "ellipsis": '…',
+	"dagger": '†', "daggerdbl": '‡', "circumflex": 'ˆ', "perthousand": '‰', "Scaron": 'Š',
+	"guilsinglleft": '‹', "OE": 'Œ', "Zcaron": 'Ž', "quoteleft": '‘', "quoteright": '’',
+	"quotedblleft": '“', "quotedblright": '”', "bullet": '•', "endash": '–', "emdash": '—',
+	"tilde": '˜', "trademark": '™', "scaron": 'š', "guilsinglright": '›', "oe": 'œ',
+	"zcaron": 'ž', "Ydieresis": 'Ÿ', "fraction": '⁄', "dotlessi": 'ı', "Lslash": 'Ł',
+	"lslash": 'ł', "breve": '˘', "dotaccent": '˙', "ring": '˚', "hungarumlaut": '˝',
+	"ogonek": '˛', "caron": 'ˇ', "minus": '−', "space": ' ', "hyphen": '-',
+	"ff": 'ﬀ', "fi": 'ﬁ', "fl": 'ﬂ', "ffi": 'ﬃ', "ffl": 'ﬄ',
+	"Delta": '∆', "Omega": 'Ω', "pi": 'π', "summation": '∑', "product": '∏',
+	"integral": '∫', "radical": '√', "infinity": '∞', "lessequal": '≤', "greaterequal": '≥',
+	"notequal": '≠', "approxequal": '≈', "partialdiff": '∂', "lozenge": '◊', "dotlessj": 'ȷ',
+	"arrowleft": '←', "arrowright": '→', "arrowup": '↑', "arrowdown": '↓', "checkmark": '✓',
+	"copyrightsans": '©', "registersans": '®', "trademarksans": '™', "nonbreakingspace": ' ',
+}
+
+// standardHigh is StandardEncoding above 0x7F.
+var standardHigh = map[byte]rune{
+	0xa1: '¡', 0xa2: '¢', 0xa3: '£', 0xa4: '⁄', 0xa5: '¥', 0xa6: 'ƒ', 0xa7: '§', 0xa8: '¤',
+	0xa9: '\'', 0xaa: '“', 0xab: '«', 0xac: '‹', 0xad: '›', 0xae: 'ﬁ', 0xaf: 'ﬂ',
+	0xb1: '–', 0xb2: '†', 0xb3: '‡', 0xb4: '·', 0xb6: '¶', 0xb7: '•', 0xb8: '‚', 0xb9: '„',
+	0xba: '”', 0xbb: '»', 0xbc: '…', 0xbd: '‰', 0xbf: '¿',
+	0xc1: '`', 0xc2: '´', 0xc3: 'ˆ', 0xc4: '˜', 0xc5: '¯', 0xc6: '˘', 0xc7: '˙', 0xc8: '¨',
+	0xca: '˚', 0xcb: '¸', 0xcd: '˝', 0xce: '˛', 0xcf: 'ˇ', 0xd0: '—',
+	0xe1: 'Æ', 0xe3: 'ª', 0xe8: 'Ł', 0xe9: 'Ø', 0xea: 'Œ', 0xeb: 'º',
+	0xf1: 'æ', 0xf5: 'ı', 0xf8: 'ł', 0xf9: 'ø', 0xfa: 'œ', 0xfb: 'ß',
+}
+
+// winAnsiHigh is WinAnsiEncoding from 0x80 to 0x9F.
+var winAnsiHigh = []rune("€\x00‚ƒ„…†‡ˆ‰Š‹Œ\x00Ž\x00\x00‘’“”•–—˜™š›œ\x00žŸ")
+
+// macRomanHigh is MacRomanEncoding from 0x80.
+var macRomanHigh = []rune("ÄÅÇÉÑÖÜáàâäãåçéè" + "êëíìîïñóòôöõúùûü" + "†°¢£§•¶ß®©™´¨≠ÆØ" + "∞±≤≥¥µ∂∑∏π∫ªºΩæø" +
+	"¿¡¬√ƒ≈∆«»… ÀÃÕŒœ" + "–—“”‘’÷◊ÿŸ⁄¤‹›ﬁﬂ" + "‡·‚„‰ÂÊÁËÈÍÎÏÌÓÔ" + "ÒÚÛÙıˆ˜¯˘˙˚¸˝˛ˇ")
+
+func init() {
+	for c := 0x20; c < 0x7f; c++ {
+		latin1Encoding[c] = rune(c)
+		glyphNames[asciiGlyphNames[c-0x20]] = rune(c)
+	}
+	for c := 0xa0; c <= 0xff; c++ {
+		latin1Encoding[c] = rune(c)
+		glyphNames[latin1GlyphNames[c-0xa0]] = rune(c)
+	}
+	for name, r := range otherGlyphNames {
+		glyphNames[name] = r
+	}
+
+	winAnsiEncoding = latin1Encoding
+	for i, r := range winAnsiHigh {
+		winAnsiEncoding[0x80+i] = r
+	}
+
+	for c := 0x20; c < 0x7f; c++ {
+		standardEncoding[c] = rune(c)
+		macRomanEncoding[c] = rune(c)
+	}
+	standardEncoding['\''] = '’'
+	standardEncoding['`'] = '‘'
+	for c, r := range standardHigh {
+		standardEncoding[c] = r
+	}
+	for i, r := range macRomanHigh {
+		macRomanEncoding[0x80+i] = r
+	}
+}
+
+// glyphText returns the text of a glyph name: a standard name, uniXXXX,
+// uXXXX[XX], a name with a variant suffix such as "a.sc", or a ligature of
+// names joined by underscores such as "f_f_i".
+func glyphText(name string) string {
+	if r, ok := glyphNames[name]; ok {
+		return string(r)
+	}
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		return glyphText(name[:i])
+	}
+	if strings.Contains(name, "_") {
+		var b strings.Builder
+		for _, part := range strings.Split(name, "_") {
+			s := glyphText(part)
+			if s == "" {
+				return ""
+			}
+			b.WriteString(s)
+		}
+		return b.String()
+	}
+	if hex, ok := strings.CutPrefix(name, "uni"); ok && len(hex) >= 4 && len(hex)%4 == 0 {
+		var units []uint16
+		for i := 0; i < len(hex); i += 4 {
+			v, err := strconv.ParseUint(hex[i:i+4], 16, 16)
+			if err != nil {
+				return ""
+			}
+			units = append(units, uint16(v))
+		}
+		return utf16Text(units)
+	}
+	if hex, ok := strings.CutPrefix(name, "u"); ok && len(hex) >= 4 && len(hex) <= 6 {
+		if v, err := strconv.ParseUint(hex, 16, 32); err == nil && v <= 0x10ffff {
+			return string(rune(v))
+		}
+	}
+	return ""
+}
+
+// Advance widths of the printable ASCII characters in two of the standard
+// fonts, which PDFs may use without giving widths. Other fonts without
+// widths are measured as the closest of these.
+var (
+	helveticaWidths = [95]int{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	}
+	timesWidths = [95]int{
+		250, 333, 408, 500, 500, 833, 778, 180, 333, 333, 500, 564, 250, 333, 250, 278,
+		500, 500, 500, 500, 500, 500, 500, 500, 500, 500, 278, 278, 564, 564, 564, 444,
+		921, 722, 667, 667, 722, 611, 556, 722, 722, 333, 389, 722, 611, 889, 722, 722,
+		556, 722, 667, 556, 611, 722, 722, 944, 722, 722, 611, 333, 278, 333, 469, 500,
+		333, 444, 500, 444, 500, 444, 333, 500, 500, 278, 278, 500, 278, 778, 500, 500,
+		500, 500, 333, 389, 278, 500, 500, 722, 500, 500, 444, 480, 200, 480, 541,
+	}
+)
+
+// standardWidth returns the width of the character r, in thousandths of the
+// font size, in the standard font closest to baseFont.
+func standardWidth(baseFont string, r rune) float64 {
+	name := strings.ToLower(baseFont)
+	switch {
+	case strings.Contains(name, "courier") || strings.Contains(name, "mono"):
+		return 600
+	case strings.Contains(name, "times") || strings.Contains(name, "serif") && !strings.Contains(name, "sans"):
+		if r >= 0x20 && r < 0x7f {
+			return float64(timesWidths[r-0x20])
+		}
+		return 500
+	default:
+		if r >= 0x20 && r < 0x7f {
+			return float64(helveticaWidths[r-0x20])
+		}
+		return 556
+	}
+}
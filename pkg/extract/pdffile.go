@@ -0,0 +1,694 @@
+package extract
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// ErrNotPDF is returned for data that is not a PDF file.
+var ErrNotPDF = errors.New("not a PDF file")
+
+// xrefEntry locates an object: at an offset in the file, or as the index-th
+// object of an object stream.
+type xrefEntry struct {
+	offset   int64
+	inStream bool
+	stream   int
+	index    int
+}
+
+// pdfFile is a parsed PDF file. Objects are read lazily through the cross
+// reference table.
+type pdfFile struct {
+	data      []byte
+	xref      map[int]xrefEntry
+	trailer   pdfDict
+	crypt     *pdfCrypt
+	objects   map[int]any
+	loading   map[int]bool
+	objStms   map[int]*objStm
+	scanned   map[int]int64 // Object offsets found by scanning, when the xref fails
+	didScan   bool
+	encryptID int               // Object number of the Encrypt dictionary, which is never encrypted
+	forms     map[pdfRef][]byte // Decoded form XObjects, which pages may draw many times
+	budget    contentBudget     // Content interpreted across the document
+}
+
+// objStm is a decoded object stream.
+type objStm struct {
+	data    []byte
+	offsets map[int]int // Offset of each object's syntax in data, by index
+	nums    []int
+	first   int
+}
+
+func openPDF(data []byte) (*pdfFile, error) {
+	header := data[:min(len(data), 1024)]
+	if !bytes.Contains(header, []byte("%PDF-")) {
+		return nil, ErrNotPDF
+	}
+	f := &pdfFile{
+		data:    data,
+		xref:    map[int]xrefEntry{},
+		objects: map[int]any{},
+		loading: map[int]bool{},
+		objStms: map[int]*objStm{},
+		forms:   map[pdfRef][]byte{},
+	}
+	if err := f.loadXrefChain(); err != nil || f.trailer["Root"] == nil {
+		// Damaged or missing cross-reference data: rebuild it by scanning.
+		f.xref = map[int]xrefEntry{}
+		f.trailer = pdfDict{}
+		if err := f.reconstruct(); err != nil {
+			return nil, err
+		}
+	}
+	if enc, ok := f.trailer["Encrypt"]; ok {
+		if ref, ok := enc.(pdfRef); ok {
+			f.encryptID = ref.num
+		}
+		encDict, _ := f.resolve(enc).(pdfDict)
+		crypt, err := newPDFCrypt(encDict, f.trailer)
+		if err != nil {
+			return nil, err
+		}
+		f.crypt = crypt
+		// Objects read so far were not decrypted.
+		f.objects = map[int]any{}
+		f.objStms = map[int]*objStm{}
+	}
+	return f, nil
+}
+
+var startxrefPattern = regexp.MustCompile(`startxref\s+(\d+)`)
+
+// loadXrefChain reads the cross-reference section named by the last
+// startxref and the sections it links to with Prev.
+func (f *pdfFile) loadXrefChain() error {
+	tail := f.data[max(0, len(f.data)-4096):]
+	matches := startxrefPattern.FindAllSubmatch(tail, -1)
+	if len(matches) == 0 {
+		return fmt.Errorf("%w: no startxref", errPDFSyntax)
+	}
+	offset, err := strconv.ParseInt(string(matches[len(matches)-1][1]), 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: bad startxref", errPDFSyntax)
+	}
+	seen := map[int64]bool{}
+	for offset > 0 && !seen[offset] && len(seen) < 256 {
+		seen[offset] = true
+		trailer, err := f.loadXref(offset)
+		if err != nil {
+			return err
+		}
+		if f.trailer == nil {
+			f.trailer = trailer
+		} else {
+			for k, v := range trailer {
+				if _, ok := f.trailer[k]; !ok {
+					f.trailer[k] = v
+				}
+			}
+		}
+		if stm, ok := trailer["XRefStm"].(int64); ok && !seen[stm] {
+			seen[stm] = true
+			if _, err := f.loadXref(stm); err != nil {
+				return err
+			}
+		}
+		prev, _ := trailer["Prev"].(int64)
+		offset = prev
+	}
+	return nil
+}
+
+// loadXref reads one cross-reference table or stream and returns its trailer.
+// Entries already known from newer sections are kept.
+func (f *pdfFile) loadXref(offset int64) (pdfDict, error) {
+	if offset < 0 || offset >= int64(len(f.data)) {
+		return nil, fmt.Errorf("%w: xref offset %d out of range", errPDFSyntax, offset)
+	}
+	l := &pdfLexer{data: f.data, pos: int(offset)}
+	l.skipSpace()
+	if hasKeywordAt(f.data, l.pos, "xref") {
+		l.pos += len("xref")
+		return f.loadXrefTable(l)
+	}
+	obj, _, err := f.readIndirect(int(offset))
+	if err != nil {
+		return nil, err
+	}
+	s, ok := obj.(*pdfStream)
+	if !ok || s.dict["Type"] != pdfName("XRef") {
+		return nil, fmt.Errorf("%w: no xref at offset %d", errPDFSyntax, offset)
+	}
+	return s.dict, f.loadXrefStream(s)
+}
+
+func (f *pdfFile) loadXrefTable(l *pdfLexer) (pdfDict, error) {
+	for {
+		tok, err := l.token()
+		if err != nil {
+			return nil, err
+		}
+		if tok == pdfKeyword("trailer") {
+			trailer, err := l.object()
+			if err != nil {
+				return nil, err
+			}
+			d, ok := trailer.(pdfDict)
+			if !ok {
+				return nil, fmt.Errorf("%w: bad trailer", errPDFSyntax)
+			}
+			return d, nil
+		}
+		start, ok1 := tok.(int64)
+		countTok, err := l.token()
+		if err != nil {
+			return nil, err
+		}
+		count, ok2 := countTok.(int64)
+		if !ok1 || !ok2 || count < 0 || count > 1<<24 {
+			return nil, fmt.Errorf("%w: bad xref subsection", errPDFSyntax)
+		}
+		for i := int64(0); i < count; i++ {
+			off, err1 := l.token()
+			_, err2 := l.token()
+			kind, err3 := l.token()
+			if err := errors.Join(err1, err2, err3); err != nil {
+				return nil, err
+			}
+			o, ok := off.(int64)
+			if !ok {
+				return nil, fmt.Errorf("%w: bad xref entry", errPDFSyntax)
+			}
+			num := int(start + i)
+			if _, known := f.xref[num]; !known && kind == pdfKeyword("n") {
+				f.xref[num] = xrefEntry{offset: o}
+			} else if !known && kind == pdfKeyword("f") {
+				f.xref[num] = xrefEntry{offset: -1}
+			}
+		}
+	}
+}
+
+func (f *pdfFile) loadXrefStream(s *pdfStream) error {
+	data, err := f.decodeStream(s)
+	if err != nil {
+		return err
+	}
+	w, _ := s.dict["W"].(pdfArray)
+	if len(w) != 3 {
+		return fmt.Errorf("%w: bad xref stream W", errPDFSyntax)
+	}
+	var widths [3]int
+	rowLen := 0
+	for i, v := range w {
+		n, _ := v.(int64)
+		if n < 0 || n > 8 {
+			return fmt.Errorf("%w: bad xref stream W", errPDFSyntax)
+		}
+		widths[i] = int(n)
+		rowLen += int(n)
+	}
+	if rowLen == 0 {
+		return fmt.Errorf("%w: bad xref stream W", errPDFSyntax)
+	}
+	index, _ := s.dict["Index"].(pdfArray)
+	if len(index) == 0 {
+		size, _ := s.dict["Size"].(int64)
+		index = pdfArray{int64(0), size}
+	}
+	pos := 0
+	for i := 0; i+1 < len(index); i += 2 {
+		start, _ := index[i].(int64)
+		count, _ := index[i+1].(int64)
+		for j := int64(0); j < count; j++ {
+			if pos+rowLen > len(data) {
+				return nil
+			}
+			var fields [3]int64
+			p := pos
+			for k, width := range widths {
+				for b := 0; b < width; b++ {
+					fields[k] = fields[k]<<8 | int64(data[p])
+					p++
+				}
+			}
+			pos += rowLen
+			if widths[0] == 0 {
+				fields[0] = 1
+			}
+			num := int(start + j)
+			if _, known := f.xref[num]; known {
+				continue
+			}
+			switch fields[0] {
+			case 0:
+				f.xref[num] = xrefEntry{offset: -1}
+			case 1:
+				f.xref[num] = xrefEntry{offset: fields[1]}
+			case 2:
+				f.xref[num] = xrefEntry{inStream: true, stream: int(fields[1]), index: int(fields[2])}
+			}
+		}
+	}
+	return nil
+}
+
+var objHeaderPattern = regexp.MustCompile(`(?m)(?:^|[\r\n\s])(\d+)\s+(\d+)\s+obj\b`)
+
+// reconstruct rebuilds the cross-reference table by scanning for object
+// headers, and finds the trailer or the catalog.
+func (f *pdfFile) reconstruct() error {
+	f.scan()
+	for num, off := range f.scanned {
+		f.xref[num] = xrefEntry{offset: off}
+	}
+	// Prefer the last trailer dictionary in the file.
+	if i := bytes.LastIndex(f.data, []byte("trailer")); i >= 0 {
+		l := &pdfLexer{data: f.data, pos: i + len("trailer")}
+		if d, err := l.object(); err == nil {
+			if t, ok := d.(pdfDict); ok && t["Root"] != nil {
+				f.trailer = t
+				return nil
+			}
+		}
+	}
+	// Otherwise look for a catalog, and for xref stream dictionaries that
+	// carry the trailer keys.
+	for num := range f.xref {
+		obj, err := f.getObject(num)
+		if err != nil {
+			continue
+		}
+		switch o := obj.(type) {
+		case pdfDict:
+			if o["Type"] == pdfName("Catalog") {
+				f.trailer["Root"] = pdfRef{num, 0}
+			}
+		case *pdfStream:
+			if o.dict["Type"] == pdfName("XRef") {
+				for _, k := range []pdfName{"Root", "Encrypt", "ID", "Info"} {
+					if v, ok := o.dict[k]; ok {
+						f.trailer[k] = v
+					}
+				}
+			} else if o.dict["Type"] == pdfName("ObjStm") {
+				f.indexObjStm(num)
+			}
+		}
+	}
+	if f.trailer["Root"] == nil {
+		return fmt.Errorf("%w: no document catalog found", errPDFSyntax)
+	}
+	return nil
+}
+
+// scan finds every object header in the file. Later definitions win, as in
+// incremental updates.
+func (f *pdfFile) scan() {
+	if f.didScan {
+		return
+	}
+	f.didScan = true
+	f.scanned = map[int]int64{}
+	for _, m := range objHeaderPattern.FindAllSubmatchIndex(f.data, -1) {
+		num, err := strconv.Atoi(string(f.data[m[2]:m[3]]))
+		if err != nil {
+			continue
+		}
+		f.scanned[num] = int64(m[2])
+	}
+}
+
+// indexObjStm adds the objects of an object stream found while
+// reconstructing the cross-reference table.
+func (f *pdfFile) indexObjStm(num int) {
+	stm, err := f.loadObjStm(num)
+	if err != nil {
+		return
+	}
+	for i, n := range stm.nums {
+		if _, ok := f.xref[n]; !ok {
+			f.xref[n] = xrefEntry{inStream: true, stream: num, index: i}
+		}
+	}
+}
+
+// resolve follows indirect references. Missing or damaged objects resolve to
+// nil, so extraction can carry on with the rest of the file.
+func (f *pdfFile) resolve(v any) any {
+	for i := 0; i < 32; i++ {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		obj, err := f.getObject(ref.num)
+		if err != nil {
+			return nil
+		}
+		v = obj
+	}
+	return nil
+}
+
+func (f *pdfFile) dict(v any) pdfDict {
+	switch d := f.resolve(v).(type) {
+	case pdfDict:
+		return d
+	case *pdfStream:
+		return d.dict
+	}
+	return nil
+}
+
+func (f *pdfFile) array(v any) pdfArray {
+	a, _ := f.resolve(v).(pdfArray)
+	return a
+}
+
+func (f *pdfFile) number(v any) (float64, bool) {
+	switch n := f.resolve(v).(type) {
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func (f *pdfFile) getObject(num int) (any, error) {
+	if obj, ok := f.objects[num]; ok {
+		return obj, nil
+	}
+	if f.loading[num] {
+		return nil, fmt.Errorf("%w: object %d refers to itself", errPDFSyntax, num)
+	}
+	f.loading[num] = true
+	defer delete(f.loading, num)
+
+	obj, err := f.loadObject(num)
+	if err != nil {
+		return nil, err
+	}
+	f.objects[num] = obj
+	return obj, nil
+}
+
+func (f *pdfFile) loadObject(num int) (any, error) {
+	entry, ok := f.xref[num]
+	if ok && entry.inStream {
+		return f.objectFromStream(entry.stream, entry.index, num)
+	}
+	if ok && entry.offset >= 0 {
+		obj, ref, err := f.readIndirect(int(entry.offset))
+		if err == nil && ref.num == num {
+			return f.decryptObject(obj, ref), nil
+		}
+	}
+	// The offset is wrong or missing: fall back to scanning the file.
+	f.scan()
+	off, ok := f.scanned[num]
+	if !ok {
+		return nil, fmt.Errorf("object %d not found", num)
+	}
+	obj, ref, err := f.readIndirect(int(off))
+	if err != nil {
+		return nil, err
+	}
+	return f.decryptObject(obj, ref), nil
+}
+
+// readIndirect parses "num gen obj ... endobj" at offset.
+func (f *pdfFile) readIndirect(offset int) (any, pdfRef, error) {
+	if offset < 0 || offset >= len(f.data) {
+		return nil, pdfRef{}, fmt.Errorf("%w: offset %d out of range", errPDFSyntax, offset)
+	}
+	l := &pdfLexer{data: f.data, pos: offset}
+	numTok, err1 := l.token()
+	genTok, err2 := l.token()
+	objTok, err3 := l.token()
+	if err := errors.Join(err1, err2, err3); err != nil {
+		return nil, pdfRef{}, err
+	}
+	num, ok1 := numTok.(int64)
+	gen, ok2 := genTok.(int64)
+	if !ok1 || !ok2 || objTok != pdfKeyword("obj") {
+		return nil, pdfRef{}, fmt.Errorf("%w: no object at offset %d", errPDFSyntax, offset)
+	}
+	ref := pdfRef{int(num), int(gen)}
+	obj, err := l.object()
+	if err != nil {
+		return nil, pdfRef{}, err
+	}
+	dict, ok := obj.(pdfDict)
+	if !ok {
+		return obj, ref, nil
+	}
+	l.skipSpace()
+	if !hasKeywordAt(f.data, l.pos, "stream") {
+		return obj, ref, nil
+	}
+	start := l.pos + len("stream")
+	if start < len(f.data) && f.data[start] == '\r' {
+		start++
+	}
+	if start < len(f.data) && f.data[start] == '\n' {
+		start++
+	}
+	return &pdfStream{dict: dict, raw: f.streamData(dict, start, ref.num), ref: ref}, ref, nil
+}
+
+// streamData returns the raw data of a stream starting at start, using its
+// Length when that is right and searching for endstream otherwise.
+func (f *pdfFile) streamData(dict pdfDict, start, num int) []byte {
+	length := -1
+	switch v := dict["Length"].(type) {
+	case int64:
+		length = int(v)
+	case pdfRef:
+		if v.num != num {
+			if n, ok := f.number(v); ok {
+				length = int(n)
+			}
+		}
+	}
+	if length >= 0 && start+length <= len(f.data) {
+		rest := f.data[start+length:]
+		rest = bytes.TrimLeft(rest[:min(len(rest), 32)], "\r\n \t\x00")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			return f.data[start : start+length]
+		}
+	}
+	end := bytes.Index(f.data[start:], []byte("endstream"))
+	if end < 0 {
+		return f.data[start:]
+	}
+	data := f.data[start : start+end]
+	data = bytes.TrimSuffix(data, []byte("\n"))
+	data = bytes.TrimSuffix(data, []byte("\r"))
+	return data
+}
+
+func (f *pdfFile) loadObjStm(num int) (*objStm, error) {
+	if stm, ok := f.objStms[num]; ok {
+		return stm, nil
+	}
+	obj, err := f.getObject(num)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := obj.(*pdfStream)
+	if !ok {
+		return nil, fmt.Errorf("%w: object %d is not an object stream", errPDFSyntax, num)
+	}
+	data, err := f.decodeStream(s)
+	if err != nil {
+		return nil, err
+	}
+	n, _ := s.dict["N"].(int64)
+	first, _ := s.dict["First"].(int64)
+	if n < 0 || first < 0 || int(first) > len(data) {
+		return nil, fmt.Errorf("%w: bad object stream %d", errPDFSyntax, num)
+	}
+	stm := &objStm{data: data, offsets: map[int]int{}, first: int(first)}
+	l := &pdfLexer{data: data[:first]}
+	for i := 0; i < int(n); i++ {
+		numTok, err1 := l.token()
+		offTok, err2 := l.token()
+		objNum, ok1 := numTok.(int64)
+		off, ok2 := offTok.(int64)
+		if err1 != nil || err2 != nil || !ok1 || !ok2 {
+			break
+		}
+		stm.nums = append(stm.nums, int(objNum))
+		stm.offsets[i] = int(first + off)
+	}
+	f.objStms[num] = stm
+	return stm, nil
+}
+
+func (f *pdfFile) objectFromStream(stream, index, num int) (any, error) {
+	stm, err := f.loadObjStm(stream)
+	if err != nil {
+		return nil, err
+	}
+	off, ok := stm.offsets[index]
+	if !ok || stm.nums[index] != num {
+		// Some writers get the index wrong; look the object up by number.
+		ok = false
+		for i, n := range stm.nums {
+			if n == num {
+				off, ok = stm.offsets[i], true
+				break
+			}
+		}
+	}
+	if !ok || off >= len(stm.data) {
+		return nil, fmt.Errorf("object %d not found in object stream %d", num, stream)
+	}
+	l := &pdfLexer{data: stm.data, pos: off}
+	// Objects in object streams are not encrypted on their own.
+	return l.object()
+}
+
+// decodeStream decrypts a stream and applies its filters.
+func (f *pdfFile) decodeStream(s *pdfStream) ([]byte, error) {
+	data := s.raw
+	if f.crypt != nil && s.dict["Type"] != pdfName("XRef") && s.ref.num != f.encryptID {
+		var err error
+		if data, err = f.crypt.decryptStream(s, data); err != nil {
+			return nil, err
+		}
+	}
+	var filters, params pdfArray
+	switch v := f.resolve(s.dict["Filter"]).(type) {
+	case pdfName:
+		filters = pdfArray{v}
+	case pdfArray:
+		filters = v
+	}
+	switch v := f.resolve(s.dict["DecodeParms"]).(type) {
+	case pdfDict:
+		params = pdfArray{v}
+	case pdfArray:
+		params = v
+	}
+	for i, filter := range filters {
+		name, _ := f.resolve(filter).(pdfName)
+		var param pdfDict
+		if i < len(params) {
+			param = f.dict(params[i])
+		}
+		var err error
+		if data, err = applyFilter(name, param, data); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// maxPageTreeDepth bounds the page tree, which damaged files can make cyclic.
+const maxPageTreeDepth = 64
+
+// pdfPage is a page and the attributes it inherits.
+type pdfPage struct {
+	dict      pdfDict
+	resources pdfDict
+	rotate    int
+}
+
+// pages returns the document's pages in order.
+func (f *pdfFile) pages() ([]pdfPage, error) {
+	root := f.dict(f.trailer["Root"])
+	if root == nil {
+		return nil, fmt.Errorf("%w: no document catalog", errPDFSyntax)
+	}
+	var pages []pdfPage
+	seen := map[any]bool{}
+	var walk func(node any, resources pdfDict, rotate, depth int)
+	walk = func(node any, resources pdfDict, rotate, depth int) {
+		if ref, ok := node.(pdfRef); ok {
+			if seen[ref] {
+				return
+			}
+			seen[ref] = true
+		}
+		d := f.dict(node)
+		if d == nil || depth > maxPageTreeDepth {
+			return
+		}
+		if r := f.dict(d["Resources"]); r != nil {
+			resources = r
+		}
+		if r, ok := f.number(d["Rotate"]); ok {
+			rotate = int(r)
+		}
+		kids, hasKids := d["Kids"]
+		if d["Type"] == pdfName("Page") || (!hasKids && d["Type"] != pdfName("Pages")) {
+			pages = append(pages, pdfPage{dict: d, resources: resources, rotate: rotate})
+			return
+		}
+		for _, kid := range f.array(kids) {
+			walk(kid, resources, rotate, depth+1)
+		}
+	}
+	walk(root["Pages"], nil, 0, 0)
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("%w: the document has no pages", errPDFSyntax)
+	}
+	return pages, nil
+}
+
+// contents returns the page's content streams, decoded and joined. Each
+// stream is decoded once however often the page lists it, and the result
+// may be at most maxPageContent bytes.
+func (f *pdfFile) contents(page pdfDict) ([]byte, error) {
+	var streams []any
+	switch c := f.resolve(page["Contents"]).(type) {
+	case *pdfStream:
+		streams = []any{c}
+	case pdfArray:
+		streams = c
+	}
+	decoded := map[pdfRef][]byte{}
+	var out []byte
+	for _, s := range streams {
+		stream, ok := f.resolve(s).(*pdfStream)
+		if !ok {
+			continue
+		}
+		data, ok := decoded[stream.ref]
+		if !ok {
+			var err error
+			if data, err = f.decodeStream(stream); err != nil {
+				continue
+			}
+			decoded[stream.ref] = data
+		}
+		if len(out)+len(data)+1 > maxPageContent {
+			return nil, fmt.Errorf("%w: a page has more than %d bytes of content", errPDFTooLarge, maxPageContent)
+		}
+		out = append(out, data...)
+		out = append(out, '\n')
+	}
+	return out, nil
+}
+
+// formContent returns the decoded content of a form XObject, decoding each
+// form once.
+func (f *pdfFile) formContent(s *pdfStream) ([]byte, error) {
+	if data, ok := f.forms[s.ref]; ok {
+		return data, nil
+	}
+	data, err := f.decodeStream(s)
+	if err != nil {
+		return nil, err
+	}
+	f.forms[s.ref] = data
+	return data, nil
+}
@@ -0,0 +1,298 @@
+package extract
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// maxStreamSize bounds the decoded size of a PDF stream, so a small crafted
+// file cannot expand into gigabytes.
+const maxStreamSize = 64 << 20
+
+// errImageFilter is returned for image compression filters, whose data is
+// never text.
+var errImageFilter = errors.New("image filter")
+
+func applyFilter(name pdfName, param pdfDict, data []byte) ([]byte, error) {
+	switch name {
+	case "FlateDecode", "Fl":
+		out, err := inflate(data)
+		if err != nil {
+			return nil, err
+		}
+		return unpredict(param, out)
+	case "LZWDecode", "LZW":
+		early := true
+		if v, ok := param["EarlyChange"].(int64); ok && v == 0 {
+			early = false
+		}
+		out, err := lzwDecode(data, early)
+		if err != nil {
+			return nil, err
+		}
+		return unpredict(param, out)
+	case "ASCIIHexDecode", "AHx":
+		return asciiHexDecode(data), nil
+	case "ASCII85Decode", "A85":
+		return ascii85Decode(data)
+	case "RunLengthDecode", "RL":
+		return runLengthDecode(data)
+	case "Crypt":
+		// Only the Identity crypt filter can appear without a handler.
+		return data, nil
+	case "DCTDecode", "DCT", "JPXDecode", "CCITTFaxDecode", "CCF", "JBIG2Decode":
+		return nil, errImageFilter
+	default:
+		return nil, fmt.Errorf("unsupported PDF filter '%s'", name)
+	}
+}
+
+// inflate decompresses zlib data, keeping what it could read from truncated
+// or damaged streams, which are common in PDFs.
+func inflate(data []byte) ([]byte, error) {
+	var r io.ReadCloser
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		// Some writers leave out the zlib header.
+		r = flate.NewReader(bytes.NewReader(data))
+	} else {
+		r = zr
+	}
+	defer r.Close()
+	out, err := io.ReadAll(io.LimitReader(r, maxStreamSize+1))
+	if len(out) > maxStreamSize {
+		return nil, fmt.Errorf("PDF stream is larger than %d bytes", maxStreamSize)
+	}
+	if err != nil && len(out) == 0 {
+		return nil, fmt.Errorf("unable to inflate PDF stream: %w", err)
+	}
+	return out, nil
+}
+
+// unpredict reverses the TIFF or PNG predictor named in the decode parameters.
+func unpredict(param pdfDict, data []byte) ([]byte, error) {
+	predictor, _ := param["Predictor"].(int64)
+	if predictor <= 1 {
+		return data, nil
+	}
+	colors, bpc, columns := int64(1), int64(8), int64(1)
+	if v, ok := param["Colors"].(int64); ok && v > 0 {
+		colors = v
+	}
+	if v, ok := param["BitsPerComponent"].(int64); ok && v > 0 {
+		bpc = v
+	}
+	if v, ok := param["Columns"].(int64); ok && v > 0 {
+		columns = v
+	}
+	if colors > 32 || bpc > 16 || columns > 1<<20 {
+		return nil, fmt.Errorf("bad PDF predictor parameters")
+	}
+	bpp := int(max(1, (colors*bpc+7)/8))
+	rowLen := int((colors*bpc*columns + 7) / 8)
+
+	if predictor == 2 {
+		if bpc != 8 {
+			return data, nil
+		}
+		out := append([]byte(nil), data...)
+		for row := 0; row+rowLen <= len(out); row += rowLen {
+			for i := bpp; i < rowLen; i++ {
+				out[row+i] += out[row+i-bpp]
+			}
+		}
+		return out, nil
+	}
+
+	// PNG predictors: each row starts with its filter type.
+	var out []byte
+	prev := make([]byte, rowLen)
+	for pos := 0; pos+1+rowLen <= len(data); pos += 1 + rowLen {
+		kind := data[pos]
+		row := append([]byte(nil), data[pos+1:pos+1+rowLen]...)
+		for i := range row {
+			var left, upLeft byte
+			if i >= bpp {
+				left, upLeft = row[i-bpp], prev[i-bpp]
+			}
+			up := prev[i]
+			switch kind {
+			case 1:
+				row[i] += left
+			case 2:
+				row[i] += up
+			case 3:
+				row[i] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[i] += paeth(left, up, upLeft)
+			}
+		}
+		out = append(out, row...)
+		prev = row
+	}
+	return out, nil
+}
+
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	default:
+		return c
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func asciiHexDecode(data []byte) []byte {
+	l := &pdfLexer{data: append(append([]byte(nil), data...), '>')}
+	s, _ := l.hexString()
+	return []byte(s)
+}
+
+func ascii85Decode(data []byte) ([]byte, error) {
+	var out []byte
+	var group [5]byte
+	n := 0
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case isPDFSpace(c):
+			continue
+		case c == '~':
+			i = len(data)
+			continue
+		case c == 'z' && n == 0:
+			out = append(out, 0, 0, 0, 0)
+			continue
+		case c < '!' || c > 'u':
+			return nil, fmt.Errorf("bad ASCII85 data")
+		}
+		group[n] = c - '!'
+		n++
+		if n == 5 {
+			v := uint32(0)
+			for _, g := range group {
+				v = v*85 + uint32(g)
+			}
+			out = append(out, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+			n = 0
+		}
+	}
+	if n > 1 {
+		for i := n; i < 5; i++ {
+			group[i] = 84
+		}
+		v := uint32(0)
+		for _, g := range group {
+			v = v*85 + uint32(g)
+		}
+		b := []byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+		out = append(out, b[:n-1]...)
+	}
+	return out, nil
+}
+
+func runLengthDecode(data []byte) ([]byte, error) {
+	var out []byte
+	for i := 0; i < len(data); {
+		n := int(data[i])
+		i++
+		switch {
+		case n == 128:
+			return out, nil
+		case n < 128:
+			end := min(i+n+1, len(data))
+			out = append(out, data[i:end]...)
+			i = end
+		default:
+			if i >= len(data) {
+				return out, nil
+			}
+			out = append(out, bytes.Repeat(data[i:i+1], 257-n)...)
+			i++
+		}
+		if len(out) > maxStreamSize {
+			return nil, fmt.Errorf("PDF stream is larger than %d bytes", maxStreamSize)
+		}
+	}
+	return out, nil
+}
+
+// lzwDecode decodes PDF LZW data. Unlike compress/lzw it supports the early
+// code width change PDF uses by default.
+func lzwDecode(data []byte, early bool) ([]byte, error) {
+	const clear, eod = 256, 257
+	var out []byte
+	table := make([][]byte, 258, 4096)
+	reset := func() {
+		table = table[:258]
+		for i := 0; i < 256; i++ {
+			table[i] = []byte{byte(i)}
+		}
+	}
+	reset()
+	width := 9
+	var prev []byte
+	var bits uint32
+	nbits := 0
+	for _, b := range data {
+		bits = bits<<8 | uint32(b)
+		nbits += 8
+		for nbits >= width {
+			code := int(bits>>(nbits-width)) & (1<<width - 1)
+			nbits -= width
+			switch {
+			case code == clear:
+				reset()
+				width, prev = 9, nil
+				continue
+			case code == eod:
+				return out, nil
+			}
+			var entry []byte
+			switch {
+			case code < len(table):
+				entry = table[code]
+			case code == len(table) && prev != nil:
+				entry = append(append([]byte(nil), prev...), prev[0])
+			default:
+				return nil, fmt.Errorf("bad LZW code %d", code)
+			}
+			out = append(out, entry...)
+			if len(out) > maxStreamSize {
+				return nil, fmt.Errorf("PDF stream is larger than %d bytes", maxStreamSize)
+			}
+			if prev != nil && len(table) < 4096 {
+				table = append(table, append(append([]byte(nil), prev...), entry[0]))
+			}
+			prev = entry
+			limit := len(table)
+			if early {
+				limit++
+			}
+			switch {
+			case limit >= 2048:
+				width = 12
+			case limit >= 1024:
+				width = 11
+			case limit >= 512:
+				width = 10
+			}
+		}
+	}
+	return out, nil
+}
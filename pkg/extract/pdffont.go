@@ -0,0 +1,457 @@
+package extract
+
+import (
+	"strings"
+	"unicode/utf16"
+)
+
+// pdfGlyph is one character code shown with a font.
+type pdfGlyph struct {
+	text      string  // Empty if the font gives no way to map the code to Unicode
+	width     float64 // Advance width in text space units, for a font size of 1
+	wordSpace bool    // The single-byte code 32, which word spacing applies to
+}
+
+// pdfFont maps the character codes of a font to text and widths.
+type pdfFont struct {
+	composite bool
+	encoding  *pdfCMap // Type0 fonts: maps codes to CIDs; nil for Identity
+	toUnicode *pdfCMap
+	utf16     bool // Type0 fonts whose codes are UTF-16, such as UniJIS-UCS2-H
+	simple    [256]string
+	widths    map[uint32]float64 // By code for simple fonts, by CID for Type0 fonts
+	dflt      float64            // Width of codes missing from widths
+	scale     float64            // Glyph space to text space
+	baseFont  string
+	standard  bool // A simple font without widths, measured with standardWidth
+}
+
+// loadFont reads a font dictionary. It never fails: unknown fonts decode to
+// glyphs without text, which the page reports as needing OCR.
+func (f *pdfFile) loadFont(d pdfDict) *pdfFont {
+	font := &pdfFont{widths: map[uint32]float64{}, scale: 0.001}
+	baseFont, _ := f.resolve(d["BaseFont"]).(pdfName)
+	font.baseFont = string(baseFont)
+	if i := strings.IndexByte(font.baseFont, '+'); i == 6 {
+		font.baseFont = font.baseFont[i+1:]
+	}
+	if s, ok := f.resolve(d["ToUnicode"]).(*pdfStream); ok {
+		if data, err := f.decodeStream(s); err == nil {
+			font.toUnicode = parseCMap(data)
+		}
+	}
+	if f.resolve(d["Subtype"]) == pdfName("Type0") {
+		f.loadCompositeFont(font, d)
+	} else {
+		f.loadSimpleFont(font, d)
+	}
+	return font
+}
+
+func (f *pdfFile) loadSimpleFont(font *pdfFont, d pdfDict) {
+	subtype, _ := f.resolve(d["Subtype"]).(pdfName)
+	descriptor := f.dict(d["FontDescriptor"])
+	if subtype == "Type3" {
+		if m := f.array(d["FontMatrix"]); len(m) == 6 {
+			if a, ok := f.number(m[0]); ok && a != 0 {
+				font.scale = a
+			}
+		}
+	}
+
+	base := &standardEncoding
+	flags, _ := f.number(descriptor["Flags"])
+	symbolic := int(flags)&4 != 0
+	name := strings.ToLower(font.baseFont)
+	if symbolic || strings.Contains(name, "symbol") || strings.Contains(name, "dingbats") {
+		base = &latin1Encoding
+	}
+	var differences pdfArray
+	switch e := f.resolve(d["Encoding"]).(type) {
+	case pdfName:
+		base = namedEncoding(e, base)
+	case pdfDict:
+		if n, ok := f.resolve(e["BaseEncoding"]).(pdfName); ok {
+			base = namedEncoding(n, base)
+		}
+		differences = f.array(e["Differences"])
+	}
+	for c, r := range base {
+		if r != 0 {
+			font.simple[c] = string(r)
+		}
+	}
+	code := 0
+	for _, v := range differences {
+		switch v := f.resolve(v).(type) {
+		case int64:
+			code = int(v)
+		case pdfName:
+			if code >= 0 && code < 256 {
+				font.simple[code] = glyphText(string(v))
+			}
+			code++
+		}
+	}
+
+	widths := f.array(d["Widths"])
+	first, _ := f.number(d["FirstChar"])
+	for i, w := range widths {
+		if n, ok := f.number(w); ok {
+			font.widths[uint32(int(first)+i)] = n
+		}
+	}
+	font.dflt, _ = f.number(descriptor["MissingWidth"])
+	font.standard = len(widths) == 0 && subtype != "Type3"
+}
+
+func namedEncoding(name pdfName, fallback *[256]rune) *[256]rune {
+	switch name {
+	case "WinAnsiEncoding":
+		return &winAnsiEncoding
+	case "MacRomanEncoding":
+		return &macRomanEncoding
+	case "StandardEncoding":
+		return &standardEncoding
+	}
+	return fallback
+}
+
+func (f *pdfFile) loadCompositeFont(font *pdfFont, d pdfDict) {
+	font.composite = true
+	switch e := f.resolve(d["Encoding"]).(type) {
+	case pdfName:
+		name := string(e)
+		if strings.Contains(name, "UCS2") || strings.Contains(name, "UTF16") {
+			font.utf16 = true
+		} else if !strings.HasPrefix(name, "Identity") {
+			// Other predefined CMaps are not built in; their codes are
+			// measured as two bytes and mapped through ToUnicode only.
+			font.encoding = &pdfCMap{space: []codeRange{{lo: []byte{0, 0}, hi: []byte{0xff, 0xff}}}}
+		}
+	case *pdfStream:
+		if data, err := f.decodeStream(e); err == nil {
+			font.encoding = parseCMap(data)
+		}
+	}
+
+	descendants := f.array(d["DescendantFonts"])
+	if len(descendants) == 0 {
+		return
+	}
+	cidFont := f.dict(descendants[0])
+	font.dflt = 1000
+	if dw, ok := f.number(cidFont["DW"]); ok {
+		font.dflt = dw
+	}
+	w := f.array(cidFont["W"])
+	for i := 0; i < len(w); {
+		first, ok := f.number(w[i])
+		if !ok || i+1 >= len(w) {
+			break
+		}
+		if list := f.array(w[i+1]); list != nil {
+			for j, v := range list {
+				if n, ok := f.number(v); ok {
+					font.widths[uint32(int(first)+j)] = n
+				}
+			}
+			i += 2
+			continue
+		}
+		last, ok1 := f.number(w[i+1])
+		if i+2 >= len(w) {
+			break
+		}
+		width, ok2 := f.number(w[i+2])
+		if ok1 && ok2 && last >= first && last-first < 1<<16 {
+			for c := int(first); c <= int(last); c++ {
+				font.widths[uint32(c)] = width
+			}
+		}
+		i += 3
+	}
+}
+
+// decode splits a string shown with the font into glyphs.
+func (font *pdfFont) decode(s pdfString) []pdfGlyph {
+	data := []byte(s)
+	glyphs := make([]pdfGlyph, 0, len(data))
+	for len(data) > 0 {
+		var code uint32
+		n := 1
+		switch {
+		case !font.composite:
+			code = uint32(data[0])
+		case font.encoding != nil:
+			code, n = font.encoding.nextCode(data, 2)
+		case font.toUnicode != nil && len(font.toUnicode.space) > 0 && !font.utf16:
+			code, n = font.toUnicode.nextCode(data, 2)
+		default:
+			n = min(2, len(data))
+			code = codeValue(data[:n])
+		}
+		if font.utf16 && code >= 0xd800 && code < 0xdc00 && len(data) >= 4 {
+			// A surrogate pair takes four bytes.
+			code, n = codeValue(data[:4]), 4
+		}
+		raw := data[:n]
+		data = data[n:]
+
+		g := pdfGlyph{wordSpace: n == 1 && code == 32}
+		g.text = font.text(code, raw)
+		g.width = font.width(code, n, g.text) * font.scale
+		glyphs = append(glyphs, g)
+	}
+	return glyphs
+}
+
+func (font *pdfFont) text(code uint32, raw []byte) string {
+	if font.toUnicode != nil {
+		if s, ok := font.toUnicode.lookup(code, len(raw)); ok {
+			return s
+		}
+	}
+	switch {
+	case font.utf16:
+		units := make([]uint16, 0, 2)
+		for i := 0; i+1 < len(raw); i += 2 {
+			units = append(units, uint16(raw[i])<<8|uint16(raw[i+1]))
+		}
+		return utf16Text(units)
+	case !font.composite:
+		return font.simple[code&0xff]
+	}
+	return ""
+}
+
+func (font *pdfFont) width(code uint32, n int, text string) float64 {
+	cid := code
+	if font.composite && font.encoding != nil {
+		if c, ok := font.encoding.cid(code, n); ok {
+			cid = c
+		}
+	}
+	if w, ok := font.widths[cid]; ok {
+		return w
+	}
+	if font.standard {
+		r := rune(' ')
+		if text != "" {
+			r = []rune(text)[0]
+		}
+		return standardWidth(font.baseFont, r)
+	}
+	if font.dflt > 0 {
+		return font.dflt
+	}
+	return 500
+}
+
+// codeRange is a range of codes of one length in a CMap's codespace.
+type codeRange struct {
+	lo, hi []byte
+}
+
+type cmapCode struct {
+	code uint32
+	n    int
+}
+
+// cmapRange maps a range of codes to consecutive CIDs, or to consecutive
+// Unicode strings that differ in their last UTF-16 unit.
+type cmapRange struct {
+	lo, hi uint32
+	n      int
+	cid    uint32
+	text   []uint16
+}
+
+// pdfCMap is a CMap: an encoding CMap of a Type0 font, mapping codes to
+// CIDs, or a ToUnicode CMap.
+type pdfCMap struct {
+	space      []codeRange
+	chars      map[cmapCode]string
+	ranges     []cmapRange
+	cids       map[cmapCode]uint32
+	cidRanges  []cmapRange
+	identityID bool
+}
+
+// parseCMap reads the parts of a CMap that matter for text: the codespace
+// and the bfchar, bfrange, cidchar and cidrange mappings. Damaged CMaps are
+// read up to the first error.
+func parseCMap(data []byte) *pdfCMap {
+	m := &pdfCMap{chars: map[cmapCode]string{}, cids: map[cmapCode]uint32{}}
+	l := &pdfLexer{data: data}
+	var ops []any
+	for l.pos < len(l.data) {
+		obj, err := l.object()
+		if err != nil {
+			break
+		}
+		kw, ok := obj.(pdfKeyword)
+		if !ok {
+			ops = append(ops, obj)
+			continue
+		}
+		switch kw {
+		case "endcodespacerange":
+			for i := 0; i+1 < len(ops); i += 2 {
+				lo, ok1 := ops[i].(pdfString)
+				hi, ok2 := ops[i+1].(pdfString)
+				if ok1 && ok2 && len(lo) == len(hi) && len(lo) > 0 && len(lo) <= 4 {
+					m.space = append(m.space, codeRange{[]byte(lo), []byte(hi)})
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(ops); i += 2 {
+				src, ok := ops[i].(pdfString)
+				if !ok || len(src) == 0 || len(src) > 4 {
+					continue
+				}
+				m.chars[cmapCode{codeValue([]byte(src)), len(src)}] = cmapText(ops[i+1])
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(ops); i += 3 {
+				lo, ok1 := ops[i].(pdfString)
+				hi, ok2 := ops[i+1].(pdfString)
+				if !ok1 || !ok2 || len(lo) != len(hi) || len(lo) == 0 || len(lo) > 4 {
+					continue
+				}
+				r := cmapRange{lo: codeValue([]byte(lo)), hi: codeValue([]byte(hi)), n: len(lo)}
+				if r.hi < r.lo {
+					continue
+				}
+				switch dst := ops[i+2].(type) {
+				case pdfString:
+					r.text = utf16Units([]byte(dst))
+					m.ranges = append(m.ranges, r)
+				case pdfArray:
+					for j, v := range dst {
+						if uint32(j) > r.hi-r.lo {
+							break
+						}
+						m.chars[cmapCode{r.lo + uint32(j), r.n}] = cmapText(v)
+					}
+				}
+			}
+		case "endcidchar":
+			for i := 0; i+1 < len(ops); i += 2 {
+				src, ok1 := ops[i].(pdfString)
+				cid, ok2 := ops[i+1].(int64)
+				if ok1 && ok2 && len(src) > 0 && len(src) <= 4 {
+					m.cids[cmapCode{codeValue([]byte(src)), len(src)}] = uint32(cid)
+				}
+			}
+		case "endcidrange":
+			for i := 0; i+2 < len(ops); i += 3 {
+				lo, ok1 := ops[i].(pdfString)
+				hi, ok2 := ops[i+1].(pdfString)
+				cid, ok3 := ops[i+2].(int64)
+				if ok1 && ok2 && ok3 && len(lo) == len(hi) && len(lo) > 0 && len(lo) <= 4 {
+					m.cidRanges = append(m.cidRanges, cmapRange{lo: codeValue([]byte(lo)), hi: codeValue([]byte(hi)), n: len(lo), cid: uint32(cid)})
+				}
+			}
+		case "usecmap":
+			if len(ops) > 0 {
+				if name, ok := ops[len(ops)-1].(pdfName); ok && strings.HasPrefix(string(name), "Identity") {
+					m.identityID = true
+				}
+			}
+		}
+		ops = ops[:0]
+	}
+	return m
+}
+
+// cmapText returns the text a bfchar or bfrange destination maps to.
+func cmapText(v any) string {
+	switch d := v.(type) {
+	case pdfString:
+		return utf16Text(utf16Units([]byte(d)))
+	case pdfName:
+		return glyphText(string(d))
+	}
+	return ""
+}
+
+func codeValue(b []byte) uint32 {
+	var v uint32
+	for _, c := range b {
+		v = v<<8 | uint32(c)
+	}
+	return v
+}
+
+// utf16Units reads big-endian UTF-16. A single odd byte is read as Latin-1,
+// which some writers use for one-byte destinations.
+func utf16Units(b []byte) []uint16 {
+	if len(b) == 1 {
+		return []uint16{uint16(b[0])}
+	}
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	return units
+}
+
+// utf16Text decodes UTF-16, dropping the NUL characters some writers map
+// unused codes to.
+func utf16Text(units []uint16) string {
+	return strings.ReplaceAll(string(utf16.Decode(units)), "\x00", "")
+}
+
+// nextCode returns the first code in data and its length, using the
+// codespace. Bytes outside it are read as codes of the fallback length.
+func (m *pdfCMap) nextCode(data []byte, fallback int) (uint32, int) {
+	for n := 1; n <= 4 && n <= len(data); n++ {
+		for _, r := range m.space {
+			if len(r.lo) == n && inCodeRange(data[:n], r) {
+				return codeValue(data[:n]), n
+			}
+		}
+	}
+	n := min(fallback, len(data))
+	return codeValue(data[:n]), n
+}
+
+func inCodeRange(b []byte, r codeRange) bool {
+	for i, c := range b {
+		if c < r.lo[i] || c > r.hi[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *pdfCMap) lookup(code uint32, n int) (string, bool) {
+	if s, ok := m.chars[cmapCode{code, n}]; ok {
+		return s, true
+	}
+	for _, r := range m.ranges {
+		if r.n == n && code >= r.lo && code <= r.hi && len(r.text) > 0 {
+			units := append([]uint16(nil), r.text...)
+			units[len(units)-1] += uint16(code - r.lo)
+			return utf16Text(units), true
+		}
+	}
+	return "", false
+}
+
+// cid returns the CID of the code of length n.
+func (m *pdfCMap) cid(code uint32, n int) (uint32, bool) {
+	if c, ok := m.cids[cmapCode{code, n}]; ok {
+		return c, true
+	}
+	for _, r := range m.cidRanges {
+		if r.n == n && code >= r.lo && code <= r.hi {
+			return r.cid + code - r.lo, true
+		}
+	}
+	if m.identityID {
+		return code, true
+	}
+	return 0, false
+}
@@ -0,0 +1,144 @@
+package extract
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Layout thresholds, as fractions of the font size.
+const (
+	spaceGap    = 0.15 // A horizontal gap wider than this separates words
+	runGap      = 1.0  // A horizontal gap wider than this ends a run of text
+	columnGap   = 1.0  // Columns must be at least this far apart
+	blockGap    = 0.5  // Blocks must be at least this far apart vertically
+	lineSpacing = 0.4  // Baselines closer than this are on the same line
+)
+
+// pdfRun is text on one baseline with no wide gaps, the unit the page is laid
+// out with.
+type pdfRun struct {
+	x0, x1 float64
+	y      float64
+	size   float64
+	text   strings.Builder
+}
+
+func (r *pdfRun) top() float64    { return r.y + 0.75*r.size }
+func (r *pdfRun) bottom() float64 { return r.y - 0.25*r.size }
+
+// layout returns the page's text in reading order: blocks found by cutting
+// the page along wide horizontal and vertical gaps (an XY-cut), top to bottom
+// and left to right, separated by blank lines. Text that does not run left to
+// right along the page, such as rotated labels, follows in drawing order.
+func layout(chars []pdfChar) string {
+	var runs []*pdfRun
+	var other strings.Builder
+	var last *pdfRun
+	for _, c := range chars {
+		if !c.upright {
+			other.WriteString(c.text)
+			continue
+		}
+		if last != nil && math.Abs(c.y-last.y) < 0.3*c.size {
+			if gap := c.x - last.x1; gap > -0.5*c.size && gap < runGap*c.size {
+				if gap > spaceGap*c.size && !strings.HasSuffix(last.text.String(), " ") && !strings.HasPrefix(c.text, " ") {
+					last.text.WriteString(" ")
+				}
+				last.text.WriteString(c.text)
+				last.x1 = max(last.x1, c.x+c.w)
+				last.size = max(last.size, c.size)
+				continue
+			}
+		}
+		last = &pdfRun{x0: c.x, x1: c.x + max(c.w, 0), y: c.y, size: c.size}
+		last.text.WriteString(c.text)
+		runs = append(runs, last)
+	}
+	runs = slices.DeleteFunc(runs, func(r *pdfRun) bool { return strings.TrimSpace(r.text.String()) == "" })
+
+	var blocks []string
+	xyCut(runs, &blocks)
+	if s := strings.Join(strings.Fields(other.String()), " "); s != "" {
+		blocks = append(blocks, s)
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+// xyCut splits runs at the widest horizontal gap between them, or failing
+// that the widest vertical one, recursively, and writes each block it cannot
+// split further. Cutting across first keeps headers above the columns below
+// them and reads tables row by row.
+func xyCut(runs []*pdfRun, blocks *[]string) {
+	if len(runs) == 0 {
+		return
+	}
+	sizes := make([]float64, len(runs))
+	for i, r := range runs {
+		sizes[i] = r.size
+	}
+	slices.Sort(sizes)
+	size := sizes[len(sizes)/2]
+
+	// The widest horizontal gap, scanning from the top.
+	byTop := slices.Clone(runs)
+	sort.SliceStable(byTop, func(i, j int) bool { return byTop[i].top() > byTop[j].top() })
+	yGap, yAt := 0.0, 0
+	bottom := byTop[0].bottom()
+	for i := 1; i < len(byTop); i++ {
+		if g := bottom - byTop[i].top(); g > yGap {
+			yGap, yAt = g, i
+		}
+		bottom = min(bottom, byTop[i].bottom())
+	}
+
+	// The widest vertical gap, scanning from the left.
+	byLeft := slices.Clone(runs)
+	sort.SliceStable(byLeft, func(i, j int) bool { return byLeft[i].x0 < byLeft[j].x0 })
+	xGap, xAt := 0.0, 0
+	right := byLeft[0].x1
+	for i := 1; i < len(byLeft); i++ {
+		if g := byLeft[i].x0 - right; g > xGap {
+			xGap, xAt = g, i
+		}
+		right = max(right, byLeft[i].x1)
+	}
+
+	switch {
+	case yGap >= blockGap*size:
+		xyCut(byTop[:yAt], blocks)
+		xyCut(byTop[yAt:], blocks)
+	case xGap >= columnGap*size:
+		xyCut(byLeft[:xAt], blocks)
+		xyCut(byLeft[xAt:], blocks)
+	default:
+		*blocks = append(*blocks, lines(runs))
+	}
+}
+
+// lines joins the runs of a block into lines, top to bottom.
+func lines(runs []*pdfRun) string {
+	runs = slices.Clone(runs)
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].y > runs[j].y })
+	var out []string
+	for i := 0; i < len(runs); {
+		j := i + 1
+		for j < len(runs) && runs[i].y-runs[j].y < lineSpacing*max(runs[i].size, runs[j].size) {
+			j++
+		}
+		line := runs[i:j]
+		sort.SliceStable(line, func(a, b int) bool { return line[a].x0 < line[b].x0 })
+		var b strings.Builder
+		for k, r := range line {
+			text := r.text.String()
+			if k > 0 && r.x0-line[k-1].x1 > spaceGap*r.size && !strings.HasSuffix(b.String(), " ") && !strings.HasPrefix(text, " ") {
+				b.WriteString(" ")
+			}
+			b.WriteString(text)
+		}
+		out = append(out, strings.Join(strings.Fields(b.String()), " "))
+		i = j
+	}
+	return strings.Join(out, "\n")
+}
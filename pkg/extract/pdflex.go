@@ -0,0 +1,374 @@
+package extract
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// PDF objects are represented as nil, bool, int64, float64, pdfString,
+// pdfName, pdfArray, pdfDict, pdfRef and *pdfStream. Content stream operators
+// are pdfKeyword.
+type (
+	pdfName    string
+	pdfString  string
+	pdfKeyword string
+	pdfArray   []any
+	pdfDict    map[pdfName]any
+	pdfRef     struct{ num, gen int }
+	pdfStream  struct {
+		dict pdfDict
+		raw  []byte // Still encoded and, for encrypted files, encrypted
+		ref  pdfRef // The object the stream was read from, for decryption
+	}
+)
+
+// Delimiter tokens.
+type pdfDelim byte
+
+var errPDFSyntax = errors.New("invalid PDF syntax")
+
+// pdfLexer splits PDF syntax into tokens.
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func isPDFSpace(c byte) bool {
+	switch c {
+	case 0, '\t', '\n', '\f', '\r', ' ':
+		return true
+	}
+	return false
+}
+
+func isPDFDelim(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+// skipSpace skips whitespace and comments.
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isPDFSpace(c) {
+			l.pos++
+		} else if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		} else {
+			return
+		}
+	}
+}
+
+// token returns the next token: a number, pdfString, pdfName, pdfKeyword
+// (including true, false, null, R, obj and the like) or pdfDelim for "[",
+// "]", "{", "}", "<<" (as '<') and ">>" (as '>'). It returns nil at the end
+// of the data.
+func (l *pdfLexer) token() (any, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, nil
+	}
+	c := l.data[l.pos]
+	switch {
+	case c == '(':
+		l.pos++
+		return l.literalString()
+	case c == '<':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '<' {
+			l.pos += 2
+			return pdfDelim('<'), nil
+		}
+		l.pos++
+		return l.hexString()
+	case c == '>':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '>' {
+			l.pos += 2
+			return pdfDelim('>'), nil
+		}
+		l.pos++
+		return nil, fmt.Errorf("%w: unexpected '>' at offset %d", errPDFSyntax, l.pos-1)
+	case c == '[' || c == ']' || c == '{' || c == '}':
+		l.pos++
+		return pdfDelim(c), nil
+	case c == '/':
+		l.pos++
+		return l.name(), nil
+	case c == ')':
+		l.pos++
+		return nil, fmt.Errorf("%w: unexpected ')' at offset %d", errPDFSyntax, l.pos-1)
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelim(l.data[l.pos]) {
+		l.pos++
+	}
+	word := l.data[start:l.pos]
+	if n, ok := parsePDFNumber(word); ok {
+		return n, nil
+	}
+	return pdfKeyword(word), nil
+}
+
+// parsePDFNumber parses an integer or real such as "12", "-3", ".5" or "4.".
+func parsePDFNumber(b []byte) (any, bool) {
+	if len(b) == 0 {
+		return nil, false
+	}
+	digits, dot := 0, false
+	for i, c := range b {
+		switch {
+		case c >= '0' && c <= '9':
+			digits++
+		case c == '.' && !dot:
+			dot = true
+		case (c == '-' || c == '+') && i == 0:
+		default:
+			return nil, false
+		}
+	}
+	if digits == 0 {
+		return nil, false
+	}
+	if !dot {
+		if n, err := strconv.ParseInt(string(b), 10, 64); err == nil {
+			return n, true
+		}
+	}
+	s := string(b)
+	if s[0] == '+' {
+		s = s[1:]
+	}
+	if s[len(s)-1] == '.' {
+		s += "0"
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	return f, err == nil
+}
+
+func (l *pdfLexer) name() pdfName {
+	var b []byte
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isPDFSpace(c) || isPDFDelim(c) {
+			break
+		}
+		if c == '#' && l.pos+2 < len(l.data) {
+			if v, err := strconv.ParseUint(string(l.data[l.pos+1:l.pos+3]), 16, 8); err == nil {
+				b = append(b, byte(v))
+				l.pos += 3
+				continue
+			}
+		}
+		b = append(b, c)
+		l.pos++
+	}
+	return pdfName(b)
+}
+
+func (l *pdfLexer) literalString() (pdfString, error) {
+	var b []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return pdfString(b), nil
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return pdfString(b), nil
+			}
+			c = l.data[l.pos]
+			l.pos++
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				// A line continuation; \r\n counts as one end of line.
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if c >= '0' && c <= '7' {
+					v := int(c - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(v)
+				}
+			}
+		case '\r':
+			// Ends of line in strings read as \n.
+			if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+				l.pos++
+			}
+			c = '\n'
+		}
+		b = append(b, c)
+	}
+	// Unterminated strings run to the end of the data.
+	return pdfString(b), nil
+}
+
+func (l *pdfLexer) hexString() (pdfString, error) {
+	var b []byte
+	var hi byte
+	half := false
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		var v byte
+		switch {
+		case c == '>':
+			if half {
+				b = append(b, hi<<4)
+			}
+			return pdfString(b), nil
+		case c >= '0' && c <= '9':
+			v = c - '0'
+		case c >= 'a' && c <= 'f':
+			v = c - 'a' + 10
+		case c >= 'A' && c <= 'F':
+			v = c - 'A' + 10
+		case isPDFSpace(c):
+			continue
+		default:
+			return "", fmt.Errorf("%w: bad hex string at offset %d", errPDFSyntax, l.pos-1)
+		}
+		if half {
+			b = append(b, hi<<4|v)
+		} else {
+			hi = v
+		}
+		half = !half
+	}
+	return pdfString(b), nil
+}
+
+// maxPDFNesting bounds how deeply arrays and dictionaries may nest.
+const maxPDFNesting = 64
+
+// object parses one object. Indirect references "n g R" are recognised
+// after integers.
+func (l *pdfLexer) object() (any, error) {
+	return l.objectDepth(0)
+}
+
+func (l *pdfLexer) objectDepth(depth int) (any, error) {
+	if depth > maxPDFNesting {
+		return nil, fmt.Errorf("%w: objects nested too deeply", errPDFSyntax)
+	}
+	tok, err := l.token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case pdfDelim:
+		switch t {
+		case '[':
+			arr := pdfArray{}
+			for {
+				l.skipSpace()
+				if l.pos < len(l.data) && l.data[l.pos] == ']' {
+					l.pos++
+					return arr, nil
+				}
+				if l.pos >= len(l.data) {
+					return arr, nil
+				}
+				v, err := l.objectDepth(depth + 1)
+				if err != nil {
+					return nil, err
+				}
+				arr = append(arr, v)
+			}
+		case '<':
+			dict := pdfDict{}
+			for {
+				l.skipSpace()
+				if l.pos+1 < len(l.data) && l.data[l.pos] == '>' && l.data[l.pos+1] == '>' {
+					l.pos += 2
+					return dict, nil
+				}
+				if l.pos >= len(l.data) {
+					return dict, nil
+				}
+				key, err := l.token()
+				if err != nil {
+					return nil, err
+				}
+				k, ok := key.(pdfName)
+				if !ok {
+					return nil, fmt.Errorf("%w: dictionary key is %T at offset %d", errPDFSyntax, key, l.pos)
+				}
+				v, err := l.objectDepth(depth + 1)
+				if err != nil {
+					return nil, err
+				}
+				if v != nil {
+					dict[k] = v
+				}
+			}
+		default:
+			return nil, fmt.Errorf("%w: unexpected '%c' at offset %d", errPDFSyntax, byte(t), l.pos-1)
+		}
+	case int64:
+		// Look ahead for "gen R".
+		save := l.pos
+		if gen, err := l.token(); err == nil {
+			if g, ok := gen.(int64); ok {
+				if r, err := l.token(); err == nil && r == pdfKeyword("R") {
+					return pdfRef{int(t), int(g)}, nil
+				}
+			}
+		}
+		l.pos = save
+		return t, nil
+	case pdfKeyword:
+		switch t {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return t, nil
+	}
+	return tok, nil
+}
+
+// hasKeywordAt reports whether data has the keyword kw at pos, followed by a
+// delimiter, whitespace or the end of the data.
+func hasKeywordAt(data []byte, pos int, kw string) bool {
+	if !bytes.HasPrefix(data[pos:], []byte(kw)) {
+		return false
+	}
+	end := pos + len(kw)
+	return end == len(data) || isPDFSpace(data[end]) || isPDFDelim(data[end])
+}
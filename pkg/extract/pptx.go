@@ -0,0 +1,169 @@
+package extract
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Slide is the text of one slide of a presentation.
+type Slide struct {
+	Number int // Counted from 1, in presentation order
+	Title  string
+	Text   string // The text of the other shapes and tables, in drawing order
+	Notes  string // The speaker notes
+}
+
+// PPTX reads the slides of a PowerPoint presentation and their speaker notes.
+func PPTX(data []byte) ([]Slide, error) {
+	pkg, err := openPackage(data)
+	if err != nil {
+		return nil, err
+	}
+	presentation, err := pkg.readXML("ppt/presentation.xml")
+	if err != nil {
+		return nil, fmt.Errorf("not a PowerPoint presentation: %w", err)
+	}
+	rels, err := pkg.relationships("ppt/presentation.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	var slides []Slide
+	for _, id := range presentation.path("sldIdLst").all("sldId") {
+		rel, ok := rels[id.relID()]
+		if !ok {
+			continue
+		}
+		part, err := pkg.readOptionalXML(rel.Target)
+		if err != nil {
+			return nil, err
+		}
+		slide := Slide{Number: len(slides) + 1}
+		if part != nil {
+			slide.Title, slide.Text = slideText(part.path("cSld", "spTree"))
+		}
+		if slide.Notes, err = readSpeakerNotes(pkg, rel.Target); err != nil {
+			return nil, err
+		}
+		slides = append(slides, slide)
+	}
+	return slides, nil
+}
+
+// slideText returns the title of a shape tree and the text of its other
+// shapes.
+func slideText(tree *node) (title, text string) {
+	var blocks []string
+	var walk func(n *node)
+	walk = func(n *node) {
+		for i := range n.Nodes {
+			c := &n.Nodes[i]
+			switch c.XMLName.Local {
+			case "grpSp":
+				walk(c)
+			case "sp":
+				s := shapeText(c.child("txBody"))
+				if s == "" {
+					continue
+				}
+				switch placeholderType(c) {
+				case "title", "ctrTitle":
+					if title == "" {
+						title = strings.Join(strings.Fields(s), " ")
+						continue
+					}
+				case "sldNum", "dt":
+					continue
+				}
+				blocks = append(blocks, s)
+			case "graphicFrame":
+				if s := tableText(c); s != "" {
+					blocks = append(blocks, s)
+				}
+			}
+		}
+	}
+	if tree != nil {
+		walk(tree)
+	}
+	return title, strings.Join(blocks, "\n\n")
+}
+
+// readSpeakerNotes returns the speaker notes of a slide: the body of its notes
+// slide, without the slide image and number.
+func readSpeakerNotes(pkg *ooxmlPackage, slide string) (string, error) {
+	rels, err := pkg.relationships(slide)
+	if err != nil {
+		return "", err
+	}
+	for _, rel := range rels {
+		if !strings.HasSuffix(rel.Type, "/notesSlide") {
+			continue
+		}
+		part, err := pkg.readOptionalXML(rel.Target)
+		if err != nil || part == nil {
+			return "", err
+		}
+		var notes []string
+		for _, sp := range part.path("cSld", "spTree").all("sp") {
+			if placeholderType(sp) != "body" {
+				continue
+			}
+			if s := shapeText(sp.child("txBody")); s != "" {
+				notes = append(notes, s)
+			}
+		}
+		return strings.Join(notes, "\n\n"), nil
+	}
+	return "", nil
+}
+
+// placeholderType returns the placeholder type of a shape, or "" if it is
+// not a placeholder.
+func placeholderType(sp *node) string {
+	ph := sp.path("nvSpPr", "nvPr", "ph")
+	if ph == nil {
+		return ""
+	}
+	if t := ph.attr("type"); t != "" {
+		return t
+	}
+	return "body"
+}
+
+// shapeText returns the paragraphs of a text body, one per line.
+func shapeText(body *node) string {
+	var lines []string
+	for _, p := range body.all("p") {
+		var b strings.Builder
+		for i := range p.Nodes {
+			c := &p.Nodes[i]
+			switch c.XMLName.Local {
+			case "r", "fld":
+				if t := c.child("t"); t != nil {
+					b.WriteString(t.Text)
+				}
+			case "br":
+				b.WriteString("\n")
+			}
+		}
+		lines = append(lines, strings.TrimRight(b.String(), " \t"))
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// tableText returns the rows of the table in a graphic frame as
+// tab-separated lines.
+func tableText(frame *node) string {
+	var rows []string
+	for _, tr := range frame.all("tr") {
+		var cells []string
+		for _, tc := range tr.all("tc") {
+			cells = append(cells, strings.Join(strings.Fields(shapeText(tc.child("txBody"))), " "))
+		}
+		if row := strings.TrimRight(strings.Join(cells, "\t"), "\t"); row != "" {
+			rows = append(rows, row)
+		}
+	}
+	return strings.Join(rows, "\n")
+}
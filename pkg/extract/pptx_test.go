@@ -0,0 +1,60 @@
+package extract
+
+import (
+	"reflect"
+	"testing"
+)
+
+const slideNS = `xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main"`
+
+var testPPTX = map[string]string{
+	"ppt/presentation.xml": `<p:presentation ` + slideNS + `><p:sldIdLst>
+<p:sldId id="257" r:id="rId3"/>
+<p:sldId id="256" r:id="rId2"/>
+</p:sldIdLst></p:presentation>`,
+	"ppt/_rels/presentation.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide" Target="slides/slide1.xml"/>
+<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide" Target="slides/slide2.xml"/>
+</Relationships>`,
+	"ppt/slides/slide2.xml": `<p:sld ` + slideNS + `><p:cSld><p:spTree>
+<p:sp><p:nvSpPr><p:nvPr><p:ph type="ctrTitle"/></p:nvPr></p:nvSpPr><p:txBody><a:p><a:r><a:t>Roadmap</a:t></a:r><a:br/><a:r><a:t>2025</a:t></a:r></a:p></p:txBody></p:sp>
+<p:sp><p:nvSpPr><p:nvPr><p:ph idx="1"/></p:nvPr></p:nvSpPr><p:txBody><a:p><a:r><a:t>Ship </a:t></a:r><a:r><a:rPr b="1"/><a:t>search</a:t></a:r></a:p><a:p><a:r><a:t>Grow usage</a:t></a:r></a:p></p:txBody></p:sp>
+<p:grpSp><p:sp><p:txBody><a:p><a:r><a:t>Grouped note</a:t></a:r></a:p></p:txBody></p:sp></p:grpSp>
+<p:sp><p:nvSpPr><p:nvPr><p:ph type="sldNum"/></p:nvPr></p:nvSpPr><p:txBody><a:p><a:fld type="slidenum"><a:t>1</a:t></a:fld></a:p></p:txBody></p:sp>
+</p:spTree></p:cSld></p:sld>`,
+	"ppt/slides/_rels/slide2.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slideLayout" Target="../slideLayouts/slideLayout1.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/notesSlide" Target="../notesSlides/notesSlide1.xml"/>
+</Relationships>`,
+	"ppt/notesSlides/notesSlide1.xml": `<p:notes ` + slideNS + `><p:cSld><p:spTree>
+<p:sp><p:nvSpPr><p:nvPr><p:ph type="sldImg"/></p:nvPr></p:nvSpPr></p:sp>
+<p:sp><p:nvSpPr><p:nvPr><p:ph type="body" idx="1"/></p:nvPr></p:nvSpPr><p:txBody><a:p><a:r><a:t>Mention the beta.</a:t></a:r></a:p></p:txBody></p:sp>
+<p:sp><p:nvSpPr><p:nvPr><p:ph type="sldNum" idx="5"/></p:nvPr></p:nvSpPr><p:txBody><a:p><a:fld type="slidenum"><a:t>1</a:t></a:fld></a:p></p:txBody></p:sp>
+</p:spTree></p:cSld></p:notes>`,
+	"ppt/slides/slide1.xml": `<p:sld ` + slideNS + `><p:cSld><p:spTree>
+<p:graphicFrame><a:graphic><a:graphicData><a:tbl>
+<a:tr><a:tc><a:txBody><a:p><a:r><a:t>Quarter</a:t></a:r></a:p></a:txBody></a:tc><a:tc><a:txBody><a:p><a:r><a:t>Revenue</a:t></a:r></a:p></a:txBody></a:tc></a:tr>
+<a:tr><a:tc><a:txBody><a:p><a:r><a:t>Q1</a:t></a:r></a:p></a:txBody></a:tc><a:tc><a:txBody><a:p><a:r><a:t>10</a:t></a:r></a:p></a:txBody></a:tc></a:tr>
+</a:tbl></a:graphicData></a:graphic></p:graphicFrame>
+</p:spTree></p:cSld></p:sld>`,
+}
+
+func TestPPTX(t *testing.T) {
+	slides, err := PPTX(buildPackage(t, testPPTX))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Slide{
+		{Number: 1, Title: "Roadmap 2025", Text: "Ship search\nGrow usage\n\nGrouped note", Notes: "Mention the beta."},
+		{Number: 2, Text: "Quarter\tRevenue\nQ1\t10"},
+	}
+	if !reflect.DeepEqual(slides, want) {
+		t.Errorf("got %#v\nwant %#v", slides, want)
+	}
+}
+
+func TestPPTXRejectsOtherFiles(t *testing.T) {
+	if _, err := PPTX(buildPackage(t, testXLSX)); err == nil {
+		t.Error("a workbook was accepted")
+	}
+}
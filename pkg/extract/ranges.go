@@ -0,0 +1,91 @@
+package extract
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Range is an inclusive range of page numbers, counted from 1. A Last of 0
+// runs to the end.
+type Range struct {
+	First, Last int
+}
+
+// Ranges selects pages. The empty Ranges selects every page.
+type Ranges []Range
+
+// ParseRanges parses comma-separated page numbers and ranges, such as
+// "1-3,5,8-". A range with no start, such as "-4", starts at 1.
+func ParseRanges(s string) (Ranges, error) {
+	var ranges Ranges
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		first, last, isRange := strings.Cut(part, "-")
+		first, last = strings.TrimSpace(first), strings.TrimSpace(last)
+		if first == "" && last == "" {
+			return nil, fmt.Errorf("invalid page range '%s'", part)
+		}
+		r := Range{First: 1}
+		var err error
+		if first != "" {
+			if r.First, err = strconv.Atoi(first); err != nil || r.First < 1 {
+				return nil, fmt.Errorf("invalid page range '%s': pages are numbered from 1", part)
+			}
+		}
+		switch {
+		case !isRange:
+			r.Last = r.First
+		case last != "":
+			if r.Last, err = strconv.Atoi(last); err != nil || r.Last < r.First {
+				return nil, fmt.Errorf("invalid page range '%s'", part)
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+// Contains reports whether page n is selected.
+func (r Ranges) Contains(n int) bool {
+	if len(r) == 0 {
+		return true
+	}
+	for _, rng := range r {
+		if n >= rng.First && (rng.Last == 0 || n <= rng.Last) {
+			return true
+		}
+	}
+	return false
+}
+
+// check returns an error if a range starts after the last of count pages.
+func (r Ranges) check(count int, unit string) error {
+	for _, rng := range r {
+		if rng.First > count {
+			return fmt.Errorf("%s %d is out of range: there are %d %ss", unit, rng.First, count, unit)
+		}
+	}
+	return nil
+}
+
+func (r Range) String() string {
+	switch r.Last {
+	case r.First:
+		return strconv.Itoa(r.First)
+	case 0:
+		return strconv.Itoa(r.First) + "-"
+	}
+	return strconv.Itoa(r.First) + "-" + strconv.Itoa(r.Last)
+}
+
+func (r Ranges) String() string {
+	parts := make([]string, len(r))
+	for i, rng := range r {
+		parts[i] = rng.String()
+	}
+	return strings.Join(parts, ",")
+}
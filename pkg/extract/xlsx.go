@@ -0,0 +1,503 @@
+package extract
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Excel's limits, which bound the cell references a sheet may use.
+const (
+	maxSheetRows    = 1 << 20
+	maxSheetColumns = 1 << 14
+)
+
+// maxWorkbookCells bounds the cells XLSX keeps, counting the empty ones
+// before a value in its row and each row before the last, so a few cells far
+// apart cannot expand into gigabytes.
+const maxWorkbookCells = 2 << 20
+
+// ErrTooLarge is returned for a workbook with more cells than XLSX keeps, or
+// a sheet whose CSV or Markdown would be longer than asked for.
+var ErrTooLarge = errors.New("sheet is too large")
+
+// Sheet is a worksheet: its cell values as text, row by row, without the
+// empty rows and columns after the last value.
+type Sheet struct {
+	Name   string
+	Hidden bool
+	Rows   [][]string
+}
+
+// XLSXOptions selects what XLSX extracts.
+type XLSXOptions struct {
+	Sheet string    // The name of the one sheet to read; all of them if empty
+	Cells CellRange // The cells to read from each sheet; all of them if zero
+}
+
+// XLSX reads the sheets of an Excel workbook. Cells hold the values Excel
+// last calculated; dates and times are written in ISO 8601.
+func XLSX(data []byte, opts XLSXOptions) ([]Sheet, error) {
+	pkg, err := openPackage(data)
+	if err != nil {
+		return nil, err
+	}
+	workbook, err := pkg.readXML("xl/workbook.xml")
+	if err != nil {
+		return nil, fmt.Errorf("not an Excel workbook: %w", err)
+	}
+	rels, err := pkg.relationships("xl/workbook.xml")
+	if err != nil {
+		return nil, err
+	}
+	x := &xlsxReader{}
+	if v := workbook.path("workbookPr").attrOrEmpty("date1904"); v == "1" || v == "true" {
+		x.date1904 = true
+	}
+	for _, rel := range rels {
+		switch {
+		case strings.HasSuffix(rel.Type, "/sharedStrings"):
+			if x.strings, err = readSharedStrings(pkg, rel.Target); err != nil {
+				return nil, err
+			}
+		case strings.HasSuffix(rel.Type, "/styles"):
+			if x.styles, err = readCellStyles(pkg, rel.Target); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var sheets []Sheet
+	var names []string
+	for _, s := range workbook.all("sheet") {
+		name := s.attr("name")
+		names = append(names, "'"+name+"'")
+		if opts.Sheet != "" && name != opts.Sheet {
+			continue
+		}
+		rel, ok := rels[s.relID()]
+		if !ok {
+			continue
+		}
+		sheet := Sheet{Name: name, Hidden: s.attr("state") == "hidden" || s.attr("state") == "veryHidden"}
+		if sheet.Rows, err = x.readSheet(pkg, rel.Target, opts.Cells); err != nil {
+			return nil, fmt.Errorf("sheet '%s': %w", name, err)
+		}
+		sheets = append(sheets, sheet)
+	}
+	if opts.Sheet != "" && len(sheets) == 0 {
+		return nil, fmt.Errorf("no sheet named '%s': the workbook has %s", opts.Sheet, strings.Join(names, ", "))
+	}
+	return sheets, nil
+}
+
+// CSV returns the sheet as CSV of at most limit bytes, or ErrTooLarge. Rows
+// end at their last value rather than being padded to the widest, and empty
+// rows are empty lines.
+func (s *Sheet) CSV(limit int) (string, error) {
+	b := &limitedBuilder{limit: limit}
+	w := csv.NewWriter(b)
+	for _, row := range s.Rows {
+		if err := w.Write(row); err != nil {
+			return "", err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// Markdown returns the sheet as a Markdown table with the first row as the
+// header, of at most limit bytes, or ErrTooLarge. A table's rows are all as
+// wide as the widest, so a sparse sheet soon outgrows the limit.
+func (s *Sheet) Markdown(limit int) (string, error) {
+	width := 0
+	for _, row := range s.Rows {
+		width = max(width, len(row))
+	}
+	if width == 0 {
+		return "", nil
+	}
+	// Cells take at most twice their length once escaped, plus a separator.
+	size := 1 + 6*width
+	for _, row := range s.Rows {
+		size += 2 + 3*width
+		for _, cell := range row {
+			size += 2 * len(cell)
+		}
+		if size > limit {
+			return "", fmt.Errorf("%w: its Markdown table would be longer than %d bytes", ErrTooLarge, limit)
+		}
+	}
+	return renderTable(s.Rows, width, Markdown) + "\n", nil
+}
+
+// limitedBuilder is a strings.Builder that fails with ErrTooLarge rather than
+// grow past limit bytes.
+type limitedBuilder struct {
+	strings.Builder
+	limit int
+}
+
+func (b *limitedBuilder) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		return 0, fmt.Errorf("%w: its CSV would be longer than %d bytes", ErrTooLarge, b.limit)
+	}
+	return b.Builder.Write(p)
+}
+
+// CellRange is a rectangle of cells, with columns and rows counted from 1. A
+// bound of 0 leaves its side open, so the zero CellRange is a whole sheet.
+type CellRange struct {
+	FirstCol, FirstRow, LastCol, LastRow int
+}
+
+// ParseCellRange parses a range in A1 notation: a cell such as "B2", cells
+// such as "A1:C10", whole columns such as "B:D", whole rows such as "2:10",
+// or a mix such as "A2:C". The empty string is the whole sheet.
+func ParseCellRange(s string) (CellRange, error) {
+	s = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(s), "$", ""))
+	if s == "" {
+		return CellRange{}, nil
+	}
+	first, last, isRange := strings.Cut(s, ":")
+	if !isRange {
+		last = first
+	}
+	var r CellRange
+	var ok1, ok2 bool
+	r.FirstCol, r.FirstRow, ok1 = parseRangeBound(first)
+	r.LastCol, r.LastRow, ok2 = parseRangeBound(last)
+	if !ok1 || !ok2 || (r.LastCol != 0 && r.LastCol < r.FirstCol) || (r.LastRow != 0 && r.LastRow < r.FirstRow) {
+		return CellRange{}, fmt.Errorf("invalid cell range '%s': use A1 notation such as 'A1:C10', 'B:D' or '2:10'", s)
+	}
+	return r, nil
+}
+
+// parseRangeBound parses one end of a cell range: a cell, a column or a row.
+func parseRangeBound(s string) (col, row int, ok bool) {
+	i := 0
+	for i < len(s) && s[i] >= 'A' && s[i] <= 'Z' {
+		i++
+	}
+	switch {
+	case i == 0:
+		row, err := strconv.Atoi(s)
+		return 0, row, err == nil && row >= 1 && row <= maxSheetRows
+	case i == len(s):
+		col, _, ok := parseCellRef(s + "1")
+		return col, 0, ok
+	}
+	col, row, ok = parseCellRef(s)
+	return col, row, ok && row <= maxSheetRows
+}
+
+// contains reports whether the cell at col and row is in the range.
+func (r CellRange) contains(col, row int) bool {
+	return col >= r.FirstCol && (r.LastCol == 0 || col <= r.LastCol) &&
+		row >= r.FirstRow && (r.LastRow == 0 || row <= r.LastRow)
+}
+
+// Number format kinds.
+const (
+	numberPlain = iota
+	numberDate
+	numberTime
+	numberDateTime
+)
+
+type xlsxReader struct {
+	strings  []string
+	styles   []int // Number format kind by cell style index
+	date1904 bool
+	cells    int // Cells kept so far, bounded by maxWorkbookCells
+}
+
+// readSheet reads the values of the cells of a worksheet part in a range. A
+// missing part is an empty sheet.
+func (x *xlsxReader) readSheet(pkg *ooxmlPackage, part string, rng CellRange) ([][]string, error) {
+	rc, err := pkg.open(part)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return x.rows(xml.NewDecoder(rc), rng)
+}
+
+// rows returns the values of the cells of a worksheet in a range, with rows
+// and columns counted from the range's first. The worksheet is streamed:
+// only the cells in the range are decoded and kept.
+func (x *xlsxReader) rows(dec *xml.Decoder, rng CellRange) ([][]string, error) {
+	var rows [][]string
+	rowNum, col := 0, 0
+	inData := false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse worksheet: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.EndElement:
+			if t.Name.Local == "sheetData" {
+				inData = false
+			}
+			continue
+		case xml.StartElement:
+			switch {
+			case t.Name.Local == "sheetData":
+				inData = true
+				continue
+			case !inData:
+				continue
+			case t.Name.Local == "row":
+				if n, err := strconv.Atoi(startAttr(t, "r")); err == nil && n >= 1 {
+					rowNum = n
+				} else {
+					rowNum++
+				}
+				col = 0
+				continue
+			case t.Name.Local != "c":
+				continue
+			}
+			if cc, rr, ok := parseCellRef(startAttr(t, "r")); ok {
+				col, rowNum = cc, rr
+			} else {
+				col++
+			}
+			if rowNum > maxSheetRows || col > maxSheetColumns || !rng.contains(col, rowNum) {
+				if err := dec.Skip(); err != nil {
+					return nil, fmt.Errorf("unable to parse worksheet: %w", err)
+				}
+				continue
+			}
+			var c node
+			if err := dec.DecodeElement(&c, &t); err != nil {
+				return nil, fmt.Errorf("unable to parse worksheet: %w", err)
+			}
+			value := x.value(&c)
+			if value == "" {
+				continue
+			}
+			i, j := rowNum-max(rng.FirstRow, 1), col-max(rng.FirstCol, 1)
+			grow := j + 1
+			if i < len(rows) {
+				grow = max(j+1-len(rows[i]), 0)
+			} else {
+				grow += i - len(rows)
+			}
+			if x.cells += grow; x.cells > maxWorkbookCells {
+				return nil, fmt.Errorf("%w: more than %d cells, counting the empty ones between values", ErrTooLarge, maxWorkbookCells)
+			}
+			for len(rows) <= i {
+				rows = append(rows, nil)
+			}
+			for len(rows[i]) <= j {
+				rows[i] = append(rows[i], "")
+			}
+			rows[i][j] = value
+		}
+	}
+}
+
+// startAttr returns the value of the attribute of a start element with the
+// given local name.
+func startAttr(t xml.StartElement, local string) string {
+	for _, a := range t.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// value returns the text of a cell.
+func (x *xlsxReader) value(c *node) string {
+	v := c.path("v")
+	raw := ""
+	if v != nil {
+		raw = v.Text
+	}
+	switch c.attr("t") {
+	case "s":
+		i, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil || i < 0 || i >= len(x.strings) {
+			return ""
+		}
+		return x.strings[i]
+	case "inlineStr":
+		if is := c.path("is"); is != nil {
+			return stringItem(is)
+		}
+		return raw
+	case "b":
+		if strings.TrimSpace(raw) == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	case "str", "e":
+		return raw
+	}
+	if s, err := strconv.Atoi(c.attr("s")); err == nil && s >= 0 && s < len(x.styles) && x.styles[s] != numberPlain {
+		if f, err := strconv.ParseFloat(strings.TrimSpace(raw), 64); err == nil {
+			return x.formatDate(f, x.styles[s])
+		}
+	}
+	return raw
+}
+
+// formatDate formats an Excel date serial number.
+func (x *xlsxReader) formatDate(serial float64, kind int) string {
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if x.date1904 {
+		epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	if serial < 0 || serial > 2958466 {
+		return strconv.FormatFloat(serial, 'f', -1, 64)
+	}
+	t := epoch.Add(time.Duration(math.Round(serial*86400)) * time.Second)
+	switch {
+	case kind == numberTime && serial < 1:
+		return t.Format(time.TimeOnly)
+	case kind == numberDate || (kind == numberDateTime && serial == math.Trunc(serial)):
+		return t.Format(time.DateOnly)
+	}
+	return t.Format(time.DateTime)
+}
+
+// parseCellRef parses a cell reference such as "B12" into its column and row,
+// both counted from 1.
+func parseCellRef(ref string) (col, row int, ok bool) {
+	i := 0
+	for i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z' {
+		col = col*26 + int(ref[i]-'A'+1)
+		if col > maxSheetColumns {
+			return 0, 0, false
+		}
+		i++
+	}
+	row, err := strconv.Atoi(ref[i:])
+	if i == 0 || err != nil || row < 1 {
+		return 0, 0, false
+	}
+	return col, row, true
+}
+
+// stringItem returns the text of a shared or inline string, leaving out
+// phonetic hints.
+func stringItem(n *node) string {
+	var b strings.Builder
+	for i := range n.Nodes {
+		c := &n.Nodes[i]
+		switch c.XMLName.Local {
+		case "t":
+			b.WriteString(c.Text)
+		case "r":
+			if t := c.child("t"); t != nil {
+				b.WriteString(t.Text)
+			}
+		}
+	}
+	return b.String()
+}
+
+func readSharedStrings(pkg *ooxmlPackage, part string) ([]string, error) {
+	n, err := pkg.readOptionalXML(part)
+	if err != nil || n == nil {
+		return nil, err
+	}
+	var out []string
+	for _, si := range n.all("si") {
+		out = append(out, stringItem(si))
+	}
+	return out, nil
+}
+
+// readCellStyles returns the number format kind of each cell style.
+func readCellStyles(pkg *ooxmlPackage, part string) ([]int, error) {
+	n, err := pkg.readOptionalXML(part)
+	if err != nil || n == nil {
+		return nil, err
+	}
+	custom := map[string]int{}
+	for _, f := range n.path("numFmts").all("numFmt") {
+		custom[f.attr("numFmtId")] = numberFormatKind(f.attr("formatCode"))
+	}
+	var kinds []int
+	for _, xf := range n.path("cellXfs").all("xf") {
+		id := xf.attr("numFmtId")
+		kind, ok := custom[id]
+		if !ok {
+			kind = builtinNumberFormatKind(id)
+		}
+		kinds = append(kinds, kind)
+	}
+	return kinds, nil
+}
+
+func builtinNumberFormatKind(id string) int {
+	switch n, _ := strconv.Atoi(id); {
+	case n >= 14 && n <= 17:
+		return numberDate
+	case n >= 18 && n <= 21, n >= 45 && n <= 47:
+		return numberTime
+	case n == 22:
+		return numberDateTime
+	}
+	return numberPlain
+}
+
+// numberFormatKind tells dates and times from numbers by the letters in a
+// format code, ignoring quoted text, escapes and bracketed colors.
+func numberFormatKind(code string) int {
+	var b strings.Builder
+	for i := 0; i < len(code); i++ {
+		switch c := code[i]; c {
+		case '"':
+			for i++; i < len(code) && code[i] != '"'; i++ {
+			}
+		case '\\', '_', '*':
+			i++
+		case '[':
+			j := strings.IndexByte(code[i:], ']')
+			if j < 0 {
+				i = len(code)
+				continue
+			}
+			// Elapsed time such as [h] counts; colors and conditions do not.
+			if inner := strings.ToLower(code[i+1 : i+j]); strings.Trim(inner, "hms") == "" {
+				b.WriteString(inner)
+			}
+			i += j
+		default:
+			b.WriteByte(c | 0x20)
+		}
+	}
+	s := b.String()
+	hasTime := strings.ContainsAny(s, "hs")
+	hasDate := strings.ContainsAny(s, "yd") || (strings.Contains(s, "m") && !hasTime)
+	switch {
+	case hasDate && hasTime:
+		return numberDateTime
+	case hasDate:
+		return numberDate
+	case hasTime:
+		return numberTime
+	}
+	return numberPlain
+}
@@ -0,0 +1,253 @@
+package extract
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sheetNS = `xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+
+var testXLSX = map[string]string{
+	"xl/workbook.xml": `<workbook ` + sheetNS + `><sheets>
+<sheet name="Sales" sheetId="1" r:id="rId1"/>
+<sheet name="Notes" sheetId="2" state="hidden" r:id="rId2"/>
+</sheets></workbook>`,
+	"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="/xl/worksheets/sheet2.xml"/>
+<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/sharedStrings" Target="sharedStrings.xml"/>
+<Relationship Id="rId4" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+</Relationships>`,
+	"xl/sharedStrings.xml": `<sst ` + sheetNS + `>
+<si><t>Region</t></si>
+<si><t>Date</t></si>
+<si><r><t>North, </t></r><r><rPr><b/></rPr><t>East</t></r><rPh><t>ignored</t></rPh></si>
+</sst>`,
+	"xl/styles.xml": `<styleSheet ` + sheetNS + `>
+<numFmts><numFmt numFmtId="164" formatCode="[Red]yyyy\-mm\-dd"/><numFmt numFmtId="165" formatCode="0.00&quot; days&quot;"/></numFmts>
+<cellXfs><xf numFmtId="0"/><xf numFmtId="164"/><xf numFmtId="165"/><xf numFmtId="22"/><xf numFmtId="20"/></cellXfs>
+</styleSheet>`,
+	"xl/worksheets/sheet1.xml": `<worksheet ` + sheetNS + `><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="inlineStr"><is><t>Units</t></is></c><c r="D1" t="inlineStr"><is><t>Open</t></is></c></row>
+<row r="2"><c r="A2" t="s"><v>2</v></c><c r="B2" s="1"><v>45292</v></c><c r="C2" s="2"><v>1.5</v></c><c r="D2" t="b"><v>1</v></c></row>
+<row r="4"><c r="B4" s="3"><v>45292.75</v></c><c r="C4" s="4"><v>0.5</v></c><c r="D4" t="str"><f>C4*2</f><v>1</v></c><c r="F4" s="1"/></row>
+</sheetData></worksheet>`,
+	"xl/worksheets/sheet2.xml": `<worksheet ` + sheetNS + `><sheetData>
+<row><c t="inlineStr"><is><t>a</t></is></c><c t="e"><v>#DIV/0!</v></c></row>
+</sheetData></worksheet>`,
+}
+
+func TestXLSX(t *testing.T) {
+	sheets, err := XLSX(buildPackage(t, testXLSX), XLSXOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Sheet{
+		{Name: "Sales", Rows: [][]string{
+			{"Region", "Date", "Units", "Open"},
+			{"North, East", "2024-01-01", "1.5", "TRUE"},
+			nil,
+			{"", "2024-01-01 18:00:00", "12:00:00", "1"},
+		}},
+		{Name: "Notes", Hidden: true, Rows: [][]string{{"a", "#DIV/0!"}}},
+	}
+	if !reflect.DeepEqual(sheets, want) {
+		t.Fatalf("got %#v\nwant %#v", sheets, want)
+	}
+
+	wantCSV := `Region,Date,Units,Open
+"North, East",2024-01-01,1.5,TRUE
+
+,2024-01-01 18:00:00,12:00:00,1
+`
+	if got, err := sheets[0].CSV(1000); err != nil || got != wantCSV {
+		t.Errorf("CSV:\n%s\nwant:\n%s (%v)", got, wantCSV, err)
+	}
+	if _, err := sheets[0].CSV(len(wantCSV) - 1); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("CSV over the limit: got error %v", err)
+	}
+}
+
+// sparseXLSX returns a workbook with one sheet holding a value in each of
+// the cells.
+func sparseXLSX(t *testing.T, cells ...string) []byte {
+	t.Helper()
+	var data strings.Builder
+	for _, c := range cells {
+		data.WriteString(`<row><c r="` + c + `" t="inlineStr"><is><t>x</t></is></c></row>`)
+	}
+	return buildPackage(t, map[string]string{
+		"xl/workbook.xml": `<workbook ` + sheetNS + `><sheets><sheet name="Sparse" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`,
+		"xl/worksheets/sheet1.xml": `<worksheet ` + sheetNS + `><sheetData>` + data.String() + `</sheetData></worksheet>`,
+	})
+}
+
+func TestXLSXSparseSheets(t *testing.T) {
+	// Rows are not padded to the widest, so far-apart cells stay small.
+	sheets, err := XLSX(sparseXLSX(t, "A1", "XFD1", "A100000"), XLSXOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	csv, err := sheets[0].CSV(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 2 * (16384 + 100000); len(csv) > want {
+		t.Errorf("CSV is %d bytes, want at most %d", len(csv), want)
+	}
+	// A Markdown table is as wide as its widest row everywhere.
+	if _, err := sheets[0].Markdown(1 << 20); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Markdown of a sparse sheet: got error %v", err)
+	}
+
+	// Cells far enough apart are refused before they are expanded.
+	rows := make([]string, 0, 200)
+	for i := 1; i <= 200; i++ {
+		rows = append(rows, fmt.Sprintf("XFD%d", i*1000))
+	}
+	if _, err := XLSX(sparseXLSX(t, rows...), XLSXOptions{}); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("XLSX of %d full-width rows: got error %v", len(rows), err)
+	}
+	// Selecting cells keeps only those.
+	sheets, err = XLSX(sparseXLSX(t, rows...), XLSXOptions{Cells: CellRange{FirstCol: 16384, FirstRow: 199000}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]string{{"x"}, 1000: {"x"}}; !reflect.DeepEqual(sheets[0].Rows, want) {
+		t.Errorf("XFD199000: got %d rows", len(sheets[0].Rows))
+	}
+}
+
+func TestXLSXSheet(t *testing.T) {
+	data := buildPackage(t, testXLSX)
+	sheets, err := XLSX(data, XLSXOptions{Sheet: "Notes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sheets) != 1 || sheets[0].Name != "Notes" {
+		t.Errorf("got %+v, want only sheet Notes", sheets)
+	}
+
+	_, err = XLSX(data, XLSXOptions{Sheet: "Missing"})
+	if err == nil || !strings.Contains(err.Error(), "'Sales', 'Notes'") {
+		t.Errorf("error %v should list the sheets", err)
+	}
+}
+
+func TestXLSXCells(t *testing.T) {
+	data := buildPackage(t, testXLSX)
+	tests := []struct {
+		cells string
+		want  [][]string
+	}{
+		{"B2:C4", [][]string{{"2024-01-01", "1.5"}, nil, {"2024-01-01 18:00:00", "12:00:00"}}},
+		{"A1", [][]string{{"Region"}}},
+		{"C:D", [][]string{{"Units", "Open"}, {"1.5", "TRUE"}, nil, {"12:00:00", "1"}}},
+		{"3:4", [][]string{nil, {"", "2024-01-01 18:00:00", "12:00:00", "1"}}},
+		{"E1:F9", nil},
+	}
+	for _, tt := range tests {
+		cells, err := ParseCellRange(tt.cells)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sheets, err := XLSX(data, XLSXOptions{Sheet: "Sales", Cells: cells})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(sheets[0].Rows, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.cells, sheets[0].Rows, tt.want)
+		}
+	}
+
+	sheets, err := XLSX(data, XLSXOptions{Sheet: "Sales", Cells: CellRange{FirstCol: 1, LastCol: 2, LastRow: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "| Region | Date |\n| --- | --- |\n| North, East | 2024-01-01 |\n"
+	if got, err := sheets[0].Markdown(1000); err != nil || got != want {
+		t.Errorf("Markdown:\n%s\nwant:\n%s (%v)", got, want, err)
+	}
+}
+
+func TestParseCellRange(t *testing.T) {
+	for s, want := range map[string]CellRange{
+		"":         {},
+		"b2":       {2, 2, 2, 2},
+		"$A$1:C10": {1, 1, 3, 10},
+		"B:D":      {FirstCol: 2, LastCol: 4},
+		"2:10":     {FirstRow: 2, LastRow: 10},
+		"A2:C":     {FirstCol: 1, FirstRow: 2, LastCol: 3},
+		"AA1":      {27, 1, 27, 1},
+	} {
+		got, err := ParseCellRange(s)
+		if err != nil || got != want {
+			t.Errorf("ParseCellRange(%q) = %+v, %v, want %+v", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"C1:A1", "A0", "1A", ":", "A1:", "XFE1", "A1:B2:C3"} {
+		if _, err := ParseCellRange(s); err == nil {
+			t.Errorf("ParseCellRange(%q) succeeded", s)
+		}
+	}
+}
+
+func TestNumberFormatKind(t *testing.T) {
+	for code, want := range map[string]int{
+		"General":              numberPlain,
+		"0.00":                 numberPlain,
+		`#,##0" days"`:         numberPlain,
+		"[Blue]0%":             numberPlain,
+		"d/m/yyyy":             numberDate,
+		"mmm yy":               numberDate,
+		"h:mm AM/PM":           numberTime,
+		"[h]:mm:ss":            numberTime,
+		"yyyy-mm-dd hh:mm":     numberDateTime,
+		`\d\a\y 0`:             numberPlain,
+		"[$-409]dddd, mmmm dd": numberDate,
+	} {
+		if got := numberFormatKind(code); got != want {
+			t.Errorf("numberFormatKind(%q) = %d, want %d", code, got, want)
+		}
+	}
+}
+
+func TestXLSXStreamsSheet(t *testing.T) {
+	// Only sheetData holds cells, and rows without a reference follow the
+	// previous one.
+	var data strings.Builder
+	for i := 1; i <= 50000; i++ {
+		fmt.Fprintf(&data, `<row><c><v>%d</v></c><c t="inlineStr"><is><t>row %d</t></is></c></row>`, i, i)
+	}
+	xlsx := buildPackage(t, map[string]string{
+		"xl/workbook.xml": `<workbook ` + sheetNS + `><sheets><sheet name="Long" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`,
+		"xl/worksheets/sheet1.xml": `<worksheet ` + sheetNS + `><sheetPr><c>not a cell</c></sheetPr><sheetData>` + data.String() + `</sheetData><extLst><c>not a cell</c></extLst></worksheet>`,
+	})
+	sheets, err := XLSX(xlsx, XLSXOptions{Cells: CellRange{FirstCol: 2, FirstRow: 40000, LastRow: 40001}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]string{{"row 40000"}, {"row 40001"}}; !reflect.DeepEqual(sheets[0].Rows, want) {
+		t.Errorf("got %q, want %q", sheets[0].Rows, want)
+	}
+
+	broken := buildPackage(t, map[string]string{
+		"xl/workbook.xml": `<workbook ` + sheetNS + `><sheets><sheet name="Broken" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`,
+		"xl/worksheets/sheet1.xml": `<worksheet ` + sheetNS + `><sheetData><row><c><v>1</v></row>`,
+	})
+	if _, err := XLSX(broken, XLSXOptions{}); err == nil || !strings.Contains(err.Error(), "sheet 'Broken': unable to parse worksheet") {
+		t.Errorf("malformed sheet: got error %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+// Package httpauth authenticates inbound requests to the HTTP transports
+// before they reach the MCP server, using static API keys or JWTs validated
+// against a JWKS.
+package httpauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// ErrUnauthenticated is returned when a request carries no usable credentials.
+var ErrUnauthenticated = errors.New("missing or invalid credentials")
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	// Subject identifies the caller: the API key name or the JWT "sub" claim.
+	Subject string
+	// Email is the JWT "email" claim, if present.
+	Email string
+	// Method is how the caller authenticated ("api_key" or "jwt").
+	Method string
+}
+
+// String formats the principal for audit logs.
+func (p *Principal) String() string {
+	if p == nil {
+		return "anonymous"
+	}
+	if p.Email != "" {
+		return fmt.Sprintf("%s:%s <%s>", p.Method, p.Subject, p.Email)
+	}
+	return p.Method + ":" + p.Subject
+}
+
+// Authenticator verifies the credentials of an inbound request.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+// Chain tries each authenticator in order and accepts the first success.
+type Chain []Authenticator
+
+// Authenticate implements Authenticator.
+func (c Chain) Authenticate(r *http.Request) (*Principal, error) {
+	var errs []error
+	for _, a := range c {
+		p, err := a.Authenticate(r)
+		if err == nil {
+			return p, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, fmt.Errorf("%w: %w", ErrUnauthenticated, errors.Join(errs...))
+}
+
+// Middleware rejects requests that a does not authenticate and stores the
+// principal of accepted ones in the request context.
+func Middleware(a Authenticator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := a.Authenticate(r)
+		if err != nil {
+			log.Printf("Rejected %s %s from %s: %v", r.Method, r.URL.Path, r.RemoteAddr, err)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="gdrive-mcp-server"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+	})
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the authenticated principal, or nil when the
+// request was not authenticated (e.g. the stdio transport).
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer" header.
+func bearerToken(r *http.Request) string {
+	h := r.Header.Get("Authorization")
+	if len(h) > 7 && strings.EqualFold(h[:7], "bearer ") {
+		return strings.TrimSpace(h[7:])
+	}
+	return ""
+}
+
+// APIKeys authenticates requests carrying one of a fixed set of keys, either
+// as a bearer token or in the X-API-Key header. Keys are compared by hash in
+// constant time.
+type APIKeys struct {
+	byHash map[[sha256.Size]byte]string
+}
+
+// NewAPIKeys builds an authenticator from a map of key name to key.
+func NewAPIKeys(keys map[string]string) *APIKeys {
+	a := &APIKeys{byHash: make(map[[sha256.Size]byte]string, len(keys))}
+	for name, key := range keys {
+		a.byHash[sha256.Sum256([]byte(key))] = name
+	}
+	return a
+}
+
+// Authenticate implements Authenticator.
+func (a *APIKeys) Authenticate(r *http.Request) (*Principal, error) {
+	key := r.Header.Get("X-API-Key")
+	if key == "" {
+		key = bearerToken(r)
+	}
+	if key == "" {
+		return nil, errors.New("no api key")
+	}
+	sum := sha256.Sum256([]byte(key))
+	for hash, name := range a.byHash {
+		if subtle.ConstantTimeCompare(hash[:], sum[:]) == 1 {
+			return &Principal{Subject: name, Method: "api_key"}, nil
+		}
+	}
+	return nil, errors.New("unknown api key")
+}
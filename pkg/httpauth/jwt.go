@@ -0,0 +1,335 @@
+package httpauth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	jwksCacheTTL       = time.Hour        // How long fetched keys are trusted
+	jwksMinRefresh     = time.Minute      // The JWKS is fetched at most this often
+	jwtClockSkew       = time.Minute      // Leeway for exp and nbf
+	jwksRequestTimeout = 10 * time.Second // Timeout for fetching the JWKS
+)
+
+// JWTConfig describes which bearer JWTs are accepted.
+type JWTConfig struct {
+	// JWKSURL serves the JSON Web Key Set used to verify signatures.
+	JWKSURL string
+	// Issuer, when set, must equal the "iss" claim.
+	Issuer string
+	// Audience, when set, must appear in the "aud" claim.
+	Audience string
+}
+
+// JWTValidator authenticates bearer JWTs signed with RS256/384/512 or
+// ES256/384/512 by a key from the configured JWKS.
+type JWTValidator struct {
+	cfg    JWTConfig
+	client *http.Client
+
+	mu          sync.Mutex
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time     // When keys were last fetched
+	attemptedAt time.Time     // When a fetch was last started
+	fetchErr    error         // Why the last fetch failed
+	fetching    chan struct{} // Closed when the fetch in progress ends
+}
+
+// NewJWTValidator returns a validator for cfg. Keys are fetched lazily.
+func NewJWTValidator(cfg JWTConfig) *JWTValidator {
+	return &JWTValidator{
+		cfg:    cfg,
+		client: &http.Client{Timeout: jwksRequestTimeout},
+	}
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Subject   string          `json:"sub"`
+	Email     string          `json:"email"`
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *float64        `json:"exp"`
+	NotBefore *float64        `json:"nbf"`
+}
+
+// Authenticate implements Authenticator.
+func (v *JWTValidator) Authenticate(r *http.Request) (*Principal, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return nil, errors.New("no bearer token")
+	}
+	claims, err := v.verify(token, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("invalid jwt: %w", err)
+	}
+	return &Principal{Subject: claims.Subject, Email: claims.Email, Method: "jwt"}, nil
+}
+
+// verify checks the signature and registered claims of a compact JWS.
+func (v *JWTValidator) verify(token string, now time.Time) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("bad header: %w", err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("bad signature encoding: %w", err)
+	}
+	key, err := v.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("bad claims: %w", err)
+	}
+	if claims.ExpiresAt == nil {
+		return nil, errors.New("token has no expiry")
+	}
+	if now.After(time.Unix(int64(*claims.ExpiresAt), 0).Add(jwtClockSkew)) {
+		return nil, errors.New("token expired")
+	}
+	if claims.NotBefore != nil && now.Add(jwtClockSkew).Before(time.Unix(int64(*claims.NotBefore), 0)) {
+		return nil, errors.New("token not yet valid")
+	}
+	if v.cfg.Issuer != "" && claims.Issuer != v.cfg.Issuer {
+		return nil, fmt.Errorf("unexpected issuer '%s'", claims.Issuer)
+	}
+	if v.cfg.Audience != "" && !audienceContains(claims.Audience, v.cfg.Audience) {
+		return nil, errors.New("token is not intended for this audience")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+	return &claims, nil
+}
+
+func decodeSegment(seg string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// audienceContains reports whether the "aud" claim (a string or an array of
+// strings) includes want.
+func audienceContains(raw json.RawMessage, want string) bool {
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return single == want
+	}
+	var many []string
+	if err := json.Unmarshal(raw, &many); err == nil {
+		for _, aud := range many {
+			if aud == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ecCurves is the curve each ECDSA algorithm signs with.
+var ecCurves = map[string]string{
+	"ES256": "P-256",
+	"ES384": "P-384",
+	"ES512": "P-521",
+}
+
+func verifySignature(alg string, key crypto.PublicKey, signingInput string, sig []byte) error {
+	var hash crypto.Hash
+	switch alg {
+	case "RS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512", "ES512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported signing algorithm '%s'", alg)
+	}
+	h := hash.New()
+	h.Write([]byte(signingInput))
+	digest := h.Sum(nil)
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") {
+			return fmt.Errorf("algorithm '%s' does not match an RSA key", alg)
+		}
+		if err := rsa.VerifyPKCS1v15(k, hash, digest, sig); err != nil {
+			return errors.New("signature verification failed")
+		}
+	case *ecdsa.PublicKey:
+		if ecCurves[alg] != k.Curve.Params().Name {
+			return fmt.Errorf("algorithm '%s' does not match an EC key on %s", alg, k.Curve.Params().Name)
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return errors.New("signature verification failed")
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return errors.New("signature verification failed")
+		}
+	default:
+		return errors.New("unsupported key type")
+	}
+	return nil
+}
+
+// key returns the verification key for kid, refreshing the JWKS when it is
+// stale or does not contain kid. The JWKS is fetched by one caller at a time,
+// without holding the lock, and at most once per jwksMinRefresh.
+func (v *JWTValidator) key(kid string) (crypto.PublicKey, error) {
+	for {
+		v.mu.Lock()
+		key, ok := v.keys[kid]
+		if ok && time.Since(v.fetchedAt) < jwksCacheTTL {
+			v.mu.Unlock()
+			return key, nil
+		}
+		if fetching := v.fetching; fetching != nil {
+			v.mu.Unlock()
+			<-fetching
+			continue
+		}
+		if time.Since(v.attemptedAt) < jwksMinRefresh {
+			err := v.fetchErr
+			v.mu.Unlock()
+			switch {
+			case ok:
+				// Keep serving the cached key if the JWKS endpoint is briefly down.
+				return key, nil
+			case err != nil:
+				return nil, err
+			default:
+				return nil, fmt.Errorf("unknown key id '%s'", kid)
+			}
+		}
+
+		fetching := make(chan struct{})
+		v.fetching, v.attemptedAt = fetching, time.Now()
+		v.mu.Unlock()
+
+		keys, err := v.fetchKeys()
+
+		v.mu.Lock()
+		v.fetching, v.fetchErr = nil, err
+		if err == nil {
+			v.keys, v.fetchedAt = keys, time.Now()
+		}
+		v.mu.Unlock()
+		close(fetching)
+	}
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (v *JWTValidator) fetchKeys() (map[string]crypto.PublicKey, error) {
+	resp, err := v.client.Get(v.cfg.JWKSURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to fetch jwks: %s", resp.Status)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("unable to parse jwks: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			continue // Skip key types we cannot use
+		}
+		keys[k.Kid] = pub
+	}
+	return keys, nil
+}
+
+func (k *jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve '%s'", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		pub := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, errors.New("ec key is not on its curve")
+		}
+		return pub, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type '%s'", k.Kty)
+	}
+}
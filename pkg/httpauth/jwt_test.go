@@ -0,0 +1,263 @@
+package httpauth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testKeys are the signing keys served by a jwksServer, by key ID.
+type testKeys struct {
+	rsa  *rsa.PrivateKey
+	p256 *ecdsa.PrivateKey
+	p384 *ecdsa.PrivateKey
+}
+
+func newTestKeys(t *testing.T) *testKeys {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testKeys{rsa: rsaKey, p256: p256, p384: p384}
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func ecJWK(kid string, k *ecdsa.PublicKey) map[string]string {
+	size := (k.Curve.Params().BitSize + 7) / 8
+	return map[string]string{"kty": "EC", "kid": kid, "crv": k.Curve.Params().Name, "x": b64(k.X.FillBytes(make([]byte, size))), "y": b64(k.Y.FillBytes(make([]byte, size)))}
+}
+
+// jwksServer serves the public keys as a JWKS and counts the requests.
+func jwksServer(t *testing.T, keys *testKeys, fetches *atomic.Int32) *httptest.Server {
+	t.Helper()
+	set := map[string]any{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa", "use": "sig", "n": b64(keys.rsa.N.Bytes()), "e": b64(big.NewInt(int64(keys.rsa.E)).Bytes())},
+		ecJWK("p256", &keys.p256.PublicKey),
+		ecJWK("p384", &keys.p384.PublicKey),
+		{"kty": "RSA", "kid": "enc", "use": "enc", "n": b64(keys.rsa.N.Bytes()), "e": "AQAB"},
+	}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		json.NewEncoder(w).Encode(set)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// sign returns a compact JWS of claims with the given header values. The
+// signature is made with key using the hash named by alg.
+func sign(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	input := b64(header) + "." + b64(payload)
+
+	hash := crypto.SHA256
+	switch alg[len(alg)-3:] {
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	}
+	h := hash.New()
+	h.Write([]byte(input))
+	digest := h.Sum(nil)
+
+	var sig []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		s, err := rsa.SignPKCS1v15(rand.Reader, k, hash, digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = s
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		sig = append(r.FillBytes(make([]byte, size)), s.FillBytes(make([]byte, size))...)
+	}
+	return input + "." + b64(sig)
+}
+
+func TestJWTValidator(t *testing.T) {
+	keys := newTestKeys(t)
+	var fetches atomic.Int32
+	srv := jwksServer(t, keys, &fetches)
+	v := NewJWTValidator(JWTConfig{JWKSURL: srv.URL, Issuer: "https://idp.example.com", Audience: "drive-mcp"})
+
+	now := time.Unix(1_800_000_000, 0)
+	claims := func(override map[string]any) map[string]any {
+		c := map[string]any{
+			"sub":   "user-1",
+			"email": "user@example.com",
+			"iss":   "https://idp.example.com",
+			"aud":   "drive-mcp",
+			"exp":   now.Add(time.Hour).Unix(),
+		}
+		for k, val := range override {
+			if val == nil {
+				delete(c, k)
+			} else {
+				c[k] = val
+			}
+		}
+		return c
+	}
+	valid := sign(t, "RS256", "rsa", keys.rsa, claims(nil))
+	parts := strings.Split(valid, ".")
+	tampered := parts[0] + "." + b64([]byte(`{"sub":"admin","iss":"https://idp.example.com","aud":"drive-mcp","exp":1900000000}`)) + "." + parts[2]
+	noneHeader := b64([]byte(`{"alg":"none","kid":"rsa"}`))
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr string
+	}{
+		{"rs256", valid, ""},
+		{"rs512", sign(t, "RS512", "rsa", keys.rsa, claims(nil)), ""},
+		{"es256", sign(t, "ES256", "p256", keys.p256, claims(nil)), ""},
+		{"es384", sign(t, "ES384", "p384", keys.p384, claims(nil)), ""},
+		{"audience list", sign(t, "RS256", "rsa", keys.rsa, claims(map[string]any{"aud": []string{"other", "drive-mcp"}})), ""},
+		{"malformed", "abc.def", "malformed token"},
+		{"bad signature", tampered, "signature verification failed"},
+		{"alg none", noneHeader + "." + parts[1] + ".", "unsupported signing algorithm 'none'"},
+		{"hmac", sign(t, "HS256", "rsa", keys.rsa, claims(nil)), "unsupported signing algorithm 'HS256'"},
+		{"es alg with rsa key", sign(t, "ES256", "rsa", keys.p256, claims(nil)), "does not match an RSA key"},
+		{"rs alg with ec key", sign(t, "RS256", "p256", keys.rsa, claims(nil)), "does not match an EC key"},
+		{"es alg with other curve", sign(t, "ES384", "p256", keys.p256, claims(nil)), "does not match an EC key on P-256"},
+		{"encryption key", sign(t, "RS256", "enc", keys.rsa, claims(nil)), "unknown key id 'enc'"},
+		{"unknown kid", sign(t, "RS256", "other", keys.rsa, claims(nil)), "unknown key id 'other'"},
+		{"expired", sign(t, "RS256", "rsa", keys.rsa, claims(map[string]any{"exp": now.Add(-2 * time.Minute).Unix()})), "token expired"},
+		{"expired within skew", sign(t, "RS256", "rsa", keys.rsa, claims(map[string]any{"exp": now.Add(-30 * time.Second).Unix()})), ""},
+		{"no expiry", sign(t, "RS256", "rsa", keys.rsa, claims(map[string]any{"exp": nil})), "token has no expiry"},
+		{"not yet valid", sign(t, "RS256", "rsa", keys.rsa, claims(map[string]any{"nbf": now.Add(2 * time.Minute).Unix()})), "token not yet valid"},
+		{"wrong issuer", sign(t, "RS256", "rsa", keys.rsa, claims(map[string]any{"iss": "https://evil.example.com"})), "unexpected issuer 'https://evil.example.com'"},
+		{"wrong audience", sign(t, "RS256", "rsa", keys.rsa, claims(map[string]any{"aud": "other"})), "not intended for this audience"},
+		{"no audience", sign(t, "RS256", "rsa", keys.rsa, claims(map[string]any{"aud": nil})), "not intended for this audience"},
+		{"no subject", sign(t, "RS256", "rsa", keys.rsa, claims(map[string]any{"sub": nil})), "token has no subject"},
+	}
+	for _, tt := range tests {
+		got, err := v.verify(tt.token, now)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case tt.wantErr == "" && (got.Subject != "user-1" || got.Email != "user@example.com"):
+			t.Errorf("%s: got claims %+v", tt.name, got)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s: got error %v, want '%s'", tt.name, err, tt.wantErr)
+		}
+	}
+	// Unknown key IDs do not refetch the JWKS within jwksMinRefresh.
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("JWKS fetched %d times, want 1", n)
+	}
+}
+
+func TestJWTValidatorAuthenticate(t *testing.T) {
+	keys := newTestKeys(t)
+	var fetches atomic.Int32
+	v := NewJWTValidator(JWTConfig{JWKSURL: jwksServer(t, keys, &fetches).URL})
+	token := sign(t, "ES256", "p256", keys.p256, map[string]any{"sub": "user-1", "exp": time.Now().Add(time.Hour).Unix()})
+
+	r := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+	if _, err := v.Authenticate(r); err == nil {
+		t.Error("a request without a token was accepted")
+	}
+	r.Header.Set("Authorization", "Bearer "+token)
+	p, err := v.Authenticate(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Subject != "user-1" || p.Method != "jwt" {
+		t.Errorf("got principal %+v", p)
+	}
+}
+
+func TestJWTValidatorFetchesKeysOnce(t *testing.T) {
+	keys := newTestKeys(t)
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	set, _ := json.Marshal(map[string]any{"keys": []map[string]string{ecJWK("p256", &keys.p256.PublicKey)}})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		<-release
+		w.Write(set)
+	}))
+	defer srv.Close()
+	v := NewJWTValidator(JWTConfig{JWKSURL: srv.URL})
+	token := sign(t, "ES256", "p256", keys.p256, map[string]any{"sub": "user-1", "exp": time.Now().Add(time.Hour).Unix()})
+
+	// Concurrent requests share one fetch, and the lock is not held during it.
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := v.verify(token, time.Now())
+			errs <- err
+		}()
+	}
+	for fetches.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// This blocks if the fetch holds the lock.
+	v.mu.Lock()
+	v.mu.Unlock()
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("JWKS fetched %d times, want 1", n)
+	}
+}
+
+func TestJWTValidatorJWKSDown(t *testing.T) {
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	v := NewJWTValidator(JWTConfig{JWKSURL: srv.URL})
+	for range 3 {
+		if _, err := v.key("any"); err == nil || !strings.Contains(err.Error(), "503") {
+			t.Errorf("got error %v, want the fetch failure", err)
+		}
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("JWKS fetched %d times while down, want 1", n)
+	}
+}
@@ -0,0 +1,44 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func listAccountsTool(d *Deps) Tool {
+	return Tool{
+		Tool: mcp.NewTool("list_accounts",
+			mcp.WithDescription("Lists the configured Google Drive accounts, whether each one is authorized, and its email address."),
+		),
+		Capabilities: Read,
+		Handler: func(ctx context.Context, request mcp.CallToolRequest) (any, error) {
+			return map[string]interface{}{"accounts": d.Accounts.Status(ctx)}, nil
+		},
+	}
+}
+
+func reauthorizeTool(d *Deps) Tool {
+	return Tool{
+		Tool: mcp.NewTool("reauthorize",
+			mcp.WithDescription("Starts a new Google OAuth authorization when the stored grant was revoked or expired, or the account failed to connect. Returns a URL (and code for the device flow) the user must visit; the server picks up the new token without restarting."),
+			accountOption,
+		),
+		Capabilities: Read,
+		Handler: func(ctx context.Context, request mcp.CallToolRequest) (any, error) {
+			return d.Accounts.Reauthorize(ctx, request.GetString("account", ""))
+		},
+	}
+}
+
+func authorizeTool(d *Deps) Tool {
+	return Tool{
+		Tool: mcp.NewTool("authorize",
+			mcp.WithDescription("Signs this session in to Google Drive. Returns a URL the user must open to grant access; Drive tools in this session then act as that Google account. Call it again to switch accounts."),
+		),
+		Capabilities: Read,
+		Handler: func(ctx context.Context, request mcp.CallToolRequest) (any, error) {
+			return d.Sessions.Authorize(SessionID(ctx), caller(ctx))
+		},
+	}
+}
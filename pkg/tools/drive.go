@@ -0,0 +1,456 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"google-drive-mcp-server/pkg/driveapi"
+	"google-drive-mcp-server/pkg/extract"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// recentFilesDefault is the number of files recent_files returns by default.
+const recentFilesDefault = 20
+
+// driveTools are the tools that work with files and folders.
+func driveTools(d *Deps) []Tool {
+	return []Tool{
+		{
+			Tool: mcp.NewTool("list_root_folders",
+				mcp.WithDescription("Fetches the list of root level folders in Google Drive."),
+				pageSizeOption,
+				pageTokenOption,
+				maxResultsOption,
+				orderByOption,
+				accountOption,
+				asUserOption,
+			),
+			Capabilities: Read,
+			Handler: d.driveHandler(func(ctx context.Context, client driveapi.DriveClient, request mcp.CallToolRequest) (any, error) {
+				opts, err := pageOptions(request)
+				if err != nil {
+					return nil, err
+				}
+				page, err := driveapi.ListRootFolders(ctx, client, opts)
+				if err != nil {
+					return nil, err
+				}
+				folders := make([]map[string]string, len(page.Files))
+				for i, folder := range page.Files {
+					folders[i] = map[string]string{"id": folder.Id, "name": folder.Name}
+				}
+				result := map[string]interface{}{"folders": folders}
+				if page.NextPageToken != "" {
+					result["next_page_token"] = page.NextPageToken
+				}
+				return result, nil
+			}),
+		},
+		{
+			Tool: mcp.NewTool("create_file_in_path",
+				mcp.WithDescription("Creates a file with the given content in the specified Google Drive path."),
+				mcp.WithString("path",
+					mcp.Required(),
+					mcp.Description("The full path including filename (e.g., 'MyFolder/file.txt')"),
+				),
+				mcp.WithString("content",
+					mcp.Required(),
+					mcp.Description("The content of the file"),
+				),
+				accountOption,
+				asUserOption,
+			),
+			Capabilities: Write,
+			Handler: d.driveHandler(func(ctx context.Context, client driveapi.DriveClient, request mcp.CallToolRequest) (any, error) {
+				filePath, err := request.RequireString("path")
+				if err != nil {
+					return nil, err
+				}
+				content, err := request.RequireString("content")
+				if err != nil {
+					return nil, err
+				}
+				file, err := driveapi.CreateFileInPath(ctx, client, filePath, content)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{"file_id": file.Id, "file_name": file.Name}, nil
+			}),
+		},
+		{
+			Tool: mcp.NewTool("create_docx_file_in_path",
+				mcp.WithDescription("Creates a .docx file with the given content in the specified Google Drive path."),
+				mcp.WithString("path",
+					mcp.Required(),
+					mcp.Description("The full path including filename (e.g., 'MyFolder/document.docx')"),
+				),
+				mcp.WithString("content",
+					mcp.Required(),
+					mcp.Description("The content of the file"),
+				),
+				accountOption,
+				asUserOption,
+			),
+			Capabilities: Write,
+			Handler: d.driveHandler(func(ctx context.Context, client driveapi.DriveClient, request mcp.CallToolRequest) (any, error) {
+				filePath, err := request.RequireString("path")
+				if err != nil {
+					return nil, err
+				}
+				content, err := request.RequireString("content")
+				if err != nil {
+					return nil, err
+				}
+				file, err := driveapi.CreateDocxFileInPath(ctx, client, filePath, content)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{"file_id": file.Id, "file_name": file.Name}, nil
+			}),
+		},
+		{
+			// Suggesting a folder creates it when it does not exist yet.
+			Tool: mcp.NewTool("suggest_folder_for_content",
+				mcp.WithDescription("Suggests a folder based on the content name."),
+				mcp.WithString("content_name",
+					mcp.Required(),
+					mcp.Description("The name of the content to suggest a folder for"),
+				),
+				accountOption,
+				asUserOption,
+			),
+			Capabilities: Read | Write,
+			Handler: d.driveHandler(func(ctx context.Context, client driveapi.DriveClient, request mcp.CallToolRequest) (any, error) {
+				contentName, err := request.RequireString("content_name")
+				if err != nil {
+					return nil, err
+				}
+				suggestedFolderID, err := driveapi.SuggestFolderForContent(ctx, client, contentName)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{"suggested_folder_id": suggestedFolderID}, nil
+			}),
+		},
+		{
+			Tool: mcp.NewTool("list_files_and_folders",
+				mcp.WithDescription("Lists files and folders within a specific folder, given by ID or path."),
+				mcp.WithString("folder_id",
+					mcp.Description("The ID of the folder to list files and folders from. Defaults to root."),
+				),
+				mcp.WithString("path",
+					mcp.Description("The path of the folder instead of its ID (e.g., 'Projects/2024')."),
+				),
+				fieldsOption,
+				pageSizeOption,
+				pageTokenOption,
+				maxResultsOption,
+				orderByOption,
+				accountOption,
+				asUserOption,
+			),
+			Capabilities: Read,
+			Handler: d.driveHandler(func(ctx context.Context, client driveapi.DriveClient, request mcp.CallToolRequest) (any, error) {
+				fields, err := fileFields(request)
+				if err != nil {
+					return nil, err
+				}
+				opts, err := pageOptions(request)
+				if err != nil {
+					return nil, err
+				}
+				folderID, err := targetID(ctx, client, request, "folder_id", true)
+				if err != nil {
+					return nil, err
+				}
+				page, err := driveapi.ListFilesAndFoldersInFolder(ctx, client, folderID, opts, fields)
+				if err != nil {
+					return nil, err
+				}
+				return pageResult("files", page, fields), nil
+			}),
+		},
+		{
+			Tool: mcp.NewTool("search_files",
+				mcp.WithDescription("Searches for files and folders in Google Drive. Every given filter must match; trashed items are left out unless trashed is true. Returns the matching items and the Drive query they were found with."),
+				mcp.WithString("name_contains",
+					mcp.Description("Text the item name must contain."),
+				),
+				mcp.WithString("full_text",
+					mcp.Description("Text the item name, description or content must contain."),
+				),
+				mcp.WithArray("mime_types",
+					mcp.Description("Types to match, any of which may apply. Full MIME types or the aliases "+strings.Join(driveapi.MimeTypeAliases(), ", ")+"."),
+					mcp.WithStringItems(),
+				),
+				mcp.WithString("folder_id",
+					mcp.Description("ID of the folder the items must be directly inside."),
+				),
+				mcp.WithString("owner",
+					mcp.Description("Email of the owner, or 'me'."),
+				),
+				mcp.WithString("modified_after",
+					mcp.Description("Only items modified after this time (RFC 3339, e.g. '2024-05-01T00:00:00Z', or a date such as '2024-05-01')."),
+				),
+				mcp.WithString("modified_before",
+					mcp.Description("Only items modified before this time (RFC 3339 or a date)."),
+				),
+				mcp.WithBoolean("starred",
+					mcp.Description("Only starred items when true, only unstarred items when false."),
+				),
+				mcp.WithBoolean("shared_with_me",
+					mcp.Description("Only items shared with the user when true, only others when false."),
+				),
+				mcp.WithBoolean("trashed",
+					mcp.Description("Search the trash instead. Defaults to false."),
+				),
+				fieldsOption,
+				pageSizeOption,
+				pageTokenOption,
+				maxResultsOption,
+				orderByOption,
+				accountOption,
+				asUserOption,
+			),
+			Capabilities: Read,
+			Handler: d.driveHandler(func(ctx context.Context, client driveapi.DriveClient, request mcp.CallToolRequest) (any, error) {
+				criteria := driveapi.SearchCriteria{
+					NameContains: request.GetString("name_contains", ""),
+					FullText:     request.GetString("full_text", ""),
+					MimeTypes:    request.GetStringSlice("mime_types", nil),
+					ParentID:     request.GetString("folder_id", ""),
+					Owner:        request.GetString("owner", ""),
+					Starred:      optionalBool(request, "starred"),
+					SharedWithMe: optionalBool(request, "shared_with_me"),
+					Trashed:      request.GetBool("trashed", false),
+				}
+				var err error
+				if criteria.ModifiedAfter, err = optionalTime(request, "modified_after"); err != nil {
+					return nil, err
+				}
+				if criteria.ModifiedBefore, err = optionalTime(request, "modified_before"); err != nil {
+					return nil, err
+				}
+				query, err := criteria.Query()
+				if err != nil {
+					return nil, err
+				}
+				fields, err := fileFields(request)
+				if err != nil {
+					return nil, err
+				}
+				opts, err := pageOptions(request)
+				if err != nil {
+					return nil, err
+				}
+				if criteria.FullText != "" && opts.OrderBy != "" {
+					return nil, errOrderByFullText
+				}
+				page, err := driveapi.SearchDriveItems(ctx, client, query.String(), opts, fields)
+				if err != nil {
+					return nil, err
+				}
+				result := pageResult("found_items", page, fields)
+				result["query"] = query.String()
+				return result, nil
+			}),
+		},
+		{
+			Tool: mcp.NewTool("search_drive_items",
+				mcp.WithDescription("Searches for files and folders in Google Drive with a raw Drive API query string. Prefer search_files, which builds a valid query from structured filters."),
+				mcp.WithString("query",
+					mcp.Required(),
+					mcp.Description("The Google Drive API search query string (e.g., 'name contains \"Projects\"')"),
+				),
+				fieldsOption,
+				pageSizeOption,
+				pageTokenOption,
+				maxResultsOption,
+				orderByOption,
+				accountOption,
+				asUserOption,
+			),
+			Capabilities: Read,
+			Handler: d.driveHandler(func(ctx context.Context, client driveapi.DriveClient, request mcp.CallToolRequest) (any, error) {
+				query, err := request.RequireString("query")
+				if err != nil {
+					return nil, err
+				}
+				fields, err := fileFields(request)
+				if err != nil {
+					return nil, err
+				}
+				opts, err := pageOptions(request)
+				if err != nil {
+					return nil, err
+				}
+				if opts.OrderBy != "" && usesFullText(query) {
+					return nil, errOrderByFullText
+				}
+				page, err := driveapi.SearchDriveItems(ctx, client, query, opts, fields)
+				if err != nil {
+					return nil, err
+				}
+				return pageResult("found_items", page, fields), nil
+			}),
+		},
+		{
+			Tool: mcp.NewTool("recent_files",
+				mcp.WithDescription("Lists the most recently modified or viewed files across the whole Google Drive, newest first."),
+				mcp.WithString("by",
+					mcp.Description("'modified' for files anyone changed recently, or 'viewed' for files the user opened recently. Defaults to modified."),
+					mcp.Enum(string(driveapi.RecentlyModified), string(driveapi.RecentlyViewed)),
+				),
+				fieldsOption,
+				pageSizeOption,
+				pageTokenOption,
+				maxResultsOption,
+				accountOption,
+				asUserOption,
+			),
+			Capabilities: Read,
+			Handler: d.driveHandler(func(ctx context.Context, client driveapi.DriveClient, request mcp.CallToolRequest) (any, error) {
+				fields, err := fileFields(request)
+				if err != nil {
+					return nil, err
+				}
+				opts, err := pageOptions(request)
+				if err != nil {
+					return nil, err
+				}
+				if opts.MaxResults == 0 {
+					opts.MaxResults = recentFilesDefault
+				}
+				by := driveapi.Recency(request.GetString("by", string(driveapi.RecentlyModified)))
+				page, err := driveapi.RecentFiles(ctx, client, by, opts, fields)
+				if err != nil {
+					return nil, err
+				}
+				return pageResult("files", page, fields), nil
+			}),
+		},
+		{
+			Tool: mcp.NewTool("read_file_content",
+				mcp.WithDescription("Reads the content of a specified file from Google Drive, given by ID or path. The file's type is detected, shortcuts are followed to their targets, and the result includes the file's ID, name, MIME type and modified time. Google Docs and .docx files are converted to plain text or Markdown, keeping headings, lists, tables, links and footnotes. The text of PDFs is returned page by page in reading order, under '--- Page N ---' markers; pages that are only scanned images are marked image-only, as they need OCR. Google Sheets and .xlsx files are returned sheet by sheet as CSV, or as Markdown tables, and Google Slides and .pptx files slide by slide, with titles and speaker notes. Google Drawings are returned as PNG images. Text files are returned as they are."),
+				mcp.WithString("file_id",
+					mcp.Description("The ID of the file to read. Either file_id or path is required."),
+				),
+				mcp.WithString("path",
+					mcp.Description("The path of the file instead of its ID (e.g., 'Projects/2024/plan.txt')."),
+				),
+				mcp.WithString("mime_type",
+					mcp.Description("Optional. The type is detected from the file; a MIME type given here is only checked against it."),
+				),
+				mcp.WithString("format",
+					mcp.Description("'text' (default) or 'markdown' for documents and spreadsheets."),
+					mcp.Enum(string(extract.Text), string(extract.Markdown)),
+				),
+				mcp.WithString("pages",
+					mcp.Description("The pages to read from a PDF, e.g. '1-3,7' or '10-'. Defaults to all pages."),
+				),
+				mcp.WithString("sheet",
+					mcp.Description("The name of the one sheet to read from a spreadsheet. Defaults to all sheets."),
+				),
+				mcp.WithString("cells",
+					mcp.Description("The cells to read from each sheet of a spreadsheet in A1 notation, e.g. 'A1:D20', 'B:D' or '2:10'. Defaults to all cells."),
+				),
+				accountOption,
+				asUserOption,
+			),
+			Capabilities: Read,
+			Handler: d.driveHandler(func(ctx context.Context, client driveapi.DriveClient, request mcp.CallToolRequest) (any, error) {
+				fileID, err := targetID(ctx, client, request, "file_id", false)
+				if err != nil {
+					return nil, err
+				}
+				if fileID == "" {
+					return nil, fmt.Errorf("file_id or path is required")
+				}
+				format, err := extract.ParseFormat(request.GetString("format", ""))
+				if err != nil {
+					return nil, err
+				}
+				pages, err := extract.ParseRanges(request.GetString("pages", ""))
+				if err != nil {
+					return nil, err
+				}
+				cells, err := extract.ParseCellRange(request.GetString("cells", ""))
+				if err != nil {
+					return nil, err
+				}
+				opts := driveapi.ReadOptions{Format: format, Pages: pages, Sheet: request.GetString("sheet", ""), Cells: cells}
+				content, err := driveapi.ReadFile(ctx, client, fileID, request.GetString("mime_type", ""), opts)
+				if err != nil {
+					return nil, err
+				}
+				if content.Image != nil {
+					// Images go to the client as image content, described by
+					// the rest of the result.
+					text, err := json.Marshal(content)
+					if err != nil {
+						return nil, err
+					}
+					return mcp.NewToolResultImage(string(text), base64.StdEncoding.EncodeToString(content.Image), content.ImageMimeType), nil
+				}
+				return content, nil
+			}),
+		},
+	}
+}
+
+// errOrderByFullText is returned when a search sorts full-text matches.
+var errOrderByFullText = errors.New("order_by cannot be combined with a full-text search; Drive orders full-text matches by relevance")
+
+// usesFullText reports whether a Drive query has a fullText term outside its
+// quoted values.
+func usesFullText(query string) bool {
+	var unquoted strings.Builder
+	quoted := false
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case quoted && c == '\\':
+			i++
+		case c == '\'':
+			quoted = !quoted
+			unquoted.WriteByte(' ')
+		case !quoted:
+			unquoted.WriteByte(c)
+		}
+	}
+	for _, word := range strings.FieldsFunc(unquoted.String(), func(r rune) bool { return r == ' ' || r == '(' || r == ')' }) {
+		if word == "fullText" {
+			return true
+		}
+	}
+	return false
+}
+
+// optionalBool returns the boolean argument key, or nil if it was not given.
+func optionalBool(request mcp.CallToolRequest, key string) *bool {
+	if _, ok := request.GetArguments()[key]; !ok {
+		return nil
+	}
+	return mcp.ToBoolPtr(request.GetBool(key, false))
+}
+
+// optionalTime parses the time argument key, given in RFC 3339 or as a date.
+// It returns the zero time if the argument was not given.
+func optionalTime(request mcp.CallToolRequest, key string) (time.Time, error) {
+	s := request.GetString(key, "")
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%s must be an RFC 3339 time such as '2024-05-01T00:00:00Z' or a date such as '2024-05-01', got '%s'", key, s)
+}
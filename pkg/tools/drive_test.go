@@ -0,0 +1,104 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"google-drive-mcp-server/pkg/driveapi"
+	"google-drive-mcp-server/pkg/driveapi/drivefake"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// fakeDeps runs the tools against d and records the account and as_user of
+// each call.
+func fakeDeps(d *drivefake.Drive, calls *[]string) *Deps {
+	return &Deps{Drive: func(ctx context.Context, account, asUser string) (driveapi.DriveClient, error) {
+		*calls = append(*calls, account+"/"+asUser)
+		return d, nil
+	}}
+}
+
+// callTool runs the named tool's handler with args.
+func callTool(t *testing.T, r *Registry, name string, args map[string]any) (any, error) {
+	t.Helper()
+	tool, ok := r.Lookup(name)
+	if !ok {
+		t.Fatalf("no tool '%s'", name)
+	}
+	var request mcp.CallToolRequest
+	request.Params.Name = name
+	request.Params.Arguments = args
+	return tool.Handler(context.Background(), request)
+}
+
+func TestToolsUseInjectedDriveClient(t *testing.T) {
+	d := drivefake.New()
+	var calls []string
+	r := New(fakeDeps(d, &calls))
+	if _, ok := r.Lookup("list_accounts"); ok {
+		t.Error("list_accounts is registered without accounts")
+	}
+
+	res, err := callTool(t, r, "create_file_in_path", map[string]any{"path": "Projects/notes.txt", "content": "hello", "account": "work"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := res.(map[string]interface{})["file_id"].(string)
+	if content, ok := d.Content(id); !ok || string(content) != "hello" {
+		t.Errorf("created file has content %q", content)
+	}
+
+	for i := range 2 {
+		if _, err := callTool(t, r, "create_file_in_path", map[string]any{"path": fmt.Sprintf("Folder%d/a.txt", i), "content": "x"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	res, err = callTool(t, r, "list_root_folders", map[string]any{"max_results": 2, "as_user": "dev@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := res.(map[string]interface{})
+	if folders := result["folders"].([]map[string]string); len(folders) != 2 || result["next_page_token"] == nil {
+		t.Errorf("got %+v", result)
+	}
+
+	want := fmt.Sprint([]string{"work/", "/", "/", "/dev@example.com"})
+	if fmt.Sprint(calls) != want {
+		t.Errorf("resolved clients for %v, want %v", calls, want)
+	}
+}
+
+func TestDisableAsUserWithInjectedClient(t *testing.T) {
+	var calls []string
+	deps := fakeDeps(drivefake.New(), &calls)
+	deps.DisableAsUser = true
+	if _, err := callTool(t, New(deps), "list_root_folders", map[string]any{"as_user": "dev@example.com"}); err == nil {
+		t.Error("as_user was accepted")
+	}
+	if len(calls) != 0 {
+		t.Errorf("a client was resolved for %v", calls)
+	}
+}
+
+func TestOrderByWithFullText(t *testing.T) {
+	var calls []string
+	r := New(fakeDeps(drivefake.New(), &calls))
+	for _, tt := range []struct {
+		tool string
+		args map[string]any
+	}{
+		{"search_files", map[string]any{"full_text": "budget", "order_by": "name"}},
+		{"search_drive_items", map[string]any{"query": "fullText contains 'budget'", "order_by": "name"}},
+		{"search_drive_items", map[string]any{"query": "trashed = false and (fullText contains 'a')", "order_by": "name"}},
+	} {
+		if _, err := callTool(t, r, tt.tool, tt.args); !errors.Is(err, errOrderByFullText) {
+			t.Errorf("%s %v: got error %v", tt.tool, tt.args, err)
+		}
+	}
+	if _, err := callTool(t, r, "search_drive_items", map[string]any{"query": "name contains 'fullText'", "order_by": "name"}); err != nil {
+		t.Errorf("a quoted fullText was rejected: %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"google-drive-mcp-server/pkg/driveapi"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// pathTools are the tools that convert between Drive paths and IDs.
+func pathTools(d *Deps) []Tool {
+	return []Tool{
+		{
+			Tool: mcp.NewTool("stat_path",
+				mcp.WithDescription("Looks up the items at a slash-separated Drive path such as 'Projects/2024/plan.txt'. Drive allows duplicate names, so several items can match; ambiguous is true when they do."),
+				mcp.WithString("path",
+					mcp.Required(),
+					mcp.Description("The path from the root of My Drive, or of a folder shared with you. '/' is the root folder."),
+				),
+				fieldsOption,
+				accountOption,
+				asUserOption,
+			),
+			Capabilities: Read,
+			Handler: d.driveHandler(func(ctx context.Context, client driveapi.DriveClient, request mcp.CallToolRequest) (any, error) {
+				path, err := request.RequireString("path")
+				if err != nil {
+					return nil, err
+				}
+				fields, err := fileFields(request)
+				if err != nil {
+					return nil, err
+				}
+				files, err := driveapi.ResolvePath(ctx, client, path, fields)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{"path": path, "matches": fields.Infos(files), "ambiguous": len(files) > 1}, nil
+			}),
+		},
+		{
+			Tool: mcp.NewTool("resolve_id",
+				mcp.WithDescription("Returns the full path of a file or folder. An item has several paths when it is in several folders."),
+				mcp.WithString("file_id",
+					mcp.Required(),
+					mcp.Description("The ID of the file or folder."),
+				),
+				accountOption,
+				asUserOption,
+			),
+			Capabilities: Read,
+			Handler: d.driveHandler(func(ctx context.Context, client driveapi.DriveClient, request mcp.CallToolRequest) (any, error) {
+				fileID, err := request.RequireString("file_id")
+				if err != nil {
+					return nil, err
+				}
+				paths, err := driveapi.PathsOf(ctx, client, fileID)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{"id": fileID, "paths": paths}, nil
+			}),
+		},
+	}
+}
+
+// targetID returns the ID given as the idKey argument or the ID of the single
+// item at the path argument. It returns "" when neither is given.
+func targetID(ctx context.Context, client driveapi.DriveClient, request mcp.CallToolRequest, idKey string, wantFolder bool) (string, error) {
+	id, path := request.GetString(idKey, ""), request.GetString("path", "")
+	switch {
+	case id != "" && path != "":
+		return "", fmt.Errorf("give either %s or path, not both", idKey)
+	case path == "":
+		return id, nil
+	}
+	file, err := driveapi.ResolvePathOne(ctx, client, path, driveapi.FileFields{})
+	if err != nil {
+		return "", err
+	}
+	if isFolder := file.MimeType == driveapi.FolderMimeType; isFolder != wantFolder {
+		if wantFolder {
+			return "", fmt.Errorf("path '%s' is not a folder", path)
+		}
+		return "", fmt.Errorf("path '%s' is a folder", path)
+	}
+	return file.Id, nil
+}
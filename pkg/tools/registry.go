@@ -0,0 +1,160 @@
+// Package tools defines the MCP tools the server exposes. Each tool declares
+// its schema, handler and capabilities, and a Registry adds the enabled ones
+// to an MCP server.
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Capability describes what a tool may do to Drive.
+type Capability int
+
+const (
+	// Read tools only read.
+	Read Capability = 1 << iota
+	// Write tools create or modify files and folders.
+	Write
+	// Destructive tools may overwrite or remove existing data.
+	Destructive
+)
+
+// Writes reports whether the capability includes Write or Destructive.
+func (c Capability) Writes() bool {
+	return c&(Write|Destructive) != 0
+}
+
+// String lists the capability flags, e.g. "read,write".
+func (c Capability) String() string {
+	var names []string
+	for _, flag := range []struct {
+		c    Capability
+		name string
+	}{{Read, "read"}, {Write, "write"}, {Destructive, "destructive"}} {
+		if c&flag.c != 0 {
+			names = append(names, flag.name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ",")
+}
+
+// Handler runs a tool call. Its result is returned to the client as JSON,
+// unless it already is an *mcp.CallToolResult. An error becomes a tool error
+// result the model can see.
+type Handler func(ctx context.Context, request mcp.CallToolRequest) (any, error)
+
+// Tool is one MCP tool.
+type Tool struct {
+	Tool         mcp.Tool
+	Capabilities Capability
+	Handler      Handler
+}
+
+// Name returns the tool's name.
+func (t *Tool) Name() string {
+	return t.Tool.Name
+}
+
+// Options select which tools Register adds to a server.
+type Options struct {
+	// ReadOnly leaves out every tool that writes to Drive.
+	ReadOnly bool
+	// Disabled names tools to leave out.
+	Disabled []string
+}
+
+// Registry is an ordered set of tools.
+type Registry struct {
+	tools      []*Tool
+	byName     map[string]*Tool
+	registered []string
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{byName: make(map[string]*Tool)}
+}
+
+// Add adds tools to the registry, setting their MCP annotations from their
+// capabilities. Adding a name twice is a programming error and panics.
+func (r *Registry) Add(tools ...Tool) {
+	for _, t := range tools {
+		if _, ok := r.byName[t.Name()]; ok {
+			panic(fmt.Sprintf("tools: duplicate tool '%s'", t.Name()))
+		}
+		t.Tool.Annotations.ReadOnlyHint = mcp.ToBoolPtr(!t.Capabilities.Writes())
+		t.Tool.Annotations.DestructiveHint = mcp.ToBoolPtr(t.Capabilities&Destructive != 0)
+		tool := t
+		r.tools = append(r.tools, &tool)
+		r.byName[t.Name()] = &tool
+	}
+}
+
+// Tools returns every tool in the registry in the order it was added.
+func (r *Registry) Tools() []*Tool {
+	return append([]*Tool(nil), r.tools...)
+}
+
+// Lookup returns the named tool.
+func (r *Registry) Lookup(name string) (*Tool, bool) {
+	t, ok := r.byName[name]
+	return t, ok
+}
+
+// Register adds the tools enabled by opts to s.
+func (r *Registry) Register(s *server.MCPServer, opts Options) {
+	disabled := map[string]bool{}
+	for _, name := range opts.Disabled {
+		if _, ok := r.byName[name]; !ok {
+			log.Printf("Unknown tool %s in the disabled tools list", name)
+		}
+		disabled[name] = true
+	}
+	for _, t := range r.tools {
+		switch {
+		case disabled[t.Name()]:
+			log.Printf("Tool %s is disabled by configuration", t.Name())
+		case opts.ReadOnly && t.Capabilities.Writes():
+			log.Printf("Tool %s is disabled in read-only mode", t.Name())
+		default:
+			s.AddTool(t.Tool, serverHandler(t.Handler))
+			r.registered = append(r.registered, t.Name())
+		}
+	}
+}
+
+// Registered returns the names of the tools Register added, sorted.
+func (r *Registry) Registered() []string {
+	names := append([]string(nil), r.registered...)
+	sort.Strings(names)
+	return names
+}
+
+// serverHandler adapts a Handler to the MCP server.
+func serverHandler(h Handler) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		v, err := h(ctx, request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if result, ok := v.(*mcp.CallToolResult); ok {
+			return result, nil
+		}
+		jsonResult, err := json.Marshal(v)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return mcp.NewToolResultText(string(jsonResult)), nil
+	}
+}
@@ -0,0 +1,84 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func testTool(name string, caps Capability) Tool {
+	return Tool{
+		Tool:         mcp.NewTool(name),
+		Capabilities: caps,
+		Handler: func(ctx context.Context, request mcp.CallToolRequest) (any, error) {
+			return map[string]string{"tool": name}, nil
+		},
+	}
+}
+
+func TestRegisterFiltersTools(t *testing.T) {
+	tests := []struct {
+		opts Options
+		want string
+	}{
+		{Options{}, "destroy,read,summarize,write"},
+		{Options{ReadOnly: true}, "read,summarize"},
+		{Options{Disabled: []string{"read", "unknown"}}, "destroy,summarize,write"},
+	}
+	for _, tt := range tests {
+		r := NewRegistry()
+		r.Add(
+			testTool("read", Read),
+			testTool("write", Read|Write),
+			testTool("destroy", Destructive),
+			testTool("summarize", 0),
+		)
+		s := server.NewMCPServer("test", "1.0.0", server.WithToolCapabilities(true))
+		r.Register(s, tt.opts)
+		if got := strings.Join(r.Registered(), ","); got != tt.want {
+			t.Errorf("Register(%+v) = %s, want %s", tt.opts, got, tt.want)
+		}
+		if len(s.ListTools()) != len(r.Registered()) {
+			t.Errorf("server has %d tools, registry reports %d", len(s.ListTools()), len(r.Registered()))
+		}
+	}
+}
+
+func TestAnnotationsFollowCapabilities(t *testing.T) {
+	r := NewRegistry()
+	r.Add(testTool("read", Read), testTool("write", Write), testTool("destroy", Write|Destructive))
+	for name, want := range map[string][2]bool{
+		"read":    {true, false},
+		"write":   {false, false},
+		"destroy": {false, true},
+	} {
+		tool, _ := r.Lookup(name)
+		a := tool.Tool.Annotations
+		if *a.ReadOnlyHint != want[0] || *a.DestructiveHint != want[1] {
+			t.Errorf("%s: readOnly=%v destructive=%v, want %v", name, *a.ReadOnlyHint, *a.DestructiveHint, want)
+		}
+	}
+}
+
+func TestAddDuplicatePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("adding a duplicate tool did not panic")
+		}
+	}()
+	r := NewRegistry()
+	r.Add(testTool("a", Read), testTool("a", Read))
+}
+
+func TestHandlerErrorsBecomeToolErrors(t *testing.T) {
+	h := serverHandler(func(ctx context.Context, request mcp.CallToolRequest) (any, error) {
+		return nil, context.DeadlineExceeded
+	})
+	res, err := h(context.Background(), mcp.CallToolRequest{})
+	if err != nil || !res.IsError {
+		t.Errorf("got (%v, %v), want a tool error result", res, err)
+	}
+}
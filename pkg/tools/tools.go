@@ -0,0 +1,162 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google-drive-mcp-server/pkg/driveapi"
+	"google-drive-mcp-server/pkg/httpauth"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"google.golang.org/api/drive/v3"
+)
+
+// Deps are the services tool handlers use. Exactly one of Accounts, Sessions
+// and Drive is set.
+type Deps struct {
+	// Accounts are the configured Drive accounts shared by every session.
+	Accounts *driveapi.Accounts
+	// Sessions gives each MCP session its own Google identity.
+	Sessions *driveapi.SessionAuth
+	// Drive resolves the client for a call's account and as_user arguments
+	// directly, e.g. to run the tools against drivefake.
+	Drive func(ctx context.Context, account, asUser string) (driveapi.DriveClient, error)
+	// DisableAsUser refuses the as_user argument.
+	DisableAsUser bool
+}
+
+// New returns a registry with every tool the server provides.
+func New(deps *Deps) *Registry {
+	r := NewRegistry()
+	r.Add(driveTools(deps)...)
+	r.Add(pathTools(deps)...)
+	r.Add(summarizeContentTool())
+	switch {
+	case deps.Sessions != nil:
+		r.Add(authorizeTool(deps))
+	case deps.Accounts != nil:
+		r.Add(listAccountsTool(deps), reauthorizeTool(deps))
+	}
+	r.Add(listToolsTool(r))
+	return r
+}
+
+// caller identifies the authenticated principal of a tool call, or returns ""
+// if the call was not authenticated.
+func caller(ctx context.Context) string {
+	if p := httpauth.PrincipalFromContext(ctx); p != nil {
+		return p.Method + ":" + p.Subject
+	}
+	return ""
+}
+
+// SessionID returns the ID of the MCP session a tool call belongs to.
+func SessionID(ctx context.Context) string {
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		return session.SessionID()
+	}
+	return ""
+}
+
+// Every Drive tool accepts an optional account and user to impersonate.
+var (
+	accountOption = mcp.WithString("account",
+		mcp.Description("Name of the configured Drive account to use (see list_accounts). Defaults to the default account."),
+	)
+	asUserOption = mcp.WithString("as_user",
+		mcp.Description("Email of a Workspace user to act as (requires service account domain-wide delegation and the user on the account's as_user_allowlist). Defaults to the configured identity."),
+	)
+)
+
+// Listing tools page through their results with these options.
+var (
+	pageSizeOption = mcp.WithNumber("page_size",
+		mcp.Description(fmt.Sprintf("Number of items to request from Drive at a time, up to %d. Defaults to %d.", driveapi.MaxPageSize, driveapi.DefaultPageSize)),
+	)
+	pageTokenOption = mcp.WithString("page_token",
+		mcp.Description("The next_page_token of a previous call, to continue where it stopped."),
+	)
+	maxResultsOption = mcp.WithNumber("max_results",
+		mcp.Description(fmt.Sprintf("Most items to return, up to %d. Defaults to %d; a next_page_token is returned when there are more.", driveapi.MaxMaxResults, driveapi.DefaultMaxResults)),
+	)
+	orderByOption = mcp.WithString("order_by",
+		mcp.Description("Sort order: comma-separated keys among "+strings.Join(driveapi.OrderKeys, ", ")+", each optionally followed by asc or desc (e.g. 'folder, modifiedTime desc'). Pass the same order with page_token."),
+	)
+)
+
+// fieldsOption selects the metadata listing tools return about each file.
+var fieldsOption = mcp.WithArray("fields",
+	mcp.Description("Metadata to return for each item besides id, name and mime_type: "+strings.Join(driveapi.FileFieldNames(), ", ")+". Defaults to size and modifiedTime."),
+	mcp.WithStringItems(),
+)
+
+// pageOptions returns the paging and order arguments of a listing call.
+func pageOptions(request mcp.CallToolRequest) (driveapi.PageOptions, error) {
+	orderBy, err := driveapi.ParseOrderBy(request.GetString("order_by", ""))
+	if err != nil {
+		return driveapi.PageOptions{}, err
+	}
+	return driveapi.PageOptions{
+		PageSize:   int64(request.GetInt("page_size", 0)),
+		PageToken:  request.GetString("page_token", ""),
+		MaxResults: request.GetInt("max_results", 0),
+		OrderBy:    orderBy,
+	}, nil
+}
+
+// fileFields returns the metadata fields a listing call selected.
+func fileFields(request mcp.CallToolRequest) (driveapi.FileFields, error) {
+	return driveapi.ParseFileFields(request.GetStringSlice("fields", nil))
+}
+
+// pageResult returns a listing result with the page's files under key and its
+// next_page_token, if there are more.
+func pageResult(key string, page *driveapi.Page, fields driveapi.FileFields) map[string]interface{} {
+	result := map[string]interface{}{key: fields.Infos(page.Files)}
+	if page.NextPageToken != "" {
+		result["next_page_token"] = page.NextPageToken
+	}
+	return result
+}
+
+// DriveHandler runs a tool call against the Drive client the call resolved to.
+type DriveHandler func(ctx context.Context, client driveapi.DriveClient, request mcp.CallToolRequest) (any, error)
+
+// driveHandler resolves the Drive client for a call before running h.
+func (d *Deps) driveHandler(h DriveHandler) Handler {
+	return func(ctx context.Context, request mcp.CallToolRequest) (any, error) {
+		client, err := d.drive(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return h(ctx, client, request)
+	}
+}
+
+// drive returns the Drive client selected by the call's account and as_user
+// arguments, or the session's own client in per-session mode.
+func (d *Deps) drive(ctx context.Context, request mcp.CallToolRequest) (driveapi.DriveClient, error) {
+	account, asUser := request.GetString("account", ""), request.GetString("as_user", "")
+	if asUser != "" && d.DisableAsUser {
+		return nil, fmt.Errorf("as_user is disabled on this server")
+	}
+	if d.Drive != nil {
+		return d.Drive(ctx, account, asUser)
+	}
+	var srv *drive.Service
+	var err error
+	if d.Sessions != nil {
+		if account != "" || asUser != "" {
+			return nil, fmt.Errorf("account and as_user are not available with per-session authorization")
+		}
+		srv, err = d.Sessions.Service(SessionID(ctx), caller(ctx))
+	} else {
+		srv, err = d.Accounts.Service(ctx, account, asUser)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return driveapi.NewServiceClient(srv), nil
+}
@@ -0,0 +1,48 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func summarizeContentTool() Tool {
+	return Tool{
+		Tool: mcp.NewTool("summarize_content",
+			mcp.WithDescription("Summarizes the provided text content."),
+			mcp.WithString("content",
+				mcp.Required(),
+				mcp.Description("The text content to summarize."),
+			),
+		),
+		Capabilities: 0, // Does not touch Drive
+		Handler: func(ctx context.Context, request mcp.CallToolRequest) (any, error) {
+			content, err := request.RequireString("content")
+			if err != nil {
+				return nil, err
+			}
+
+			// Placeholder for actual summarization logic.
+			// In a real scenario, this would integrate with an LLM or a summarization library.
+			summary := content
+			if len(content) > 200 { // Example: truncate if too long
+				summary = content[:200] + "..."
+			}
+			summary = "Placeholder summary: " + summary
+			return map[string]interface{}{"summary": summary}, nil
+		},
+	}
+}
+
+// listToolsTool reports the tools r registered.
+func listToolsTool(r *Registry) Tool {
+	return Tool{
+		Tool: mcp.NewTool("mcp/list_tools",
+			mcp.WithDescription("Lists all available tools on the MCP server."),
+		),
+		Capabilities: 0, // Does not touch Drive
+		Handler: func(ctx context.Context, request mcp.CallToolRequest) (any, error) {
+			return map[string]interface{}{"tools": r.Registered()}, nil
+		},
+	}
+}